@@ -8,6 +8,8 @@ package ast
 
 import (
 	"bytes"
+	"fmt"
+	"math/big"
 	"strings"
 
 	"github.com/vtallen/go-interpreter/token"
@@ -21,6 +23,8 @@ import (
 type Node interface {
 	TokenLiteral() string // All nodes should be able to return the literal value of the token that they are associated with.
 	String() string       // All nodes should be able to return a string representation of the node.
+	Pos() token.Position  // Position of the first character belonging to the node.
+	End() token.Position  // Position immediately after the last character belonging to the node.
 }
 
 /*
@@ -55,6 +59,13 @@ type ExpressionStatement struct {
 
 func (es *ExpressionStatement) statementNode()       {}
 func (es *ExpressionStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *ExpressionStatement) Pos() token.Position  { return es.Token.Pos }
+func (es *ExpressionStatement) End() token.Position {
+	if es.Expression != nil {
+		return es.Expression.End()
+	}
+	return es.Token.End
+}
 func (es *ExpressionStatement) String() string {
 	if es.Expression != nil {
 		return es.Expression.String()
@@ -69,6 +80,9 @@ func (es *ExpressionStatement) String() string {
  */
 type Program struct {
 	Statements []Statement // All statements in the program
+
+	Comments   []*CommentGroup // Every comment in the program in source order, only set when the lexer keeps comments
+	CommentMap CommentMap      // The statements in the program that have comments attached to them
 }
 
 /*
@@ -89,6 +103,34 @@ func (p *Program) TokenLiteral() string {
 	}
 }
 
+/*
+* Function: Program.Pos
+*
+* Paramters: none
+*
+* Return: token.Position - The position of the first statement in the program, invalid if the program is empty.
+ */
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+	return token.Position{}
+}
+
+/*
+* Function: Program.End
+*
+* Paramters: none
+*
+* Return: token.Position - The end of the last statement in the program, invalid if the program is empty.
+ */
+func (p *Program) End() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[len(p.Statements)-1].End()
+	}
+	return token.Position{}
+}
+
 /*
 * Function: Program.String
 *
@@ -127,6 +169,16 @@ type LetStatement struct {
 
 func (ls *LetStatement) statementNode()       {}
 func (ls *LetStatement) TokenLiteral() string { return ls.Token.Literal }
+func (ls *LetStatement) Pos() token.Position  { return ls.Token.Pos }
+func (ls *LetStatement) End() token.Position {
+	if ls.Value != nil {
+		return ls.Value.End()
+	}
+	if ls.Name != nil {
+		return ls.Name.End()
+	}
+	return ls.Token.End
+}
 
 func (ls *LetStatement) String() string {
 	var out bytes.Buffer
@@ -160,6 +212,8 @@ type Identifier struct {
 
 func (i *Identifier) expressionNode()      {}
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
+func (i *Identifier) Pos() token.Position  { return i.Token.Pos }
+func (i *Identifier) End() token.Position  { return i.Token.End }
 
 func (i *Identifier) String() string { return i.Value }
 
@@ -179,6 +233,13 @@ type ReturnStatement struct {
 
 func (rs *ReturnStatement) statementNode()       {}
 func (rs *ReturnStatement) TokenLiteral() string { return rs.Token.Literal }
+func (rs *ReturnStatement) Pos() token.Position  { return rs.Token.Pos }
+func (rs *ReturnStatement) End() token.Position {
+	if rs.ReturnValue != nil {
+		return rs.ReturnValue.End()
+	}
+	return rs.Token.End
+}
 
 /*
 * Function: ReturnStatement.String
@@ -193,10 +254,10 @@ func (rs *ReturnStatement) TokenLiteral() string { return rs.Token.Literal }
 func (rs *ReturnStatement) String() string {
 	var out bytes.Buffer
 
-	out.WriteString(rs.TokenLiteral() + " ")
+	out.WriteString(rs.TokenLiteral())
 
 	if rs.ReturnValue != nil {
-		out.WriteString(rs.ReturnValue.String())
+		out.WriteString(" " + rs.ReturnValue.String())
 	}
 
 	out.WriteString(";")
@@ -204,23 +265,204 @@ func (rs *ReturnStatement) String() string {
 	return out.String()
 }
 
+/*
+* Struct: WhileStatement
+*
+* Implements: Statement
+*
+* Description: A while (condition) { ... } loop. The body runs for as long as the condition is truthy. The loop
+*              itself produces no value
+ */
+type WhileStatement struct {
+	Token     token.Token // The 'while' token
+	Condition Expression
+	Body      *BlockStatement
+}
+
+func (ws *WhileStatement) statementNode()       {}
+func (ws *WhileStatement) TokenLiteral() string { return ws.Token.Literal }
+func (ws *WhileStatement) Pos() token.Position  { return ws.Token.Pos }
+func (ws *WhileStatement) End() token.Position  { return ws.Body.End() }
+func (ws *WhileStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("while (")
+	out.WriteString(ws.Condition.String())
+	out.WriteString(") { ")
+	out.WriteString(ws.Body.String())
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+/*
+* Struct: ForInStatement
+*
+* Implements: Statement
+*
+* Description: A for (x in iterable) { ... } or for (k, v in iterable) { ... } loop. The body runs once for every
+*              element of the iterable with the variables bound to it. Like a while loop it produces no value
+ */
+type ForInStatement struct {
+	Token     token.Token   // The 'for' token
+	Variables []*Identifier // The one or two names bound on every iteration
+	Iterable  Expression
+	Body      *BlockStatement
+}
+
+func (fs *ForInStatement) statementNode()       {}
+func (fs *ForInStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForInStatement) Pos() token.Position  { return fs.Token.Pos }
+func (fs *ForInStatement) End() token.Position  { return fs.Body.End() }
+func (fs *ForInStatement) String() string {
+	var out bytes.Buffer
+
+	names := []string{}
+	for _, v := range fs.Variables {
+		names = append(names, v.String())
+	}
+
+	out.WriteString("for (")
+	out.WriteString(strings.Join(names, ", "))
+	out.WriteString(" in ")
+	out.WriteString(fs.Iterable.String())
+	out.WriteString(") { ")
+	out.WriteString(fs.Body.String())
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+/*
+* Struct: BreakStatement
+*
+* Implements: Statement
+*
+* Description: Leaves the innermost loop it is in
+ */
+type BreakStatement struct {
+	Token token.Token // The 'break' token
+}
+
+func (bs *BreakStatement) statementNode()       {}
+func (bs *BreakStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BreakStatement) String() string       { return bs.Token.Literal + ";" }
+func (bs *BreakStatement) Pos() token.Position  { return bs.Token.Pos }
+func (bs *BreakStatement) End() token.Position  { return bs.Token.End }
+
+/*
+* Struct: ContinueStatement
+*
+* Implements: Statement
+*
+* Description: Skips the rest of the body of the innermost loop it is in and checks the condition again
+ */
+type ContinueStatement struct {
+	Token token.Token // The 'continue' token
+}
+
+func (cs *ContinueStatement) statementNode()       {}
+func (cs *ContinueStatement) TokenLiteral() string { return cs.Token.Literal }
+func (cs *ContinueStatement) String() string       { return cs.Token.Literal + ";" }
+func (cs *ContinueStatement) Pos() token.Position  { return cs.Token.Pos }
+func (cs *ContinueStatement) End() token.Position  { return cs.Token.End }
+
 /*
 * Struct: IntegerLiteral
 *
 * Implements: Expression, Statement
 *
-* Description: This struct represents an integer literal in the Monkey programming language.
+* Description: This struct represents an integer literal in the Monkey programming language. Literals too large
+*              for an int64 keep their value in Big instead of Value
  */
 type IntegerLiteral struct {
 	Expression
 	Statement
 	Token token.Token // The token.INT token
 	Value int64       // The underlying value of the integer
+	Big   *big.Int    // The value of the integer if it does not fit in Value, nil otherwise
 }
 
 func (il *IntegerLiteral) expressionNode()      {}
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
 func (il *IntegerLiteral) String() string       { return il.Token.Literal }
+func (il *IntegerLiteral) Pos() token.Position  { return il.Token.Pos }
+func (il *IntegerLiteral) End() token.Position  { return il.Token.End }
+
+/*
+* Struct: FloatLiteral
+*
+* Implements: Expression
+*
+* Description: This struct represents a floating point literal in the Monkey programming language. String returns
+*              the literal the way it was written, so 1e3 stays 1e3 instead of becoming 1000
+ */
+type FloatLiteral struct {
+	Token token.Token // The token.FLOAT token
+	Value float64     // The underlying value of the float
+}
+
+func (fl *FloatLiteral) expressionNode()      {}
+func (fl *FloatLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FloatLiteral) String() string       { return fl.Token.Literal }
+func (fl *FloatLiteral) Pos() token.Position  { return fl.Token.Pos }
+func (fl *FloatLiteral) End() token.Position  { return fl.Token.End }
+
+/*
+* Struct: StringLiteral
+*
+* Implements: Expression
+*
+* Description: This struct represents a string literal in the Monkey programming language.
+ */
+type StringLiteral struct {
+	Token token.Token // The token.STRING token
+	Value string      // The value of the string, with escape sequences already replaced
+}
+
+func (sl *StringLiteral) expressionNode()      {}
+func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *StringLiteral) String() string       { return quote(sl.Value) }
+func (sl *StringLiteral) Pos() token.Position  { return sl.Token.Pos }
+func (sl *StringLiteral) End() token.Position  { return sl.Token.End }
+
+/*
+* Function: quote
+*
+* Paramters: s string - The value of a string literal
+*
+* Return: string - s surrounded by double quotes, written so that lexing it again gives back s
+*
+* Description: Escapes quotes, backslashes and control characters using Monkey's escape sequences.
+ */
+func quote(s string) string {
+	var out bytes.Buffer
+
+	out.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			out.WriteString(`\"`)
+		case '\\':
+			out.WriteString(`\\`)
+		case '\n':
+			out.WriteString(`\n`)
+		case '\t':
+			out.WriteString(`\t`)
+		case '\r':
+			out.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&out, `\u{%x}`, r)
+			} else {
+				out.WriteRune(r)
+			}
+		}
+	}
+	out.WriteByte('"')
+
+	return out.String()
+}
 
 type PrefixExpression struct {
 	Token    token.Token // The prefix token, e.g. !
@@ -230,6 +472,13 @@ type PrefixExpression struct {
 
 func (pe *PrefixExpression) expressionNode()      {}
 func (pe *PrefixExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PrefixExpression) Pos() token.Position  { return pe.Token.Pos }
+func (pe *PrefixExpression) End() token.Position {
+	if pe.Right != nil {
+		return pe.Right.End()
+	}
+	return pe.Token.End
+}
 func (pe *PrefixExpression) String() string {
 	var out bytes.Buffer
 
@@ -250,6 +499,13 @@ type InfixExpression struct {
 
 func (oe *InfixExpression) expressionNode()      {}
 func (oe *InfixExpression) TokenLiteral() string { return oe.Token.Literal }
+func (oe *InfixExpression) Pos() token.Position  { return oe.Left.Pos() }
+func (oe *InfixExpression) End() token.Position {
+	if oe.Right != nil {
+		return oe.Right.End()
+	}
+	return oe.Token.End
+}
 func (oe *InfixExpression) String() string {
 	var out bytes.Buffer
 
@@ -262,6 +518,79 @@ func (oe *InfixExpression) String() string {
 	return out.String()
 }
 
+/*
+* Struct: LogicalExpression
+*
+* Implements: Expression
+*
+* Description: An && or || expression. These are kept apart from InfixExpression because they short-circuit: the
+*              right operand is only evaluated when the left one does not already decide the result
+ */
+type LogicalExpression struct {
+	Token    token.Token // The && or || token
+	Left     Expression
+	Operator string
+	Right    Expression
+}
+
+func (le *LogicalExpression) expressionNode()      {}
+func (le *LogicalExpression) TokenLiteral() string { return le.Token.Literal }
+func (le *LogicalExpression) Pos() token.Position  { return le.Left.Pos() }
+func (le *LogicalExpression) End() token.Position {
+	if le.Right != nil {
+		return le.Right.End()
+	}
+	return le.Token.End
+}
+func (le *LogicalExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(le.Left.String())
+	out.WriteString(" " + le.Operator + " ")
+	out.WriteString(le.Right.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
+/*
+* Struct: AssignExpression
+*
+* Implements: Expression
+*
+* Description: Gives a new value to something that already exists, such as x = 5, x += 1 or a[0] = 1. Target is
+*              either an *Identifier or an *IndexExpression. For the compound forms Operator holds the whole
+*              operator, so x += 1 keeps += rather than being turned into x = x + 1
+ */
+type AssignExpression struct {
+	Token    token.Token // The = or compound assignment token
+	Target   Expression
+	Operator string
+	Value    Expression
+}
+
+func (ae *AssignExpression) expressionNode()      {}
+func (ae *AssignExpression) TokenLiteral() string { return ae.Token.Literal }
+func (ae *AssignExpression) Pos() token.Position  { return ae.Target.Pos() }
+func (ae *AssignExpression) End() token.Position {
+	if ae.Value != nil {
+		return ae.Value.End()
+	}
+	return ae.Token.End
+}
+func (ae *AssignExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(ae.Target.String())
+	out.WriteString(" " + ae.Operator + " ")
+	out.WriteString(ae.Value.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
 type Boolean struct {
 	Token token.Token
 	Value bool
@@ -270,14 +599,19 @@ type Boolean struct {
 func (b *Boolean) expressionNode()      {}
 func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
 func (b *Boolean) String() string       { return b.Token.Literal }
+func (b *Boolean) Pos() token.Position  { return b.Token.Pos }
+func (b *Boolean) End() token.Position  { return b.Token.End }
 
 type BlockStatement struct {
-	Token      token.Token
+	Token      token.Token // The { token
 	Statements []Statement
+	Rbrace     token.Token // The } token closing the block
 }
 
 func (bs *BlockStatement) statementNode()       {}
 func (bs *BlockStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BlockStatement) Pos() token.Position  { return bs.Token.Pos }
+func (bs *BlockStatement) End() token.Position  { return bs.Rbrace.End }
 func (bs *BlockStatement) String() string {
 	var out bytes.Buffer
 
@@ -292,22 +626,35 @@ type IfExpression struct {
 	Token       token.Token
 	Condition   Expression
 	Consequence *BlockStatement
-	Alternative *BlockStatement
+	Alternative Node // A *BlockStatement for else, an *IfExpression for else if, nil when there is neither
 }
 
 func (ie *IfExpression) expressionNode()      {}
 func (ie *IfExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IfExpression) Pos() token.Position  { return ie.Token.Pos }
+func (ie *IfExpression) End() token.Position {
+	if ie.Alternative != nil {
+		return ie.Alternative.End()
+	}
+	return ie.Consequence.End()
+}
 func (ie *IfExpression) String() string {
 	var out bytes.Buffer
 
-	out.WriteString("if")
+	out.WriteString("if (")
 	out.WriteString(ie.Condition.String())
-	out.WriteString(" ")
+	out.WriteString(") { ")
 	out.WriteString(ie.Consequence.String())
-
-	if ie.Alternative != nil {
-		out.WriteString("else ")
-		out.WriteString(ie.Alternative.String())
+	out.WriteString(" }")
+
+	switch alt := ie.Alternative.(type) {
+	case *IfExpression:
+		out.WriteString(" else ")
+		out.WriteString(alt.String())
+	case *BlockStatement:
+		out.WriteString(" else { ")
+		out.WriteString(alt.String())
+		out.WriteString(" }")
 	}
 
 	return out.String()
@@ -321,6 +668,8 @@ type FunctionLiteral struct {
 
 func (fl *FunctionLiteral) expressionNode()      {}
 func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FunctionLiteral) Pos() token.Position  { return fl.Token.Pos }
+func (fl *FunctionLiteral) End() token.Position  { return fl.Body.End() }
 func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
 
@@ -332,20 +681,24 @@ func (fl *FunctionLiteral) String() string {
 	out.WriteString(fl.TokenLiteral())
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
-	out.WriteString(") ")
+	out.WriteString(") { ")
 	out.WriteString(fl.Body.String())
+	out.WriteString(" }")
 
 	return out.String()
 }
 
 type CallExpression struct {
-	Token     token.Token
+	Token     token.Token // The ( token
 	Function  Expression
 	Arguments []Expression
+	Rparen    token.Token // The ) token closing the arguments
 }
 
 func (ce *CallExpression) expressionNode()      {}
 func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *CallExpression) Pos() token.Position  { return ce.Function.Pos() }
+func (ce *CallExpression) End() token.Position  { return ce.Rparen.End }
 func (ce *CallExpression) String() string {
 	var out bytes.Buffer
 
@@ -361,3 +714,147 @@ func (ce *CallExpression) String() string {
 
 	return out.String()
 }
+
+/*
+* Struct: ArrayLiteral
+*
+* Implements: Expression
+*
+* Description: This struct represents an array literal such as [1, 2 * 3] in the Monkey programming language.
+ */
+type ArrayLiteral struct {
+	Token    token.Token // The [ token
+	Elements []Expression
+	Rbracket token.Token // The ] token closing the array
+}
+
+func (al *ArrayLiteral) expressionNode()      {}
+func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
+func (al *ArrayLiteral) Pos() token.Position  { return al.Token.Pos }
+func (al *ArrayLiteral) End() token.Position  { return al.Rbracket.End }
+func (al *ArrayLiteral) String() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, el := range al.Elements {
+		elements = append(elements, el.String())
+	}
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+
+	return out.String()
+}
+
+/*
+* Struct: IndexExpression
+*
+* Implements: Expression
+*
+* Description: This struct represents indexing into a value, such as myArray[1], in the Monkey programming language.
+ */
+type IndexExpression struct {
+	Token    token.Token // The [ token
+	Left     Expression  // The value being indexed
+	Index    Expression
+	Rbracket token.Token // The ] token closing the index
+}
+
+func (ie *IndexExpression) expressionNode()      {}
+func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IndexExpression) Pos() token.Position  { return ie.Left.Pos() }
+func (ie *IndexExpression) End() token.Position  { return ie.Rbracket.End }
+func (ie *IndexExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(ie.Left.String())
+	out.WriteString("[")
+	out.WriteString(ie.Index.String())
+	out.WriteString("])")
+
+	return out.String()
+}
+
+/*
+* Struct: HashLiteral
+*
+* Implements: Expression
+*
+* Description: This struct represents a hash literal such as {"name": "monkey", 1: true} in the Monkey programming
+*              language. The pairs are kept in the order they were written.
+ */
+type HashLiteral struct {
+	Token  token.Token // The { token
+	Pairs  []HashPair
+	Rbrace token.Token // The } token closing the hash
+}
+
+/*
+* Struct: HashPair
+*
+* Description: A single key: value pair of a hash literal. Any expression can be used as a key, whether its value
+*              can actually be used as a key is checked when the program runs.
+ */
+type HashPair struct {
+	Key   Expression
+	Value Expression
+}
+
+func (hl *HashLiteral) expressionNode()      {}
+func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
+func (hl *HashLiteral) Pos() token.Position  { return hl.Token.Pos }
+func (hl *HashLiteral) End() token.Position  { return hl.Rbrace.End }
+func (hl *HashLiteral) String() string {
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for _, pair := range hl.Pairs {
+		pairs = append(pairs, pair.Key.String()+": "+pair.Value.String())
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+/*
+* Struct: BadStatement
+*
+* Implements: Statement
+*
+* Description: A placeholder for a statement that could not be parsed. It covers the tokens the parser skipped while
+*              recovering from the error, so the rest of the program can still be used by tools
+ */
+type BadStatement struct {
+	From token.Token // The first token of the statement
+	To   token.Token // The last token skipped while recovering
+}
+
+func (bs *BadStatement) statementNode()       {}
+func (bs *BadStatement) TokenLiteral() string { return bs.From.Literal }
+func (bs *BadStatement) String() string       { return "<bad statement>" }
+func (bs *BadStatement) Pos() token.Position  { return bs.From.Pos }
+func (bs *BadStatement) End() token.Position  { return bs.To.End }
+
+/*
+* Struct: BadExpression
+*
+* Implements: Expression
+*
+* Description: A placeholder for an expression that could not be parsed, used so a node with a missing or broken
+*              child still has a non-nil child
+ */
+type BadExpression struct {
+	From token.Token // The first token of the expression
+	To   token.Token // The last token of the expression
+}
+
+func (be *BadExpression) expressionNode()      {}
+func (be *BadExpression) TokenLiteral() string { return be.From.Literal }
+func (be *BadExpression) String() string       { return "<bad expression>" }
+func (be *BadExpression) Pos() token.Position  { return be.From.Pos }
+func (be *BadExpression) End() token.Position  { return be.To.End }
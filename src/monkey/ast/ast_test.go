@@ -1,6 +1,7 @@
 package ast
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/vtallen/go-interpreter/token"
@@ -27,3 +28,76 @@ func TestString(t *testing.T) {
 		t.Errorf("program.String() wrong. got=%q", program.String())
 	}
 }
+
+func TestStringLiteralString(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"hello", `"hello"`},
+		{"say \"hi\"", `"say \"hi\""`},
+		{"a\\b", `"a\\b"`},
+		{"line\nbreak\ttab\r", `"line\nbreak\ttab\r"`},
+		{"bell\a", `"bell\u{7}"`},
+		{"héllo", `"héllo"`},
+	}
+
+	for _, tt := range tests {
+		sl := &StringLiteral{Token: token.Token{Type: token.STRING, Literal: tt.value}, Value: tt.value}
+		if sl.String() != tt.expected {
+			t.Errorf("sl.String() wrong. expected=%q, got=%q", tt.expected, sl.String())
+		}
+	}
+}
+
+func TestInspect(t *testing.T) {
+	ident := func(name string) *Identifier {
+		return &Identifier{Token: token.Token{Type: token.IDENT, Literal: name}, Value: name}
+	}
+
+	// if (a) { f(b) }, with no else
+	program := &Program{
+		Statements: []Statement{
+			&ExpressionStatement{Expression: &IfExpression{
+				Condition: ident("a"),
+				Consequence: &BlockStatement{Statements: []Statement{
+					&ExpressionStatement{Expression: &CallExpression{
+						Function:  ident("f"),
+						Arguments: []Expression{ident("b")},
+					}},
+				}},
+			}},
+		},
+	}
+
+	var names []string
+	Inspect(program, func(n Node) bool {
+		if id, ok := n.(*Identifier); ok {
+			names = append(names, id.Value)
+		}
+		// Skip the arguments of calls
+		_, isCall := n.(*CallExpression)
+		return !isCall
+	})
+
+	if strings.Join(names, " ") != "a" {
+		t.Errorf("visited identifiers wrong. expected=%q, got=%q", "a", names)
+	}
+}
+
+func TestCommentGroupText(t *testing.T) {
+	comment := func(text string) *Comment {
+		return &Comment{Token: token.Token{Type: token.COMMENT, Literal: text}}
+	}
+
+	group := &CommentGroup{List: []*Comment{
+		comment("// first line"),
+		comment("//second line"),
+		comment("/* a block\n   over two lines */"),
+	}}
+
+	expected := "first line\nsecond line\na block\nover two lines"
+	if group.Text() != expected {
+		t.Errorf("group.Text() wrong. expected=%q, got=%q", expected, group.Text())
+	}
+}
@@ -0,0 +1,219 @@
+/*
+* File: ast/comments.go
+*
+* Description: Contains the nodes for comments and the code that works out which statement each comment belongs
+*              to, so tools such as a formatter or a documentation generator can keep them
+*
+ */
+
+package ast
+
+import (
+	"strings"
+
+	"github.com/vtallen/go-interpreter/token"
+)
+
+/*
+* Struct: Comment
+*
+* Description: A single // or block comment. Comments are not statements or expressions, they are only produced
+*              when the lexer is asked to keep them and are stored beside the tree in Program.Comments
+ */
+type Comment struct {
+	Token token.Token // The token.COMMENT token
+}
+
+func (c *Comment) TokenLiteral() string { return c.Token.Literal }
+func (c *Comment) String() string       { return c.Token.Literal }
+func (c *Comment) Pos() token.Position  { return c.Token.Pos }
+func (c *Comment) End() token.Position  { return c.Token.End }
+
+/*
+* Struct: CommentGroup
+*
+* Description: Comments with no code and no blank line between them, such as several // lines in a row. A group
+*              is what gets attached to a statement
+ */
+type CommentGroup struct {
+	List []*Comment
+}
+
+func (g *CommentGroup) TokenLiteral() string { return g.List[0].TokenLiteral() }
+func (g *CommentGroup) Pos() token.Position  { return g.List[0].Pos() }
+func (g *CommentGroup) End() token.Position  { return g.List[len(g.List)-1].End() }
+func (g *CommentGroup) String() string {
+	lines := make([]string, len(g.List))
+	for i, c := range g.List {
+		lines[i] = c.String()
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+/*
+* Function: CommentGroup.Text
+*
+* Parameters: none
+*
+* Returns: string - The text of the comments without the characters that mark them as comments
+*
+* Description: Strips the // or the characters around a block comment and the spaces next to them, then joins the
+*              comments with newlines. This is the text a documentation generator would show
+ */
+func (g *CommentGroup) Text() string {
+	var lines []string
+
+	for _, c := range g.List {
+		text := c.Token.Literal
+
+		if strings.HasPrefix(text, "//") {
+			text = text[2:]
+		} else {
+			text = strings.TrimSuffix(text[2:], "*/")
+		}
+
+		for _, line := range strings.Split(text, "\n") {
+			lines = append(lines, strings.TrimSpace(line))
+		}
+	}
+
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+/*
+* Struct: NodeComments
+*
+* Description: The comments attached to a single node
+ */
+type NodeComments struct {
+	Leading  *CommentGroup // The group on the lines right above the node, nil if there is none
+	Trailing *CommentGroup // The group after the node on the line the node ends on, nil if there is none
+}
+
+/*
+* Type: CommentMap
+*
+* Description: Maps statements to the comments attached to them. Only statements get comments, a comment in the
+*              middle of an expression belongs to no node and is only found in Program.Comments
+ */
+type CommentMap map[Node]*NodeComments
+
+/*
+* Function: NewCommentMap
+*
+* Parameters: root   Node            - The tree the comments were found in
+*             groups []*CommentGroup - The comment groups in the order they appear in the source
+*
+* Returns: CommentMap - The statements of root that have comments attached to them
+*
+* Description: A group that starts on the line a statement ends on, after the end of the statement, trails it.
+*              When several statements end on that line the one ending last wins. Otherwise a group leads the
+*              first statement starting after it, as long as that statement starts on the line the group ends on or
+*              on the line after it, so a blank line separates a comment from the code below it
+*
+ */
+func NewCommentMap(root Node, groups []*CommentGroup) CommentMap {
+	cmap := make(CommentMap)
+	if len(groups) == 0 {
+		return cmap
+	}
+
+	// Statements in the order they start in, a parent always comes before the statements it contains
+	var stmts []Statement
+	Inspect(root, func(n Node) bool {
+		if stmt, ok := n.(Statement); ok {
+			stmts = append(stmts, stmt)
+		}
+		return true
+	})
+
+	for _, group := range groups {
+		if stmt := trailedStatement(stmts, group); stmt != nil {
+			cmap.comments(stmt).Trailing = group
+			continue
+		}
+
+		if stmt := ledStatement(stmts, group); stmt != nil {
+			cmap.comments(stmt).Leading = group
+		}
+	}
+
+	return cmap
+}
+
+/*
+* Function: CommentMap.Leading
+*
+* Parameters: node Node - The node to look up
+*
+* Returns: *CommentGroup - The comments right above node, nil if there are none
+ */
+func (cmap CommentMap) Leading(node Node) *CommentGroup {
+	if c, ok := cmap[node]; ok {
+		return c.Leading
+	}
+	return nil
+}
+
+/*
+* Function: CommentMap.Trailing
+*
+* Parameters: node Node - The node to look up
+*
+* Returns: *CommentGroup - The comments after node on the same line, nil if there are none
+ */
+func (cmap CommentMap) Trailing(node Node) *CommentGroup {
+	if c, ok := cmap[node]; ok {
+		return c.Trailing
+	}
+	return nil
+}
+
+// comments returns the entry for node, creating it if it does not exist yet
+func (cmap CommentMap) comments(node Node) *NodeComments {
+	c, ok := cmap[node]
+	if !ok {
+		c = &NodeComments{}
+		cmap[node] = c
+	}
+	return c
+}
+
+// trailedStatement finds the statement group trails, nil if it is not on the same line as the end of a statement
+func trailedStatement(stmts []Statement, group *CommentGroup) Statement {
+	var found Statement
+	start := group.Pos()
+
+	for _, stmt := range stmts {
+		end := stmt.End()
+		if end.Line != start.Line || end.Offset > start.Offset {
+			continue
+		}
+
+		if found == nil || end.Offset > found.End().Offset {
+			found = stmt
+		}
+	}
+
+	return found
+}
+
+// ledStatement finds the statement group is placed above, nil if no statement follows it closely enough
+func ledStatement(stmts []Statement, group *CommentGroup) Statement {
+	end := group.End()
+
+	for _, stmt := range stmts {
+		pos := stmt.Pos()
+		if pos.Offset < end.Offset {
+			continue
+		}
+
+		if pos.Line <= end.Line+1 {
+			return stmt
+		}
+		return nil
+	}
+
+	return nil
+}
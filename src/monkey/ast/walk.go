@@ -0,0 +1,106 @@
+/*
+* File: ast/walk.go
+*
+* Description: Contains a function for visiting every node of an AST, so tools do not each need their own switch
+*              over the node types
+*
+ */
+
+package ast
+
+/*
+* Function: Inspect
+*
+* Parameters: node Node             - The root of the tree to visit
+*             f    func(Node) bool - Called for every node, children are only visited when it returns true
+*
+* Returns: none
+*
+* Description: Visits node and everything below it in depth first order, calling f on a parent before its children.
+*              Children are visited in the order they appear in the source. Missing children, such as the value of
+*              a let statement that could not be parsed, are skipped
+*
+ */
+func Inspect(node Node, f func(Node) bool) {
+	if isNil(node) || !f(node) {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		for _, stmt := range n.Statements {
+			Inspect(stmt, f)
+		}
+	case *LetStatement:
+		Inspect(n.Name, f)
+		Inspect(n.Value, f)
+	case *ReturnStatement:
+		Inspect(n.ReturnValue, f)
+	case *ExpressionStatement:
+		Inspect(n.Expression, f)
+	case *WhileStatement:
+		Inspect(n.Condition, f)
+		Inspect(n.Body, f)
+	case *ForInStatement:
+		for _, v := range n.Variables {
+			Inspect(v, f)
+		}
+		Inspect(n.Iterable, f)
+		Inspect(n.Body, f)
+	case *BlockStatement:
+		for _, stmt := range n.Statements {
+			Inspect(stmt, f)
+		}
+	case *PrefixExpression:
+		Inspect(n.Right, f)
+	case *InfixExpression:
+		Inspect(n.Left, f)
+		Inspect(n.Right, f)
+	case *LogicalExpression:
+		Inspect(n.Left, f)
+		Inspect(n.Right, f)
+	case *AssignExpression:
+		Inspect(n.Target, f)
+		Inspect(n.Value, f)
+	case *IfExpression:
+		Inspect(n.Condition, f)
+		Inspect(n.Consequence, f)
+		Inspect(n.Alternative, f)
+	case *FunctionLiteral:
+		for _, param := range n.Parameters {
+			Inspect(param, f)
+		}
+		Inspect(n.Body, f)
+	case *CallExpression:
+		Inspect(n.Function, f)
+		for _, arg := range n.Arguments {
+			Inspect(arg, f)
+		}
+	case *ArrayLiteral:
+		for _, el := range n.Elements {
+			Inspect(el, f)
+		}
+	case *IndexExpression:
+		Inspect(n.Left, f)
+		Inspect(n.Index, f)
+	case *HashLiteral:
+		for _, pair := range n.Pairs {
+			Inspect(pair.Key, f)
+			Inspect(pair.Value, f)
+		}
+	}
+}
+
+// isNil reports whether node is nil, including a nil pointer stored in the interface such as a missing Alternative
+func isNil(node Node) bool {
+	switch n := node.(type) {
+	case nil:
+		return true
+	case *Identifier:
+		return n == nil
+	case *BlockStatement:
+		return n == nil
+	default:
+		return false
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"io"
+	"testing"
+
+	"github.com/vtallen/go-interpreter/lexer"
+	"github.com/vtallen/go-interpreter/object"
+	"github.com/vtallen/go-interpreter/parser"
+)
+
+// fibonacci computes the 20th fibonacci number. The function is passed to itself so it can recurse without a name
+const fibonacci = `
+fn(fib) { fib(fib, 20) }(fn(self, n) {
+	if (n < 2) {
+		n
+	} else {
+		self(self, n - 1) + self(self, n - 2)
+	}
+})
+`
+
+// BenchmarkFibonacci compares the engines on a program that spends its time calling functions and doing arithmetic.
+// Run it with go test -bench Fibonacci -run ^$
+func BenchmarkFibonacci(b *testing.B) {
+	p := parser.New(lexer.New(fibonacci))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		b.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	for _, name := range []string{"eval", "vm"} {
+		run := engines[name]
+
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				result, err := run(program, &object.Array{}, io.Discard)
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				if integer, ok := result.(*object.Integer); !ok || integer.Value != 6765 {
+					b.Fatalf("wrong result: %v", result)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,318 @@
+/*
+* File: code/code.go
+*
+* Description: Defines the bytecode the compiler produces and the virtual machine runs, along with functions to
+*              encode and decode instructions
+*
+ */
+
+package code
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+/*
+* Type: Instructions
+*
+* Description: A sequence of encoded instructions. Each instruction is an opcode followed by its operands
+ */
+type Instructions []byte
+
+/*
+* Function: Instructions.String
+*
+* Parameters: none
+*
+* Returns: string - The instructions disassembled, one per line, prefixed with their offset
+ */
+func (ins Instructions) String() string {
+	var out bytes.Buffer
+
+	i := 0
+	for i < len(ins) {
+		def, err := Lookup(ins[i])
+		if err != nil {
+			fmt.Fprintf(&out, "ERROR: %s\n", err)
+			i++
+			continue
+		}
+
+		operands, read := ReadOperands(def, ins[i+1:])
+
+		fmt.Fprintf(&out, "%04d %s\n", i, ins.fmtInstruction(def, operands))
+
+		i += 1 + read
+	}
+
+	return out.String()
+}
+
+func (ins Instructions) fmtInstruction(def *Definition, operands []int) string {
+	operandCount := len(def.OperandWidths)
+
+	if len(operands) != operandCount {
+		return fmt.Sprintf("ERROR: operand len %d does not match defined %d\n", len(operands), operandCount)
+	}
+
+	switch operandCount {
+	case 0:
+		return def.Name
+	case 1:
+		return fmt.Sprintf("%s %d", def.Name, operands[0])
+	case 2:
+		return fmt.Sprintf("%s %d %d", def.Name, operands[0], operands[1])
+	}
+
+	return fmt.Sprintf("ERROR: unhandled operandCount for %s\n", def.Name)
+}
+
+type Opcode byte
+
+const (
+	OpConstant Opcode = iota // Pushes the constant at the index given by the operand
+
+	// Arithmetic and comparison, pop two values and push the result
+	OpAdd
+	OpSub
+	OpMul
+	OpDiv
+	OpMod
+	OpEqual
+	OpNotEqual
+	OpGreaterThan
+	OpLessThan
+	OpGreaterEqual
+	OpLessEqual
+	OpBitAnd
+	OpBitOr
+	OpBitXor
+	OpShiftLeft
+	OpShiftRight
+	OpRange
+	OpRangeInclusive
+
+	// Prefix operators, pop one value and push the result
+	OpMinus
+	OpBang
+	OpBitNot
+
+	OpPop // Pops the value on top of the stack, emitted after every expression statement
+
+	OpTrue
+	OpFalse
+	OpNull
+
+	OpJumpNotTruthy // Pops the condition and jumps to the operand if it is not truthy
+	OpJump          // Jumps to the operand
+
+	OpJumpNotTruthyOrPop // Jumps to the operand if the value on top of the stack is not truthy, pops the value otherwise
+	OpJumpTruthyOrPop    // Jumps to the operand if the value on top of the stack is truthy, pops the value otherwise
+
+	OpIter     // Replaces the value on top of the stack with an iterator over it
+	OpIterNext // Pushes as many values from the iterator on top of the stack as the second operand, jumps to the first when done
+	OpLoop     // Remembers the stack pointer for the loop at the nesting level given by the operand
+	OpLoopJump // Drops everything above the stack pointer remembered for the level given by the second operand, jumps to the first
+
+	OpGetGlobal
+	OpSetGlobal
+	OpGetLocal
+	OpSetLocal
+	OpGetBuiltin
+	OpGetFree
+
+	OpAssignGlobal  // Stores the value on top of the stack in a global that has to be bound already, leaving it on the stack
+	OpAssignLocal   // Like OpAssignGlobal, for a local
+	OpAssignFree    // Like OpAssignGlobal, for a free variable, which changes it for every closure that captured it
+	OpAssignBuiltin // Fails, the builtin given by the operand can not be assigned
+	OpCaptureLocal  // Pushes the cell of the local given by the operand, for OpClosure to capture
+	OpCaptureFree   // Pushes the cell of the free variable given by the operand, for OpClosure to capture
+
+	OpArray   // Builds an array from the number of values given by the operand
+	OpHash    // Builds a hash from the number of keys and values given by the operand
+	OpHashKey // Fails unless the value on top of the stack can be a hash key, leaving it there so the key is checked before its value runs
+	OpIndex
+	OpSetIndex // Pops a value, an index and the array or hash below them, stores the value at the index and pushes it
+	OpDupTwo   // Pushes copies of the two values on top of the stack, lets a[i] += x read a[i] without evaluating a and i twice
+
+	OpCall        // Calls the function below the number of arguments given by the operand
+	OpReturnValue // Returns the value on top of the stack from the current function
+	OpReturn      // Returns null from the current function
+
+	OpClosure // Wraps the function constant given by the first operand and that many cells from the stack in a closure
+)
+
+/*
+* Struct: Definition
+*
+* Description: Describes an opcode. OperandWidths holds the number of bytes each operand takes up
+ */
+type Definition struct {
+	Name          string
+	OperandWidths []int
+}
+
+var definitions = map[Opcode]*Definition{
+	OpConstant: {"OpConstant", []int{2}},
+
+	OpAdd:            {"OpAdd", []int{}},
+	OpSub:            {"OpSub", []int{}},
+	OpMul:            {"OpMul", []int{}},
+	OpDiv:            {"OpDiv", []int{}},
+	OpMod:            {"OpMod", []int{}},
+	OpEqual:          {"OpEqual", []int{}},
+	OpNotEqual:       {"OpNotEqual", []int{}},
+	OpGreaterThan:    {"OpGreaterThan", []int{}},
+	OpLessThan:       {"OpLessThan", []int{}},
+	OpGreaterEqual:   {"OpGreaterEqual", []int{}},
+	OpLessEqual:      {"OpLessEqual", []int{}},
+	OpBitAnd:         {"OpBitAnd", []int{}},
+	OpBitOr:          {"OpBitOr", []int{}},
+	OpBitXor:         {"OpBitXor", []int{}},
+	OpShiftLeft:      {"OpShiftLeft", []int{}},
+	OpShiftRight:     {"OpShiftRight", []int{}},
+	OpRange:          {"OpRange", []int{}},
+	OpRangeInclusive: {"OpRangeInclusive", []int{}},
+
+	OpMinus:  {"OpMinus", []int{}},
+	OpBang:   {"OpBang", []int{}},
+	OpBitNot: {"OpBitNot", []int{}},
+
+	OpPop: {"OpPop", []int{}},
+
+	OpTrue:  {"OpTrue", []int{}},
+	OpFalse: {"OpFalse", []int{}},
+	OpNull:  {"OpNull", []int{}},
+
+	OpJumpNotTruthy: {"OpJumpNotTruthy", []int{2}},
+	OpJump:          {"OpJump", []int{2}},
+
+	OpJumpNotTruthyOrPop: {"OpJumpNotTruthyOrPop", []int{2}},
+	OpJumpTruthyOrPop:    {"OpJumpTruthyOrPop", []int{2}},
+
+	OpIter:     {"OpIter", []int{}},
+	OpIterNext: {"OpIterNext", []int{2, 1}},
+	OpLoop:     {"OpLoop", []int{1}},
+	OpLoopJump: {"OpLoopJump", []int{2, 1}},
+
+	OpGetGlobal:  {"OpGetGlobal", []int{2}},
+	OpSetGlobal:  {"OpSetGlobal", []int{2}},
+	OpGetLocal:   {"OpGetLocal", []int{1}},
+	OpSetLocal:   {"OpSetLocal", []int{1}},
+	OpGetBuiltin: {"OpGetBuiltin", []int{1}},
+	OpGetFree:    {"OpGetFree", []int{1}},
+
+	OpAssignGlobal:  {"OpAssignGlobal", []int{2}},
+	OpAssignLocal:   {"OpAssignLocal", []int{1}},
+	OpAssignFree:    {"OpAssignFree", []int{1}},
+	OpAssignBuiltin: {"OpAssignBuiltin", []int{1}},
+	OpCaptureLocal:  {"OpCaptureLocal", []int{1}},
+	OpCaptureFree:   {"OpCaptureFree", []int{1}},
+
+	OpArray:   {"OpArray", []int{2}},
+	OpHash:    {"OpHash", []int{2}},
+	OpHashKey: {"OpHashKey", []int{}},
+	OpIndex:   {"OpIndex", []int{}},
+
+	OpSetIndex: {"OpSetIndex", []int{}},
+	OpDupTwo:   {"OpDupTwo", []int{}},
+
+	OpCall:        {"OpCall", []int{1}},
+	OpReturnValue: {"OpReturnValue", []int{}},
+	OpReturn:      {"OpReturn", []int{}},
+
+	OpClosure: {"OpClosure", []int{2, 1}},
+}
+
+/*
+* Function: Lookup
+*
+* Parameters: op byte - The opcode to look up
+*
+* Returns: *Definition - The definition of op
+*          error       - Non nil if op is not a known opcode
+ */
+func Lookup(op byte) (*Definition, error) {
+	def, ok := definitions[Opcode(op)]
+	if !ok {
+		return nil, fmt.Errorf("opcode %d undefined", op)
+	}
+
+	return def, nil
+}
+
+/*
+* Function: Make
+*
+* Parameters: op       Opcode - The opcode of the instruction
+*             operands ...int - The operands of the instruction
+*
+* Returns: []byte - The encoded instruction, empty if op is not a known opcode
+*
+* Description: Encodes an instruction. Operands are stored big endian using the widths from the definition of op
+ */
+func Make(op Opcode, operands ...int) []byte {
+	def, ok := definitions[op]
+	if !ok {
+		return []byte{}
+	}
+
+	instructionLen := 1
+	for _, w := range def.OperandWidths {
+		instructionLen += w
+	}
+
+	instruction := make([]byte, instructionLen)
+	instruction[0] = byte(op)
+
+	offset := 1
+	for i, o := range operands {
+		width := def.OperandWidths[i]
+		switch width {
+		case 2:
+			binary.BigEndian.PutUint16(instruction[offset:], uint16(o))
+		case 1:
+			instruction[offset] = byte(o)
+		}
+		offset += width
+	}
+
+	return instruction
+}
+
+/*
+* Function: ReadOperands
+*
+* Parameters: def *Definition  - The definition of the instruction being read
+*             ins Instructions - The instructions, starting right after the opcode
+*
+* Returns: []int - The decoded operands
+*          int   - The number of bytes read
+*
+* Description: Decodes the operands of an instruction, the opposite of Make
+ */
+func ReadOperands(def *Definition, ins Instructions) ([]int, int) {
+	operands := make([]int, len(def.OperandWidths))
+	offset := 0
+
+	for i, width := range def.OperandWidths {
+		switch width {
+		case 2:
+			operands[i] = int(ReadUint16(ins[offset:]))
+		case 1:
+			operands[i] = int(ReadUint8(ins[offset:]))
+		}
+
+		offset += width
+	}
+
+	return operands, offset
+}
+
+func ReadUint16(ins Instructions) uint16 {
+	return binary.BigEndian.Uint16(ins)
+}
+
+func ReadUint8(ins Instructions) uint8 { return uint8(ins[0]) }
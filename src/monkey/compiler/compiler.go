@@ -0,0 +1,1027 @@
+/*
+* File: compiler/compiler.go
+*
+* Description: Compiles the AST of a Monkey program to bytecode for the virtual machine
+*
+ */
+
+package compiler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vtallen/go-interpreter/ast"
+	"github.com/vtallen/go-interpreter/code"
+	"github.com/vtallen/go-interpreter/object"
+)
+
+/*
+* Struct: Bytecode
+*
+* Description: The output of the compiler, everything the virtual machine needs to run a program
+ */
+type Bytecode struct {
+	Instructions code.Instructions
+	Constants    []object.Object
+	GlobalNames  []string // The names of the globals, indexed by slot
+}
+
+/*
+* Struct: EmittedInstruction
+*
+* Description: Remembers an instruction that was emitted and where, so it can be looked at or removed again
+ */
+type EmittedInstruction struct {
+	Opcode   code.Opcode
+	Position int
+}
+
+/*
+* Struct: CompilationScope
+*
+* Description: The instructions of one function being compiled. The top level of the program is a scope too
+ */
+type CompilationScope struct {
+	instructions        code.Instructions
+	lastInstruction     EmittedInstruction
+	previousInstruction EmittedInstruction
+	loops               []*loop // The loops around the instruction being compiled, innermost last
+}
+
+// loop remembers where the break and continue statements of a loop being compiled jump to
+type loop struct {
+	start  int   // Position of the condition, which continue jumps back to
+	level  int   // Number of loops around this one in the same scope, the OpLoop of the loop records its stack pointer under it
+	breaks []int // Positions of the jumps emitted for break, patched to the end of the loop once it is known
+}
+
+/*
+* Struct: Compiler
+*
+* Description: Walks the AST and emits instructions. Function literals are compiled in a scope of their own and
+*              end up in the constant pool
+ */
+type Compiler struct {
+	constants []object.Object
+
+	symbolTable *SymbolTable
+
+	scopes     []CompilationScope
+	scopeIndex int
+
+	err error // The first operand that did not fit in its instruction, Compile returns it
+}
+
+/*
+* Function: New
+*
+* Parameters: none
+*
+* Returns: *Compiler - Pointer to a new compiler that knows about the builtin functions
+ */
+func New() *Compiler {
+	mainScope := CompilationScope{
+		instructions:        code.Instructions{},
+		lastInstruction:     EmittedInstruction{},
+		previousInstruction: EmittedInstruction{},
+	}
+
+	return &Compiler{
+		constants:   []object.Object{},
+		symbolTable: NewGlobalSymbolTable(),
+		scopes:      []CompilationScope{mainScope},
+		scopeIndex:  0,
+	}
+}
+
+/*
+* Function: NewWithState
+*
+* Parameters: s         *SymbolTable    - The global symbol table to compile against
+*             constants []object.Object - The constant pool to add to
+*
+* Returns: *Compiler - Pointer to a new compiler
+*
+* Description: Creates a compiler that continues where another one left off, so globals defined by an earlier
+*              program can be used by the next one. The symbol table is expected to come from NewGlobalSymbolTable
+ */
+func NewWithState(s *SymbolTable, constants []object.Object) *Compiler {
+	compiler := New()
+	compiler.symbolTable = s
+	compiler.constants = constants
+	return compiler
+}
+
+/*
+* Function: NewGlobalSymbolTable
+*
+* Parameters: none
+*
+* Returns: *SymbolTable - Pointer to a new global symbol table that knows about the builtin functions
+ */
+func NewGlobalSymbolTable() *SymbolTable {
+	symbolTable := NewSymbolTable()
+	for i, v := range object.Builtins {
+		symbolTable.DefineBuiltin(i, v.Name)
+	}
+	return symbolTable
+}
+
+/*
+* Function: Compiler.Compile
+*
+* Parameters: node ast.Node - The node to compile
+*
+* Returns: error - Non nil if the node can not be compiled, such as when it has more locals or constants than
+*                  the operands of the instructions can refer to
+*
+* Description: Compiles a node and all of its children. A missing node, such as the value of a let or return
+*              statement that has none, compiles to null
+ */
+func (c *Compiler) Compile(node ast.Node) error {
+	switch node := node.(type) {
+
+	// Statements
+	case *ast.Program:
+		for _, s := range node.Statements {
+			err := c.Compile(s)
+			if err != nil {
+				return err
+			}
+		}
+
+	case *ast.ExpressionStatement:
+		err := c.Compile(node.Expression)
+		if err != nil {
+			return err
+		}
+		c.emit(code.OpPop)
+
+	case *ast.BlockStatement:
+		for _, s := range node.Statements {
+			err := c.Compile(s)
+			if err != nil {
+				return err
+			}
+		}
+
+	case *ast.LetStatement:
+		// The name is defined after compiling the value, so the value sees what the name meant before, as in the
+		// evaluator. A function only uses the name once it is called, when the let statement has bound it, so it is
+		// defined first and the function can refer to itself
+		if _, ok := node.Value.(*ast.FunctionLiteral); ok {
+			c.symbolTable.Define(node.Name.Value)
+		}
+
+		err := c.Compile(node.Value)
+		if err != nil {
+			return err
+		}
+
+		c.storeSymbol(node.Name.Value)
+
+	case *ast.ReturnStatement:
+		err := c.Compile(node.ReturnValue)
+		if err != nil {
+			return err
+		}
+		c.emit(code.OpReturnValue)
+
+	case *ast.WhileStatement:
+		return c.compileWhileStatement(node)
+
+	case *ast.ForInStatement:
+		return c.compileForInStatement(node)
+
+	case *ast.BreakStatement:
+		lp := c.currentLoop()
+		if lp == nil {
+			return fmt.Errorf("break outside of a loop")
+		}
+
+		// Emit an `OpLoopJump` with a bogus jump, it is patched once the end of the loop is known
+		lp.breaks = append(lp.breaks, c.emit(code.OpLoopJump, 9999, lp.level))
+
+	case *ast.ContinueStatement:
+		lp := c.currentLoop()
+		if lp == nil {
+			return fmt.Errorf("continue outside of a loop")
+		}
+
+		c.emit(code.OpLoopJump, lp.start, lp.level)
+
+	// Expressions
+	case *ast.IntegerLiteral:
+		var integer object.Object = &object.Integer{Value: node.Value}
+		if node.Big != nil {
+			integer = &object.BigInt{Value: node.Big}
+		}
+		c.emit(code.OpConstant, c.addConstant(integer))
+
+	case *ast.FloatLiteral:
+		float := &object.Float{Value: node.Value}
+		c.emit(code.OpConstant, c.addConstant(float))
+
+	case *ast.StringLiteral:
+		str := &object.String{Value: node.Value}
+		c.emit(code.OpConstant, c.addConstant(str))
+
+	case *ast.Boolean:
+		if node.Value {
+			c.emit(code.OpTrue)
+		} else {
+			c.emit(code.OpFalse)
+		}
+
+	case *ast.PrefixExpression:
+		err := c.Compile(node.Right)
+		if err != nil {
+			return err
+		}
+
+		switch node.Operator {
+		case "!":
+			c.emit(code.OpBang)
+		case "-":
+			c.emit(code.OpMinus)
+		case "~":
+			c.emit(code.OpBitNot)
+		default:
+			return fmt.Errorf("unknown operator %s", node.Operator)
+		}
+
+	case *ast.InfixExpression:
+		err := c.Compile(node.Left)
+		if err != nil {
+			return err
+		}
+
+		err = c.Compile(node.Right)
+		if err != nil {
+			return err
+		}
+
+		op, ok := infixOperators[node.Operator]
+		if !ok {
+			return fmt.Errorf("unknown operator %s", node.Operator)
+		}
+		c.emit(op)
+
+	case *ast.LogicalExpression:
+		return c.compileLogicalExpression(node)
+
+	case *ast.AssignExpression:
+		return c.compileAssignExpression(node)
+
+	case *ast.IfExpression:
+		return c.compileIfExpression(node)
+
+	case *ast.Identifier:
+		c.loadSymbol(c.symbolTable.Reference(node.Value))
+
+	case *ast.FunctionLiteral:
+		return c.compileFunction(node)
+
+	case *ast.CallExpression:
+		err := c.Compile(node.Function)
+		if err != nil {
+			return err
+		}
+
+		for _, a := range node.Arguments {
+			err := c.Compile(a)
+			if err != nil {
+				return err
+			}
+		}
+
+		c.emit(code.OpCall, len(node.Arguments))
+
+	case *ast.ArrayLiteral:
+		for _, el := range node.Elements {
+			err := c.Compile(el)
+			if err != nil {
+				return err
+			}
+		}
+
+		c.emit(code.OpArray, len(node.Elements))
+
+	case *ast.HashLiteral:
+		for _, pair := range node.Pairs {
+			err := c.Compile(pair.Key)
+			if err != nil {
+				return err
+			}
+			if !isLiteralKey(pair.Key) {
+				c.emit(code.OpHashKey)
+			}
+
+			err = c.Compile(pair.Value)
+			if err != nil {
+				return err
+			}
+		}
+
+		c.emit(code.OpHash, len(node.Pairs)*2)
+
+	case *ast.IndexExpression:
+		err := c.Compile(node.Left)
+		if err != nil {
+			return err
+		}
+
+		err = c.Compile(node.Index)
+		if err != nil {
+			return err
+		}
+
+		c.emit(code.OpIndex)
+
+	// Placeholders left behind by the parser after a syntax error
+	case *ast.BadStatement:
+		return fmt.Errorf("syntax error at %s", node.Pos())
+
+	case *ast.BadExpression:
+		return fmt.Errorf("syntax error at %s", node.Pos())
+
+	case nil:
+		c.emit(code.OpNull)
+
+	default:
+		return fmt.Errorf("can not compile %T", node)
+	}
+
+	return c.err
+}
+
+// The opcodes of the infix operators, each pops both operands and pushes the result
+var infixOperators = map[string]code.Opcode{
+	"+":   code.OpAdd,
+	"-":   code.OpSub,
+	"*":   code.OpMul,
+	"/":   code.OpDiv,
+	"%":   code.OpMod,
+	"==":  code.OpEqual,
+	"!=":  code.OpNotEqual,
+	">":   code.OpGreaterThan,
+	"<":   code.OpLessThan,
+	">=":  code.OpGreaterEqual,
+	"<=":  code.OpLessEqual,
+	"&":   code.OpBitAnd,
+	"|":   code.OpBitOr,
+	"^":   code.OpBitXor,
+	"<<":  code.OpShiftLeft,
+	">>":  code.OpShiftRight,
+	"..":  code.OpRange,
+	"..=": code.OpRangeInclusive,
+}
+
+/*
+* Function: Compiler.compileIfExpression
+*
+* Parameters: node *ast.IfExpression - The if expression to compile
+*
+* Returns: error - Non nil if any part of the expression can not be compiled
+*
+* Description: Both branches leave their value on the stack. An if without an else gets an alternative that
+*              produces null, so the expression always has a value. An else if is compiled as the alternative, so
+*              every link of a chain jumps straight past the rest of it once a branch has run
+ */
+func (c *Compiler) compileIfExpression(node *ast.IfExpression) error {
+	err := c.Compile(node.Condition)
+	if err != nil {
+		return err
+	}
+
+	// Emit an `OpJumpNotTruthy` with a bogus value, it is patched once the consequence has been compiled
+	jumpNotTruthyPos := c.emit(code.OpJumpNotTruthy, 9999)
+
+	err = c.compileBlockValue(node.Consequence)
+	if err != nil {
+		return err
+	}
+
+	jumpPos := c.emit(code.OpJump, 9999)
+
+	c.changeOperand(jumpNotTruthyPos, len(c.currentInstructions()))
+
+	switch alt := node.Alternative.(type) {
+	case nil:
+		c.emit(code.OpNull)
+	case *ast.BlockStatement:
+		err := c.compileBlockValue(alt)
+		if err != nil {
+			return err
+		}
+	default:
+		// An else if is an expression, so it already leaves its value on the stack
+		err := c.Compile(alt)
+		if err != nil {
+			return err
+		}
+	}
+
+	c.changeOperand(jumpPos, len(c.currentInstructions()))
+
+	return nil
+}
+
+/*
+* Function: Compiler.compileWhileStatement
+*
+* Parameters: node *ast.WhileStatement - The loop to compile
+*
+* Returns: error - Non nil if the condition or the body can not be compiled
+*
+* Description: The condition is checked at the top of the loop and the body jumps back to it when it is done.
+*              The loop leaves nothing on the stack, the values of the statements in the body are popped as usual
+*              and the loop ends by popping a null, since it has no value of its own
+ */
+func (c *Compiler) compileWhileStatement(node *ast.WhileStatement) error {
+	lp := c.startLoop()
+
+	err := c.Compile(node.Condition)
+	if err != nil {
+		return err
+	}
+
+	// Emit an `OpJumpNotTruthy` with a bogus value, it is patched once the body has been compiled
+	exitPos := c.emit(code.OpJumpNotTruthy, 9999)
+
+	err = c.compileLoopBody(lp, node.Body)
+	if err != nil {
+		return err
+	}
+
+	end := len(c.currentInstructions())
+	c.changeOperand(exitPos, end)
+	c.patchBreaks(lp, end)
+
+	c.emitNoValue()
+
+	return nil
+}
+
+/*
+* Function: Compiler.compileForInStatement
+*
+* Parameters: node *ast.ForInStatement - The loop to compile
+*
+* Returns: error - Non nil if the iterable or the body can not be compiled
+*
+* Description: The iterator over the iterable stays on the stack while the loop runs. At the top of the loop
+*              OpIterNext pushes the next values, which are stored in the loop variables like a let statement
+*              would. Once the iterator is done, and on a break, the loop jumps to an OpPop that removes it
+ */
+func (c *Compiler) compileForInStatement(node *ast.ForInStatement) error {
+	err := c.Compile(node.Iterable)
+	if err != nil {
+		return err
+	}
+
+	c.emit(code.OpIter)
+
+	lp := c.startLoop()
+
+	// Emit an `OpIterNext` with a bogus jump, it is patched once the body has been compiled
+	nextPos := c.emit(code.OpIterNext, 9999, len(node.Variables))
+
+	// The values are pushed in the order of the variables, so the last one is on top
+	for i := len(node.Variables) - 1; i >= 0; i-- {
+		c.storeSymbol(node.Variables[i].Value)
+	}
+
+	err = c.compileLoopBody(lp, node.Body)
+	if err != nil {
+		return err
+	}
+
+	end := len(c.currentInstructions())
+	c.replaceInstruction(nextPos, c.makeInstruction(code.OpIterNext, end, len(node.Variables)))
+	c.patchBreaks(lp, end)
+
+	c.emit(code.OpPop)
+	c.emitNoValue()
+
+	return nil
+}
+
+// emitNoValue pushes and pops a null, so a statement without a value does not leave the last value it popped,
+// such as the condition of a loop, where LastPoppedStackElem of the virtual machine would find it
+func (c *Compiler) emitNoValue() {
+	c.emit(code.OpNull)
+	c.emit(code.OpPop)
+}
+
+// startLoop emits the OpLoop that records the stack pointer at the start of a new loop. break and continue reset
+// the stack pointer to it, dropping whatever the expression they appear in had pushed so far
+func (c *Compiler) startLoop() *loop {
+	lp := &loop{level: len(c.scopes[c.scopeIndex].loops)}
+	c.emit(code.OpLoop, lp.level)
+	lp.start = len(c.currentInstructions())
+
+	return lp
+}
+
+// patchBreaks makes the break statements of lp jump to end
+func (c *Compiler) patchBreaks(lp *loop, end int) {
+	for _, pos := range lp.breaks {
+		c.replaceInstruction(pos, c.makeInstruction(code.OpLoopJump, end, lp.level))
+	}
+}
+
+// compileLoopBody compiles the body of lp followed by the jump back to its start, with break and continue in the
+// body belonging to lp
+func (c *Compiler) compileLoopBody(lp *loop, body *ast.BlockStatement) error {
+	// The scope is looked up by index again afterwards, since compiling a function in the body grows c.scopes
+	index := c.scopeIndex
+	c.scopes[index].loops = append(c.scopes[index].loops, lp)
+
+	err := c.Compile(body)
+
+	loops := c.scopes[index].loops
+	c.scopes[index].loops = loops[:len(loops)-1]
+
+	if err != nil {
+		return err
+	}
+
+	c.emit(code.OpJump, lp.start)
+
+	return nil
+}
+
+// storeSymbol defines name in the current scope and pops the value on top of the stack into it
+func (c *Compiler) storeSymbol(name string) {
+	c.setSymbol(c.symbolTable.Define(name))
+}
+
+// setSymbol pops the value on top of the stack into s, which has to be a global or local binding
+func (c *Compiler) setSymbol(s Symbol) {
+	if s.Scope == GlobalScope {
+		c.emit(code.OpSetGlobal, s.Index)
+	} else {
+		c.emit(code.OpSetLocal, s.Index)
+	}
+}
+
+// currentLoop returns the innermost loop of the current scope, nil if the scope is not inside a loop
+func (c *Compiler) currentLoop() *loop {
+	loops := c.scopes[c.scopeIndex].loops
+	if len(loops) == 0 {
+		return nil
+	}
+	return loops[len(loops)-1]
+}
+
+/*
+* Function: Compiler.compileLogicalExpression
+*
+* Parameters: node *ast.LogicalExpression - The && or || expression to compile
+*
+* Returns: error - Non nil if either operand can not be compiled
+*
+* Description: When the left operand decides the result it is left on the stack and the right operand is jumped
+*              over, otherwise it is popped and the value of the right operand takes its place
+ */
+func (c *Compiler) compileLogicalExpression(node *ast.LogicalExpression) error {
+	err := c.Compile(node.Left)
+	if err != nil {
+		return err
+	}
+
+	jump := code.OpJumpNotTruthyOrPop
+	if node.Operator == "||" {
+		jump = code.OpJumpTruthyOrPop
+	}
+
+	jumpPos := c.emit(jump, 9999)
+
+	err = c.Compile(node.Right)
+	if err != nil {
+		return err
+	}
+
+	c.changeOperand(jumpPos, len(c.currentInstructions()))
+
+	return nil
+}
+
+/*
+* Function: Compiler.compileAssignExpression
+*
+* Parameters: node *ast.AssignExpression - The assignment to compile
+*
+* Returns: error - Non nil if the target can not be assigned to or either side can not be compiled
+*
+* Description: Leaves the assigned value on the stack, since an assignment is an expression. Like the evaluator,
+*              the compound forms read the name before the value, and plain = resolves the name once the value has
+*              been compiled, since the value can bind it. Names that can not be assigned fail when the assignment
+*              runs, not while compiling, so a program stops at the same point in both engines
+ */
+func (c *Compiler) compileAssignExpression(node *ast.AssignExpression) error {
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		if node.Operator != "=" {
+			symbol := c.symbolTable.Reference(target.Value)
+			if symbol.Scope == BuiltinScope {
+				// Reading a builtin succeeds, so the assignment has to fail before the value is evaluated
+				c.emit(code.OpAssignBuiltin, symbol.Index)
+				return nil
+			}
+
+			c.loadSymbol(symbol)
+		}
+
+		err := c.compileAssignedValue(node)
+		if err != nil {
+			return err
+		}
+
+		c.assignSymbol(c.symbolTable.Reference(target.Value))
+
+	case *ast.IndexExpression:
+		err := c.Compile(target.Left)
+		if err != nil {
+			return err
+		}
+
+		err = c.Compile(target.Index)
+		if err != nil {
+			return err
+		}
+
+		if node.Operator != "=" {
+			c.emit(code.OpDupTwo)
+			c.emit(code.OpIndex)
+		}
+
+		err = c.compileAssignedValue(node)
+		if err != nil {
+			return err
+		}
+
+		c.emit(code.OpSetIndex)
+
+	default:
+		return fmt.Errorf("cannot assign to %s", node.Target.String())
+	}
+
+	return nil
+}
+
+// compileAssignedValue compiles the right side of an assignment, combining it with the current value already on the
+// stack for the compound forms
+func (c *Compiler) compileAssignedValue(node *ast.AssignExpression) error {
+	err := c.Compile(node.Value)
+	if err != nil || node.Operator == "=" {
+		return err
+	}
+
+	op, ok := infixOperators[strings.TrimSuffix(node.Operator, "=")]
+	if !ok {
+		return fmt.Errorf("unknown operator %s", node.Operator)
+	}
+	c.emit(op)
+
+	return nil
+}
+
+// compileBlockValue compiles a block so that the value of its last expression stays on the stack
+func (c *Compiler) compileBlockValue(block *ast.BlockStatement) error {
+	err := c.Compile(block)
+	if err != nil {
+		return err
+	}
+
+	if c.lastInstructionIs(code.OpPop) {
+		c.removeLastPop()
+	} else if !c.lastInstructionIs(code.OpReturnValue) {
+		c.emit(code.OpNull)
+	}
+
+	return nil
+}
+
+/*
+* Function: Compiler.compileFunction
+*
+* Parameters: node *ast.FunctionLiteral - The function literal to compile
+*
+* Returns: error - Non nil if the body of the function can not be compiled
+*
+* Description: Compiles the body of a function in a new scope, adds the compiled function to the constant pool
+*              and emits the instructions that push the cells of the free variables it uses and wrap it in a
+*              closure
+ */
+func (c *Compiler) compileFunction(node *ast.FunctionLiteral) error {
+	c.enterScope()
+
+	c.symbolTable.Bound = map[string]bool{}
+	boundNames(node.Body, c.symbolTable.Bound)
+
+	for _, p := range node.Parameters {
+		c.symbolTable.Define(p.Value)
+	}
+
+	err := c.Compile(node.Body)
+	if err != nil {
+		return err
+	}
+
+	// The value of the last expression is returned implicitly
+	if c.lastInstructionIs(code.OpPop) {
+		c.replaceLastPopWithReturn()
+	}
+	if !c.lastInstructionIs(code.OpReturnValue) {
+		c.emit(code.OpReturn)
+	}
+
+	freeSymbols := c.symbolTable.FreeSymbols
+	localNames := c.symbolTable.Names()
+	instructions := c.leaveScope()
+
+	freeNames := make([]string, len(freeSymbols))
+	for i, s := range freeSymbols {
+		c.captureSymbol(s)
+		freeNames[i] = s.Name
+	}
+
+	compiledFn := &object.CompiledFunction{
+		Instructions:  instructions,
+		NumLocals:     len(localNames),
+		NumParameters: len(node.Parameters),
+		LocalNames:    localNames,
+		FreeNames:     freeNames,
+		Literal:       object.InspectFunction(node.Parameters, node.Body),
+	}
+
+	fnIndex := c.addConstant(compiledFn)
+	c.emit(code.OpClosure, fnIndex, len(freeSymbols))
+
+	return nil
+}
+
+// boundNames adds the names that let and for-in statements in node bind to names. The bodies of function literals
+// are skipped, what they bind belongs to their own calls
+func boundNames(node ast.Node, names map[string]bool) {
+	switch node := node.(type) {
+	case *ast.BlockStatement:
+		for _, s := range node.Statements {
+			boundNames(s, names)
+		}
+	case *ast.LetStatement:
+		names[node.Name.Value] = true
+		boundNames(node.Value, names)
+	case *ast.ForInStatement:
+		for _, v := range node.Variables {
+			names[v.Value] = true
+		}
+		boundNames(node.Iterable, names)
+		boundNames(node.Body, names)
+	case *ast.WhileStatement:
+		boundNames(node.Condition, names)
+		boundNames(node.Body, names)
+	case *ast.ExpressionStatement:
+		boundNames(node.Expression, names)
+	case *ast.ReturnStatement:
+		boundNames(node.ReturnValue, names)
+	case *ast.PrefixExpression:
+		boundNames(node.Right, names)
+	case *ast.InfixExpression:
+		boundNames(node.Left, names)
+		boundNames(node.Right, names)
+	case *ast.LogicalExpression:
+		boundNames(node.Left, names)
+		boundNames(node.Right, names)
+	case *ast.AssignExpression:
+		boundNames(node.Target, names)
+		boundNames(node.Value, names)
+	case *ast.IfExpression:
+		boundNames(node.Condition, names)
+		boundNames(node.Consequence, names)
+		boundNames(node.Alternative, names)
+	case *ast.CallExpression:
+		boundNames(node.Function, names)
+		for _, a := range node.Arguments {
+			boundNames(a, names)
+		}
+	case *ast.ArrayLiteral:
+		for _, e := range node.Elements {
+			boundNames(e, names)
+		}
+	case *ast.HashLiteral:
+		for _, pair := range node.Pairs {
+			boundNames(pair.Key, names)
+			boundNames(pair.Value, names)
+		}
+	case *ast.IndexExpression:
+		boundNames(node.Left, names)
+		boundNames(node.Index, names)
+	}
+}
+
+// Literal keys can always be used in a hash, only computed keys need OpHashKey to check them
+func isLiteralKey(node ast.Expression) bool {
+	switch node.(type) {
+	case *ast.IntegerLiteral, *ast.StringLiteral, *ast.Boolean:
+		return true
+	default:
+		return false
+	}
+}
+
+/*
+* Function: Compiler.Bytecode
+*
+* Parameters: none
+*
+* Returns: *Bytecode - The instructions and constants compiled so far
+ */
+func (c *Compiler) Bytecode() *Bytecode {
+	return &Bytecode{
+		Instructions: c.currentInstructions(),
+		Constants:    c.constants,
+		GlobalNames:  c.symbolTable.Names(),
+	}
+}
+
+func (c *Compiler) addConstant(obj object.Object) int {
+	c.constants = append(c.constants, obj)
+	return len(c.constants) - 1
+}
+
+// makeInstruction is code.Make, but an operand that does not fit in its width is recorded in c.err instead of being
+// cut short, which would make the instruction refer to the wrong constant, local or jump target
+func (c *Compiler) makeInstruction(op code.Opcode, operands ...int) []byte {
+	def, err := code.Lookup(byte(op))
+	if err == nil && c.err == nil {
+		for i, o := range operands {
+			max := 1<<(8*def.OperandWidths[i]) - 1
+			if o > max {
+				c.err = operandLimitError(op, i, max)
+				break
+			}
+		}
+	}
+
+	return code.Make(op, operands...)
+}
+
+// operandLimitError describes what a program has too many of when operand i of op is over max
+func operandLimitError(op code.Opcode, i int, max int) error {
+	switch {
+	case op == code.OpConstant || op == code.OpClosure && i == 0:
+		return fmt.Errorf("too many constants: the limit is %d", max+1)
+	case op == code.OpGetGlobal || op == code.OpSetGlobal || op == code.OpAssignGlobal:
+		return fmt.Errorf("too many globals: the limit is %d", max+1)
+	case op == code.OpGetLocal || op == code.OpSetLocal || op == code.OpAssignLocal || op == code.OpCaptureLocal:
+		return fmt.Errorf("too many locals in a function: the limit is %d", max+1)
+	case op == code.OpGetFree || op == code.OpAssignFree || op == code.OpCaptureFree || op == code.OpClosure:
+		return fmt.Errorf("too many free variables in a function: the limit is %d", max)
+	case op == code.OpCall:
+		return fmt.Errorf("too many arguments in a call: the limit is %d", max)
+	case op == code.OpArray:
+		return fmt.Errorf("too many elements in an array literal: the limit is %d", max)
+	case op == code.OpHash:
+		return fmt.Errorf("too many pairs in a hash literal: the limit is %d", max/2)
+	case op == code.OpLoop || op == code.OpLoopJump && i == 1:
+		return fmt.Errorf("too many nested loops in a function: the limit is %d", max+1)
+	default:
+		return fmt.Errorf("too much code to jump over: the limit is %d bytes", max)
+	}
+}
+
+// emit adds an instruction to the current scope and returns the position it starts at
+func (c *Compiler) emit(op code.Opcode, operands ...int) int {
+	ins := c.makeInstruction(op, operands...)
+	pos := c.addInstruction(ins)
+
+	c.setLastInstruction(op, pos)
+
+	return pos
+}
+
+func (c *Compiler) addInstruction(ins []byte) int {
+	posNewInstruction := len(c.currentInstructions())
+	updatedInstructions := append(c.currentInstructions(), ins...)
+
+	c.scopes[c.scopeIndex].instructions = updatedInstructions
+
+	return posNewInstruction
+}
+
+func (c *Compiler) setLastInstruction(op code.Opcode, pos int) {
+	previous := c.scopes[c.scopeIndex].lastInstruction
+	last := EmittedInstruction{Opcode: op, Position: pos}
+
+	c.scopes[c.scopeIndex].previousInstruction = previous
+	c.scopes[c.scopeIndex].lastInstruction = last
+}
+
+func (c *Compiler) lastInstructionIs(op code.Opcode) bool {
+	if len(c.currentInstructions()) == 0 {
+		return false
+	}
+
+	return c.scopes[c.scopeIndex].lastInstruction.Opcode == op
+}
+
+func (c *Compiler) removeLastPop() {
+	last := c.scopes[c.scopeIndex].lastInstruction
+	previous := c.scopes[c.scopeIndex].previousInstruction
+
+	old := c.currentInstructions()
+	new := old[:last.Position]
+
+	c.scopes[c.scopeIndex].instructions = new
+	c.scopes[c.scopeIndex].lastInstruction = previous
+}
+
+func (c *Compiler) replaceLastPopWithReturn() {
+	lastPos := c.scopes[c.scopeIndex].lastInstruction.Position
+	c.replaceInstruction(lastPos, code.Make(code.OpReturnValue))
+
+	c.scopes[c.scopeIndex].lastInstruction.Opcode = code.OpReturnValue
+}
+
+func (c *Compiler) replaceInstruction(pos int, newInstruction []byte) {
+	ins := c.currentInstructions()
+
+	for i := 0; i < len(newInstruction); i++ {
+		ins[pos+i] = newInstruction[i]
+	}
+}
+
+// changeOperand replaces the operand of the instruction at opPos, used to patch jumps once their target is known
+func (c *Compiler) changeOperand(opPos int, operand int) {
+	op := code.Opcode(c.currentInstructions()[opPos])
+	newInstruction := c.makeInstruction(op, operand)
+
+	c.replaceInstruction(opPos, newInstruction)
+}
+
+func (c *Compiler) currentInstructions() code.Instructions {
+	return c.scopes[c.scopeIndex].instructions
+}
+
+func (c *Compiler) enterScope() {
+	scope := CompilationScope{
+		instructions:        code.Instructions{},
+		lastInstruction:     EmittedInstruction{},
+		previousInstruction: EmittedInstruction{},
+	}
+	c.scopes = append(c.scopes, scope)
+	c.scopeIndex++
+
+	c.symbolTable = NewEnclosedSymbolTable(c.symbolTable)
+}
+
+func (c *Compiler) leaveScope() code.Instructions {
+	instructions := c.currentInstructions()
+
+	c.scopes = c.scopes[:len(c.scopes)-1]
+	c.scopeIndex--
+
+	c.symbolTable = c.symbolTable.Outer
+
+	return instructions
+}
+
+func (c *Compiler) loadSymbol(s Symbol) {
+	switch s.Scope {
+	case GlobalScope:
+		c.emit(code.OpGetGlobal, s.Index)
+	case LocalScope:
+		c.emit(code.OpGetLocal, s.Index)
+	case BuiltinScope:
+		c.emit(code.OpGetBuiltin, s.Index)
+	case FreeScope:
+		c.emit(code.OpGetFree, s.Index)
+	}
+}
+
+// assignSymbol stores the value on top of the stack in s and leaves it there as the value of the assignment
+func (c *Compiler) assignSymbol(s Symbol) {
+	switch s.Scope {
+	case GlobalScope:
+		c.emit(code.OpAssignGlobal, s.Index)
+	case LocalScope:
+		c.emit(code.OpAssignLocal, s.Index)
+	case FreeScope:
+		c.emit(code.OpAssignFree, s.Index)
+	case BuiltinScope:
+		c.emit(code.OpAssignBuiltin, s.Index)
+	}
+}
+
+// captureSymbol pushes the cell of s, a local or free variable of the enclosing function, for OpClosure to capture
+func (c *Compiler) captureSymbol(s Symbol) {
+	if s.Scope == LocalScope {
+		c.emit(code.OpCaptureLocal, s.Index)
+	} else {
+		c.emit(code.OpCaptureFree, s.Index)
+	}
+}
@@ -0,0 +1,193 @@
+/*
+* File: compiler/symbol_table.go
+*
+* Description: Contains the symbol table the compiler uses to decide where the value bound to a name lives
+*
+ */
+
+package compiler
+
+type SymbolScope string
+
+const (
+	GlobalScope  SymbolScope = "GLOBAL"  // Bound at the top level of the program
+	LocalScope   SymbolScope = "LOCAL"   // Bound inside the function being compiled
+	BuiltinScope SymbolScope = "BUILTIN" // One of the builtin functions
+	FreeScope    SymbolScope = "FREE"    // Bound in an enclosing function and captured by a closure
+)
+
+/*
+* Struct: Symbol
+*
+* Description: Everything the compiler knows about a name. Index is the slot the value is stored in, its meaning
+*              depends on the scope
+ */
+type Symbol struct {
+	Name  string
+	Scope SymbolScope
+	Index int
+}
+
+/*
+* Struct: SymbolTable
+*
+* Description: Maps names to symbols. Every function being compiled gets its own table enclosed by the table of
+*              the code around it
+ */
+type SymbolTable struct {
+	Outer *SymbolTable // The enclosing table, nil for the global table
+
+	store map[string]Symbol
+	names []string // The names defined in this table, the name of slot i is names[i]
+
+	FreeSymbols []Symbol // The symbols of the enclosing tables the function uses, in the order they were captured
+
+	Bound map[string]bool // The names let and for-in statements bind anywhere in the body, set before it is compiled
+}
+
+/*
+* Function: NewSymbolTable
+*
+* Parameters: none
+*
+* Returns: *SymbolTable - Pointer to a new, empty global symbol table
+ */
+func NewSymbolTable() *SymbolTable {
+	s := make(map[string]Symbol)
+	free := []Symbol{}
+	return &SymbolTable{store: s, FreeSymbols: free}
+}
+
+/*
+* Function: NewEnclosedSymbolTable
+*
+* Parameters: outer *SymbolTable - The table to enclose
+*
+* Returns: *SymbolTable - Pointer to a new symbol table for the body of a function
+ */
+func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
+	s := NewSymbolTable()
+	s.Outer = outer
+	return s
+}
+
+/*
+* Function: SymbolTable.Define
+*
+* Parameters: name string - The name to define
+*
+* Returns: Symbol - The symbol name is now bound to
+*
+* Description: Binds name to the next free slot, global or local depending on whether the table is enclosed.
+*              Defining a name that is already defined in the same table reuses its slot
+ */
+func (s *SymbolTable) Define(name string) Symbol {
+	if symbol, ok := s.store[name]; ok && (symbol.Scope == GlobalScope || symbol.Scope == LocalScope) {
+		return symbol
+	}
+
+	symbol := Symbol{Name: name, Index: len(s.names)}
+	if s.Outer == nil {
+		symbol.Scope = GlobalScope
+	} else {
+		symbol.Scope = LocalScope
+	}
+
+	s.store[name] = symbol
+	s.names = append(s.names, name)
+	return symbol
+}
+
+// Names returns the names defined in this table, indexed by slot. The virtual machine uses them in error messages
+func (s *SymbolTable) Names() []string {
+	return s.names
+}
+
+/*
+* Function: SymbolTable.DefineBuiltin
+*
+* Parameters: index int    - The index of the builtin in object.Builtins
+*             name  string - The name of the builtin
+*
+* Returns: Symbol - The symbol name is now bound to
+ */
+func (s *SymbolTable) DefineBuiltin(index int, name string) Symbol {
+	symbol := Symbol{Name: name, Index: index, Scope: BuiltinScope}
+	s.store[name] = symbol
+	return symbol
+}
+
+/*
+* Function: SymbolTable.Resolve
+*
+* Parameters: name string - The name to look up
+*
+* Returns: Symbol - The symbol name is bound to
+*          bool   - True if name was found in this table or any enclosing table
+*
+* Description: Looks up a name, walking outwards through the enclosing tables. Local bindings of an enclosing
+*              function are turned into free symbols of this table, so the closure captures them
+ */
+func (s *SymbolTable) Resolve(name string) (Symbol, bool) {
+	obj, ok := s.store[name]
+	if !ok && s.Outer != nil {
+		obj, ok = s.Outer.Resolve(name)
+		if !ok {
+			return obj, ok
+		}
+
+		if obj.Scope == GlobalScope || obj.Scope == BuiltinScope {
+			return obj, ok
+		}
+
+		free := s.defineFree(obj)
+		return free, true
+	}
+
+	return obj, ok
+}
+
+/*
+* Function: SymbolTable.Reference
+*
+* Parameters: name string - The name being used
+*
+* Returns: Symbol - The symbol name is bound to
+*
+* Description: Resolves name, reserving a slot for it if it is not bound yet. The slot goes to the innermost
+*              function that binds name further down its body, so a closure made before the let statement runs
+*              sees the value once it does, like in the evaluator. Otherwise a global slot is reserved, which a
+*              let statement at the top level that comes later fills. If no let statement ever fills the slot,
+*              the virtual machine reports the name when the code using it runs, which is when the evaluator
+*              reports it too
+ */
+func (s *SymbolTable) Reference(name string) Symbol {
+	if symbol, ok := s.Resolve(name); ok {
+		return symbol
+	}
+
+	for table := s; table.Outer != nil; table = table.Outer {
+		if table.Bound[name] {
+			table.Define(name)
+			symbol, _ := s.Resolve(name)
+			return symbol
+		}
+	}
+
+	global := s
+	for global.Outer != nil {
+		global = global.Outer
+	}
+
+	return global.Define(name)
+}
+
+func (s *SymbolTable) defineFree(original Symbol) Symbol {
+	s.FreeSymbols = append(s.FreeSymbols, original)
+
+	symbol := Symbol{Name: original.Name, Index: len(s.FreeSymbols) - 1}
+	symbol.Scope = FreeScope
+
+	s.store[original.Name] = symbol
+	return symbol
+}
@@ -0,0 +1,121 @@
+package compiler
+
+import "testing"
+
+func TestDefine(t *testing.T) {
+	expected := map[string]Symbol{
+		"a": {Name: "a", Scope: GlobalScope, Index: 0},
+		"b": {Name: "b", Scope: GlobalScope, Index: 1},
+		"c": {Name: "c", Scope: LocalScope, Index: 0},
+		"d": {Name: "d", Scope: LocalScope, Index: 1},
+	}
+
+	global := NewSymbolTable()
+
+	if a := global.Define("a"); a != expected["a"] {
+		t.Errorf("expected a=%+v, got=%+v", expected["a"], a)
+	}
+
+	if b := global.Define("b"); b != expected["b"] {
+		t.Errorf("expected b=%+v, got=%+v", expected["b"], b)
+	}
+
+	// Defining a name again reuses its slot
+	if a := global.Define("a"); a != expected["a"] {
+		t.Errorf("expected redefined a=%+v, got=%+v", expected["a"], a)
+	}
+
+	local := NewEnclosedSymbolTable(global)
+
+	if c := local.Define("c"); c != expected["c"] {
+		t.Errorf("expected c=%+v, got=%+v", expected["c"], c)
+	}
+
+	if d := local.Define("d"); d != expected["d"] {
+		t.Errorf("expected d=%+v, got=%+v", expected["d"], d)
+	}
+
+	if names := global.Names(); len(names) != 2 || names[0] != "a" || names[1] != "b" {
+		t.Errorf("global names wrong. expected=[a b], got=%v", names)
+	}
+
+	if names := local.Names(); len(names) != 2 || names[0] != "c" || names[1] != "d" {
+		t.Errorf("local names wrong. expected=[c d], got=%v", names)
+	}
+}
+
+func TestResolveFree(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+	global.DefineBuiltin(0, "len")
+
+	firstLocal := NewEnclosedSymbolTable(global)
+	firstLocal.Define("c")
+
+	secondLocal := NewEnclosedSymbolTable(firstLocal)
+	secondLocal.Define("e")
+
+	expected := []Symbol{
+		{Name: "a", Scope: GlobalScope, Index: 0},
+		{Name: "len", Scope: BuiltinScope, Index: 0},
+		{Name: "c", Scope: FreeScope, Index: 0},
+		{Name: "e", Scope: LocalScope, Index: 0},
+	}
+
+	for _, sym := range expected {
+		result, ok := secondLocal.Resolve(sym.Name)
+		if !ok {
+			t.Errorf("name %s not resolvable", sym.Name)
+			continue
+		}
+		if result != sym {
+			t.Errorf("expected %s to resolve to %+v, got=%+v", sym.Name, sym, result)
+		}
+	}
+
+	expectedFree := []Symbol{{Name: "c", Scope: LocalScope, Index: 0}}
+	if len(secondLocal.FreeSymbols) != len(expectedFree) || secondLocal.FreeSymbols[0] != expectedFree[0] {
+		t.Errorf("wrong free symbols. expected=%+v, got=%+v", expectedFree, secondLocal.FreeSymbols)
+	}
+
+	if _, ok := secondLocal.Resolve("b"); ok {
+		t.Errorf("name b resolved, but was expected not to")
+	}
+}
+
+func TestReference(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+
+	local := NewEnclosedSymbolTable(global)
+	local.Define("b")
+
+	expected := []Symbol{
+		{Name: "a", Scope: GlobalScope, Index: 0},
+		{Name: "b", Scope: LocalScope, Index: 0},
+		// A name bound nowhere is given the next global slot, which a later let at the top level reuses
+		{Name: "c", Scope: GlobalScope, Index: 1},
+	}
+
+	for _, sym := range expected {
+		if result := local.Reference(sym.Name); result != sym {
+			t.Errorf("expected %s to resolve to %+v, got=%+v", sym.Name, sym, result)
+		}
+	}
+
+	if c := global.Define("c"); c != expected[2] {
+		t.Errorf("expected c=%+v, got=%+v", expected[2], c)
+	}
+
+	// A name the enclosing function binds later gets a slot there instead, captured through every table in between
+	local.Bound = map[string]bool{"d": true}
+	inner := NewEnclosedSymbolTable(local)
+
+	if d := inner.Reference("d"); d != (Symbol{Name: "d", Scope: FreeScope, Index: 0}) {
+		t.Errorf("expected d to be free, got=%+v", d)
+	}
+
+	if d := local.Define("d"); d != (Symbol{Name: "d", Scope: LocalScope, Index: 1}) {
+		t.Errorf("expected d to reuse the local slot reserved for it, got=%+v", d)
+	}
+}
@@ -0,0 +1,128 @@
+/*
+* File: diagnostic/diagnostic.go
+*
+* Description: Defines the structured diagnostics reported while lexing and parsing Monkey programs
+*
+ */
+
+package diagnostic
+
+import (
+	"fmt"
+
+	"github.com/vtallen/go-interpreter/token"
+)
+
+type Severity int
+
+const (
+	Error   Severity = iota // The program can not be run
+	Warning                 // The program can be run, but probably does not do what was intended
+	Note                    // Extra information attached to another diagnostic
+)
+
+var severityNames = map[Severity]string{
+	Error:   "error",
+	Warning: "warning",
+	Note:    "note",
+}
+
+func (s Severity) String() string {
+	if name, ok := severityNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("severity(%d)", int(s))
+}
+
+// Severities are written by name when a diagnostic is marshalled, so CI tools do not need to know the numbering
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+/*
+* Type: Code
+*
+* Description: A stable, machine readable identifier for the kind of problem a diagnostic describes. Messages may
+*              change wording over time, codes do not
+ */
+type Code string
+
+const (
+	UnexpectedToken Code = "P0001" // A specific token was expected and something else was found
+	NoPrefixParseFn Code = "P0002" // The token can not start an expression
+	InvalidInteger  Code = "P0003" // An integer literal could not be converted to a value
+	InvalidFloat    Code = "P0004" // A float literal could not be converted to a value
+	OutsideLoop     Code = "P0005" // A break or continue is not inside a loop
+	InvalidTarget   Code = "P0006" // The left side of an assignment is not a name or an index expression
+
+	UnterminatedString  Code = "L0001" // A string literal is missing its closing quote
+	InvalidEscape       Code = "L0002" // A string literal contains an escape sequence that does not exist
+	InvalidNumber       Code = "L0003" // A number literal is malformed, such as 0x without digits or 1__0
+	UnterminatedComment Code = "L0004" // A /* comment is missing its closing */
+	InvalidUTF8         Code = "L0005" // The source contains bytes that are not valid UTF-8
+	ReadError           Code = "L0006" // The source could not be read to the end
+	InvalidCharacter    Code = "L0007" // The source contains a NUL character, which has no meaning in Monkey
+)
+
+/*
+* Struct: Span
+*
+* Description: The range of source code a diagnostic refers to. End is the position immediately after the last
+*              character, so an empty span (Start == End) points in between two characters
+ */
+type Span struct {
+	Start token.Position `json:"start"`
+	End   token.Position `json:"end"`
+}
+
+/*
+* Function: SpanOf
+*
+* Parameters: tok token.Token - The token to get the span of
+*
+* Returns: Span - The span covering tok
+ */
+func SpanOf(tok token.Token) Span {
+	return Span{Start: tok.Pos, End: tok.End}
+}
+
+/*
+* Struct: FixIt
+*
+* Description: A suggestion for fixing the problem a diagnostic describes. Replacing the source code covered by
+*              Span with Replacement applies the fix, an empty Span means Replacement should be inserted
+ */
+type FixIt struct {
+	Message     string `json:"message"`
+	Span        Span   `json:"span"`
+	Replacement string `json:"replacement"`
+}
+
+/*
+* Struct: Diagnostic
+*
+* Description: A problem found in a Monkey program along with where it was found and, when known, what the lexer
+*              or parser expected to find there
+ */
+type Diagnostic struct {
+	Severity Severity          `json:"severity"`
+	Code     Code              `json:"code"`
+	Message  string            `json:"message"`
+	Span     Span              `json:"span"`
+	Expected []token.TokenType `json:"expected,omitempty"` // The token types that would have been valid
+	Actual   token.TokenType   `json:"actual,omitempty"`   // The token type that was found instead
+	Hints    []FixIt           `json:"hints,omitempty"`
+}
+
+/*
+* Function: Diagnostic.Error
+*
+* Parameters: none
+*
+* Returns: string - The diagnostic formatted as file:line:column: severity[code]: message
+*
+* Description: Formats the diagnostic on a single line. This also lets a Diagnostic be used as an error
+ */
+func (d Diagnostic) Error() string {
+	return fmt.Sprintf("%s: %s[%s]: %s", d.Span.Start, d.Severity, d.Code, d.Message)
+}
@@ -0,0 +1,97 @@
+/*
+* File: diagnostic/render.go
+*
+* Description: Prints diagnostics along with the line of source code they refer to
+*
+ */
+
+package diagnostic
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+/*
+* Function: Render
+*
+* Parameters: w      io.Writer  - Where to write the rendered diagnostic
+*             source string     - The source code the diagnostic was reported for
+*             d      Diagnostic - The diagnostic to render
+*
+* Returns: none
+*
+* Description: Writes the diagnostic followed by the offending source line with the span underlined, for example:
+*
+*                test.mk:1:10: error[P0001]: expected next token to be ), got ; instead
+*                   1 | add(1, 2;
+*                     |         ^
+*                  hint: insert ")"
+ */
+func Render(w io.Writer, source string, d Diagnostic) {
+	fmt.Fprintln(w, d.Error())
+
+	start := d.Span.Start
+	if start.IsValid() && start.Offset <= len(source) {
+		lineStart := strings.LastIndexByte(source[:start.Offset], '\n') + 1
+		lineEnd := len(source)
+		if i := strings.IndexByte(source[lineStart:], '\n'); i >= 0 {
+			lineEnd = lineStart + i
+		}
+		line := strings.TrimSuffix(source[lineStart:lineEnd], "\r")
+
+		// Spans running past the end of the line are cut off there, empty spans still get a single caret
+		end := d.Span.End.Offset
+		if end > lineStart+len(line) || d.Span.End.Line != start.Line {
+			end = lineStart + len(line)
+		}
+		if end < start.Offset {
+			end = start.Offset
+		}
+
+		gutter := fmt.Sprintf("%4d | ", start.Line)
+		fmt.Fprintf(w, "%s%s\n", gutter, line)
+		fmt.Fprintf(w, "%s| %s%s\n",
+			strings.Repeat(" ", len(gutter)-2),
+			padding(line[:min(start.Offset-lineStart, len(line))]),
+			strings.Repeat("^", max(utf8.RuneCountInString(source[start.Offset:end]), 1)))
+	}
+
+	for _, hint := range d.Hints {
+		fmt.Fprintf(w, "  hint: %s\n", hint.Message)
+	}
+}
+
+/*
+* Function: RenderAll
+*
+* Parameters: w      io.Writer    - Where to write the rendered diagnostics
+*             source string       - The source code the diagnostics were reported for
+*             diags  []Diagnostic - The diagnostics to render
+*
+* Returns: none
+*
+* Description: Renders every diagnostic in order
+ */
+func RenderAll(w io.Writer, source string, diags []Diagnostic) {
+	for _, d := range diags {
+		Render(w, source, d)
+	}
+}
+
+// padding returns whitespace as wide as prefix, keeping tabs so the caret lines up with the source line
+func padding(prefix string) string {
+	var out strings.Builder
+
+	for _, r := range prefix {
+		if r == '\t' {
+			out.WriteRune('\t')
+		} else {
+			out.WriteRune(' ')
+		}
+	}
+
+	return out.String()
+}
@@ -0,0 +1,62 @@
+package diagnostic
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/vtallen/go-interpreter/token"
+)
+
+func TestRender(t *testing.T) {
+	source := "let x = 1;\n\tadd(1, 2;\n"
+
+	d := Diagnostic{
+		Severity: Error,
+		Code:     UnexpectedToken,
+		Message:  "expected next token to be ), got ; instead",
+		Span: Span{
+			Start: token.Position{Filename: "test.mk", Offset: 20, Line: 2, Column: 10},
+			End:   token.Position{Filename: "test.mk", Offset: 21, Line: 2, Column: 11},
+		},
+		Expected: []token.TokenType{token.RPAREN},
+		Actual:   token.SEMICOLON,
+		Hints:    []FixIt{{Message: `insert ")"`, Replacement: ")"}},
+	}
+
+	expected := "test.mk:2:10: error[P0001]: expected next token to be ), got ; instead\n" +
+		"   2 | \tadd(1, 2;\n" +
+		"     | \t        ^\n" +
+		"  hint: insert \")\"\n"
+
+	var out bytes.Buffer
+	Render(&out, source, d)
+
+	if out.String() != expected {
+		t.Errorf("rendered diagnostic wrong.\nexpected=%q\ngot=%q", expected, out.String())
+	}
+}
+
+func TestRenderAtEndOfInput(t *testing.T) {
+	source := "add(1"
+
+	d := Diagnostic{
+		Severity: Error,
+		Code:     UnexpectedToken,
+		Message:  "expected next token to be ), got EOF instead",
+		Span: Span{
+			Start: token.Position{Offset: 5, Line: 1, Column: 6},
+			End:   token.Position{Offset: 5, Line: 1, Column: 6},
+		},
+	}
+
+	expected := "1:6: error[P0001]: expected next token to be ), got EOF instead\n" +
+		"   1 | add(1\n" +
+		"     |      ^\n"
+
+	var out bytes.Buffer
+	Render(&out, source, d)
+
+	if out.String() != expected {
+		t.Errorf("rendered diagnostic wrong.\nexpected=%q\ngot=%q", expected, out.String())
+	}
+}
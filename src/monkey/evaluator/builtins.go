@@ -0,0 +1,17 @@
+/*
+* File: evaluator/builtins.go
+*
+* Description: Maps the names of the functions built into the Monkey programming language to their implementation
+*
+ */
+
+package evaluator
+
+import (
+	"github.com/vtallen/go-interpreter/object"
+)
+
+var builtins = map[string]*object.Builtin{
+	"len":  object.GetBuiltinByName("len"),
+	"puts": object.GetBuiltinByName("puts"),
+}
@@ -0,0 +1,739 @@
+/*
+* File: evaluator/evaluator.go
+*
+* Description: Contains the tree-walking evaluator for the Monkey programming language
+*
+ */
+
+package evaluator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vtallen/go-interpreter/ast"
+	"github.com/vtallen/go-interpreter/object"
+)
+
+// There is only ever one true, false and null, so they are shared instead of allocating new objects every time
+var (
+	NULL  = object.NULL
+	TRUE  = object.TRUE
+	FALSE = object.FALSE
+
+	BREAK    = &object.Break{}
+	CONTINUE = &object.Continue{}
+)
+
+// MaxCallDepth is how deeply function calls can be nested before evaluation stops with a stack overflow error. It
+// keeps Go's own stack from running out and is the same as vm.MaxFrames, so both engines stop at the same depth
+const MaxCallDepth = 1024
+
+/*
+* Function: Eval
+*
+* Parameters: node ast.Node            - The node to evaluate
+*             env  *object.Environment - The environment to resolve and bind names in
+*
+* Returns: object.Object - The value the node evaluates to
+*
+* Description: Evaluates a node of the AST, recursing into its children as needed
+*
+ */
+func Eval(node ast.Node, env *object.Environment) object.Object {
+	switch node := node.(type) {
+
+	// Statements
+	case *ast.Program:
+		return evalProgram(node, env)
+
+	case *ast.ExpressionStatement:
+		return Eval(node.Expression, env)
+
+	case *ast.BlockStatement:
+		return evalBlockStatement(node, env)
+
+	case *ast.ReturnStatement:
+		val := Eval(node.ReturnValue, env)
+		if interrupts(val) {
+			return val
+		}
+		return &object.ReturnValue{Value: val}
+
+	case *ast.LetStatement:
+		val := Eval(node.Value, env)
+		if interrupts(val) {
+			return val
+		}
+		env.Set(node.Name.Value, val)
+		return nil
+
+	case *ast.WhileStatement:
+		return evalWhileStatement(node, env)
+
+	case *ast.ForInStatement:
+		return evalForInStatement(node, env)
+
+	case *ast.BreakStatement:
+		return BREAK
+
+	case *ast.ContinueStatement:
+		return CONTINUE
+
+	// Expressions
+	case *ast.IntegerLiteral:
+		if node.Big != nil {
+			return &object.BigInt{Value: node.Big}
+		}
+		return &object.Integer{Value: node.Value}
+
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
+
+	case *ast.StringLiteral:
+		return &object.String{Value: node.Value}
+
+	case *ast.Boolean:
+		return nativeBoolToBooleanObject(node.Value)
+
+	case *ast.PrefixExpression:
+		right := Eval(node.Right, env)
+		if interrupts(right) {
+			return right
+		}
+		return evalPrefixExpression(node.Operator, right)
+
+	case *ast.InfixExpression:
+		left := Eval(node.Left, env)
+		if interrupts(left) {
+			return left
+		}
+
+		right := Eval(node.Right, env)
+		if interrupts(right) {
+			return right
+		}
+
+		return evalInfixExpression(node.Operator, left, right)
+
+	case *ast.LogicalExpression:
+		return evalLogicalExpression(node, env)
+
+	case *ast.AssignExpression:
+		return evalAssignExpression(node, env)
+
+	case *ast.IfExpression:
+		return evalIfExpression(node, env)
+
+	case *ast.Identifier:
+		return evalIdentifier(node, env)
+
+	case *ast.FunctionLiteral:
+		return &object.Function{Parameters: node.Parameters, Body: node.Body, Env: env}
+
+	case *ast.CallExpression:
+		function := Eval(node.Function, env)
+		if interrupts(function) {
+			return function
+		}
+
+		args := evalExpressions(node.Arguments, env)
+		if len(args) == 1 && interrupts(args[0]) {
+			return args[0]
+		}
+
+		return applyFunction(function, args, env)
+
+	case *ast.ArrayLiteral:
+		elements := evalExpressions(node.Elements, env)
+		if len(elements) == 1 && interrupts(elements[0]) {
+			return elements[0]
+		}
+		return &object.Array{Elements: elements}
+
+	case *ast.HashLiteral:
+		return evalHashLiteral(node, env)
+
+	case *ast.IndexExpression:
+		left := Eval(node.Left, env)
+		if interrupts(left) {
+			return left
+		}
+
+		index := Eval(node.Index, env)
+		if interrupts(index) {
+			return index
+		}
+
+		return evalIndexExpression(left, index)
+
+	// Placeholders left behind by the parser after a syntax error
+	case *ast.BadStatement:
+		return newError("syntax error at %s", node.Pos())
+
+	case *ast.BadExpression:
+		return newError("syntax error at %s", node.Pos())
+	}
+
+	// Missing nodes, such as the value of a let or return statement that has none, evaluate to null
+	return NULL
+}
+
+/*
+* Function: evalProgram
+*
+* Parameters: program *ast.Program        - The program to evaluate
+*             env     *object.Environment - The global environment
+*
+* Returns: object.Object - The value of the last statement evaluated, nil if that statement produces no value
+*
+* Description: Evaluates every statement in the program, stopping early on a return statement or an error.
+*              The return value is unwrapped here since there is nothing above the program to return to
+ */
+func evalProgram(program *ast.Program, env *object.Environment) object.Object {
+	var result object.Object
+
+	for _, statement := range program.Statements {
+		result = Eval(statement, env)
+
+		switch result := result.(type) {
+		case *object.ReturnValue:
+			return result.Value
+		case *object.Error:
+			return result
+		}
+	}
+
+	return result
+}
+
+/*
+* Function: evalBlockStatement
+*
+* Parameters: block *ast.BlockStatement  - The block to evaluate
+*             env   *object.Environment  - The environment of the block
+*
+* Returns: object.Object - The value of the last statement evaluated, null if that statement has no value
+*
+* Description: Evaluates every statement in a block. Unlike evalProgram, return values are not unwrapped so
+*              that a return inside nested blocks stops the evaluation of all of the enclosing blocks. A break or
+*              continue stops the block the same way, until it reaches the loop it belongs to
+ */
+func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
+	var result object.Object = NULL
+
+	for _, statement := range block.Statements {
+		result = Eval(statement, env)
+		if result == nil {
+			// let statements and loops do not produce a value, a block ending in one is null like an empty block
+			result = NULL
+		}
+
+		if interrupts(result) {
+			return result
+		}
+	}
+
+	return result
+}
+
+/*
+* Function: evalWhileStatement
+*
+* Parameters: loop *ast.WhileStatement - The loop to evaluate
+*             env  *object.Environment - The environment of the loop
+*
+* Returns: object.Object - nil once the loop is done, or the return value or error that stopped it
+*
+* Description: Evaluates the body for as long as the condition is truthy. A break ends the loop and a continue
+*              goes straight to checking the condition again. Like a let statement the loop has no value itself
+ */
+func evalWhileStatement(loop *ast.WhileStatement, env *object.Environment) object.Object {
+	for {
+		condition := Eval(loop.Condition, env)
+		if interrupts(condition) {
+			return condition
+		}
+
+		if !isTruthy(condition) {
+			return nil
+		}
+
+		switch result := Eval(loop.Body, env).(type) {
+		case *object.ReturnValue, *object.Error:
+			return result
+		case *object.Break:
+			return nil
+		}
+	}
+}
+
+/*
+* Function: evalForInStatement
+*
+* Parameters: loop *ast.ForInStatement - The loop to evaluate
+*             env  *object.Environment - The environment of the loop
+*
+* Returns: object.Object - nil once the loop is done, or the return value or error that stopped it
+*
+* Description: Binds the variables of the loop to each element of the iterable in turn and evaluates the body.
+*              The variables are bound like let statements, so they are still set after the loop. A break ends
+*              the loop and a continue moves on to the next element
+ */
+func evalForInStatement(loop *ast.ForInStatement, env *object.Environment) object.Object {
+	iterable := Eval(loop.Iterable, env)
+	if interrupts(iterable) {
+		return iterable
+	}
+
+	it, err := object.NewIterator(iterable)
+	if err != nil {
+		return newError("%s", err)
+	}
+
+	for {
+		first, second, ok := it.Next(len(loop.Variables))
+		if !ok {
+			return nil
+		}
+
+		env.Set(loop.Variables[0].Value, first)
+		if len(loop.Variables) == 2 {
+			env.Set(loop.Variables[1].Value, second)
+		}
+
+		switch result := Eval(loop.Body, env).(type) {
+		case *object.ReturnValue, *object.Error:
+			return result
+		case *object.Break:
+			return nil
+		}
+	}
+}
+
+func evalPrefixExpression(operator string, right object.Object) object.Object {
+	switch operator {
+	case "!":
+		return evalBangOperatorExpression(right)
+	case "-":
+		return evalMinusPrefixOperatorExpression(right)
+	case "~":
+		return evalBitwiseNotOperatorExpression(right)
+	default:
+		return newError("unknown operator: %s%s", operator, right.Type())
+	}
+}
+
+func evalBangOperatorExpression(right object.Object) object.Object {
+	switch right {
+	case TRUE:
+		return FALSE
+	case FALSE:
+		return TRUE
+	case NULL:
+		return TRUE
+	default:
+		return FALSE
+	}
+}
+
+func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
+	result, err := object.NegateNumber(right)
+	if err != nil {
+		return newError("%s", err)
+	}
+	return result
+}
+
+func evalBitwiseNotOperatorExpression(right object.Object) object.Object {
+	result, err := object.BitwiseNot(right)
+	if err != nil {
+		return newError("%s", err)
+	}
+	return result
+}
+
+func evalInfixExpression(operator string, left, right object.Object) object.Object {
+	switch {
+	case operator == ".." || operator == "..=":
+		result, err := object.NewRange(operator, left, right)
+		if err != nil {
+			return newError("%s", err)
+		}
+		return result
+	case object.IsNumber(left) && object.IsNumber(right):
+		result, err := object.NumericOperation(operator, left, right)
+		if err != nil {
+			return newError("%s", err)
+		}
+		return result
+	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
+		return evalStringInfixExpression(operator, left, right)
+	// Booleans and null are singletons, so comparing the pointers is enough
+	case operator == "==":
+		return nativeBoolToBooleanObject(left == right)
+	case operator == "!=":
+		return nativeBoolToBooleanObject(left != right)
+	case left.Type() != right.Type():
+		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+func evalStringInfixExpression(operator string, left, right object.Object) object.Object {
+	leftVal := left.(*object.String).Value
+	rightVal := right.(*object.String).Value
+
+	switch operator {
+	case "+":
+		return &object.String{Value: leftVal + rightVal}
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+/*
+* Function: evalLogicalExpression
+*
+* Parameters: le  *ast.LogicalExpression - The && or || expression to evaluate
+*             env *object.Environment    - The environment to evaluate it in
+*
+* Returns: object.Object - The value of the operand that decided the result
+*
+* Description: The right operand is only evaluated when the left one does not decide the result. Like in Lua the
+*              result is one of the operands rather than a boolean: a && b is a when a is not truthy and b
+*              otherwise, a || b is a when a is truthy and b otherwise. This makes x || default work
+ */
+func evalLogicalExpression(le *ast.LogicalExpression, env *object.Environment) object.Object {
+	left := Eval(le.Left, env)
+	if interrupts(left) {
+		return left
+	}
+
+	if isTruthy(left) == (le.Operator == "||") {
+		return left
+	}
+
+	return Eval(le.Right, env)
+}
+
+func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
+	condition := Eval(ie.Condition, env)
+	if interrupts(condition) {
+		return condition
+	}
+
+	if isTruthy(condition) {
+		return Eval(ie.Consequence, env)
+	} else if ie.Alternative != nil {
+		return Eval(ie.Alternative, env)
+	} else {
+		return NULL
+	}
+}
+
+func evalIndexExpression(left, index object.Object) object.Object {
+	switch {
+	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalArrayIndexExpression(left, index)
+	case left.Type() == object.HASH_OBJ:
+		return evalHashIndexExpression(left, index)
+	default:
+		return newError("index operator not supported: %s", left.Type())
+	}
+}
+
+// Indexing past either end of an array gives null instead of an error. A BigInt index is always past the end
+func evalArrayIndexExpression(array, index object.Object) object.Object {
+	arrayObject := array.(*object.Array)
+	max := int64(len(arrayObject.Elements) - 1)
+
+	integer, ok := index.(*object.Integer)
+	if !ok || integer.Value < 0 || integer.Value > max {
+		return NULL
+	}
+
+	return arrayObject.Elements[integer.Value]
+}
+
+func evalHashIndexExpression(hash, index object.Object) object.Object {
+	hashObject := hash.(*object.Hash)
+
+	key, ok := index.(object.Hashable)
+	if !ok {
+		return newError("unusable as hash key: %s", index.Type())
+	}
+
+	pair, ok := hashObject.Pairs[key.HashKey()]
+	if !ok {
+		return NULL
+	}
+
+	return pair.Value
+}
+
+func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
+	hash := object.NewHash()
+
+	for _, pair := range node.Pairs {
+		key := Eval(pair.Key, env)
+		if interrupts(key) {
+			return key
+		}
+
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return newError("unusable as hash key: %s", key.Type())
+		}
+
+		value := Eval(pair.Value, env)
+		if interrupts(value) {
+			return value
+		}
+
+		hash.Set(hashKey, value)
+	}
+
+	return hash
+}
+
+// Names bound in the environment shadow the builtin functions
+func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
+	if val, ok := env.Get(node.Value); ok {
+		return val
+	}
+
+	if builtin, ok := builtins[node.Value]; ok {
+		return builtin
+	}
+
+	return newError("identifier not found: " + node.Value)
+}
+
+/*
+* Function: evalAssignExpression
+*
+* Parameters: node *ast.AssignExpression - The assignment to evaluate
+*             env  *object.Environment   - The environment of the assignment
+*
+* Returns: object.Object - The value assigned, or an error
+*
+* Description: Gives an existing name or an element of an array or hash a new value. The name is changed in the
+*              environment it is bound in, so a closure that captured it sees the new value. The compound forms
+*              look the name up before evaluating the value, plain = only once the value is known, since evaluating
+*              it can bind the name. The virtual machine checks in the same order, so both report the same error
+ */
+func evalAssignExpression(node *ast.AssignExpression, env *object.Environment) object.Object {
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		var current object.Object
+		if node.Operator != "=" {
+			owner, err := assignableOwner(target.Value, env)
+			if err != nil {
+				return err
+			}
+			current, _ = owner.Get(target.Value)
+		}
+
+		val := evalAssignedValue(node, current, env)
+		if interrupts(val) {
+			return val
+		}
+
+		owner, err := assignableOwner(target.Value, env)
+		if err != nil {
+			return err
+		}
+
+		return owner.Set(target.Value, val)
+
+	case *ast.IndexExpression:
+		left := Eval(target.Left, env)
+		if interrupts(left) {
+			return left
+		}
+
+		index := Eval(target.Index, env)
+		if interrupts(index) {
+			return index
+		}
+
+		// Plain = never reads the element, so it can add a key that is not in a hash yet
+		var current object.Object
+		if node.Operator != "=" {
+			current = evalIndexExpression(left, index)
+			if interrupts(current) {
+				return current
+			}
+		}
+
+		val := evalAssignedValue(node, current, env)
+		if interrupts(val) {
+			return val
+		}
+
+		if err := object.SetIndex(left, index, val); err != nil {
+			return newError("%s", err)
+		}
+
+		return val
+
+	default:
+		return newError("cannot assign to %s", node.Target.String())
+	}
+}
+
+// assignableOwner returns the environment name is bound in, or the error for assigning to a name that is not bound
+func assignableOwner(name string, env *object.Environment) (*object.Environment, *object.Error) {
+	if owner := env.Owner(name); owner != nil {
+		return owner, nil
+	}
+
+	if builtins[name] != nil {
+		return nil, newError("cannot assign to builtin: %s", name)
+	}
+
+	return nil, newError("identifier not found: %s", name)
+}
+
+// evalAssignedValue evaluates the right side of an assignment, combining it with current for the compound forms
+func evalAssignedValue(node *ast.AssignExpression, current object.Object, env *object.Environment) object.Object {
+	val := Eval(node.Value, env)
+	if interrupts(val) || node.Operator == "=" {
+		return val
+	}
+
+	return evalInfixExpression(strings.TrimSuffix(node.Operator, "="), current, val)
+}
+
+/*
+* Function: evalExpressions
+*
+* Parameters: exps []ast.Expression     - The expressions to evaluate, in order
+*             env  *object.Environment  - The environment to evaluate them in
+*
+* Returns: []object.Object - The values of the expressions. If one of them interrupts, it is the only element returned
+*
+* Description: Evaluates a list of expressions from left to right, used for the arguments of a call and the
+*              elements of an array
+ */
+func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
+	var result []object.Object
+
+	for _, e := range exps {
+		evaluated := Eval(e, env)
+		if interrupts(evaluated) {
+			return []object.Object{evaluated}
+		}
+		result = append(result, evaluated)
+	}
+
+	return result
+}
+
+/*
+* Function: applyFunction
+*
+* Parameters: fn   object.Object        - The function to call
+*             args []object.Object      - The values of the arguments
+*             env  *object.Environment  - The environment of the call, builtins print to its output
+*
+* Returns: object.Object - The value the function returns
+ */
+func applyFunction(fn object.Object, args []object.Object, env *object.Environment) object.Object {
+	switch fn := fn.(type) {
+
+	case *object.Function:
+		if len(args) != len(fn.Parameters) {
+			return newError("wrong number of arguments: want=%d, got=%d", len(fn.Parameters), len(args))
+		}
+
+		if env.Depth() >= MaxCallDepth {
+			return newError("stack overflow")
+		}
+
+		extendedEnv := extendFunctionEnv(fn, args, env)
+		evaluated := Eval(fn.Body, extendedEnv)
+
+		return unwrapReturnValue(evaluated)
+
+	case *object.Builtin:
+		return fn.Fn(env.Output(), args...)
+
+	default:
+		return newError("not a function: %s", fn.Type())
+	}
+}
+
+/*
+* Function: extendFunctionEnv
+*
+* Parameters: fn     *object.Function    - The function being called
+*             args   []object.Object     - The values of the arguments
+*             caller *object.Environment - The environment of the call
+*
+* Returns: *object.Environment - The environment to evaluate the function body in
+*
+* Description: Binds the arguments to the function's parameters in a new environment enclosed by the environment
+*              the function was defined in (not the one it is called from), which is what makes closures work.
+*              The caller is only used to count how deep the calls are nested
+ */
+func extendFunctionEnv(fn *object.Function, args []object.Object, caller *object.Environment) *object.Environment {
+	env := object.NewCallEnvironment(fn.Env, caller)
+
+	for paramIdx, param := range fn.Parameters {
+		env.Set(param.Value, args[paramIdx])
+	}
+
+	return env
+}
+
+// A return inside a function should only stop the function, not whatever called it
+func unwrapReturnValue(obj object.Object) object.Object {
+	if returnValue, ok := obj.(*object.ReturnValue); ok {
+		return returnValue.Value
+	}
+
+	return obj
+}
+
+func nativeBoolToBooleanObject(input bool) *object.Boolean {
+	return object.NativeBoolToBooleanObject(input)
+}
+
+// null and false are falsy, everything else is truthy
+func isTruthy(obj object.Object) bool {
+	switch obj {
+	case NULL:
+		return false
+	case TRUE:
+		return true
+	case FALSE:
+		return false
+	default:
+		return true
+	}
+}
+
+func newError(format string, a ...interface{}) *object.Error {
+	return &object.Error{Message: fmt.Sprintf(format, a...)}
+}
+
+// interrupts reports whether obj has to be passed up instead of being used as a value: an error, or a return, break
+// or continue on its way to the function or loop it belongs to
+func interrupts(obj object.Object) bool {
+	if obj == nil {
+		return false
+	}
+
+	switch obj.Type() {
+	case object.ERROR_OBJ, object.RETURN_VALUE_OBJ, object.BREAK_OBJ, object.CONTINUE_OBJ:
+		return true
+	}
+	return false
+}
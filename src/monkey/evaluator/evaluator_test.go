@@ -0,0 +1,749 @@
+/*
+* File: evaluator/evaluator_test.go
+*
+* Description: Contains the tests for the evaluator of the monkey programming language
+*
+ */
+
+package evaluator
+
+import (
+	"testing"
+
+	"github.com/vtallen/go-interpreter/lexer"
+	"github.com/vtallen/go-interpreter/object"
+	"github.com/vtallen/go-interpreter/parser"
+)
+
+func testEval(input string) object.Object {
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	env := object.NewEnvironment()
+
+	return Eval(program, env)
+}
+
+func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
+	result, ok := obj.(*object.Integer)
+	if !ok {
+		t.Errorf("object is not Integer. got=%T (%+v)", obj, obj)
+		return false
+	}
+
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%d, want=%d", result.Value, expected)
+		return false
+	}
+
+	return true
+}
+
+func testBooleanObject(t *testing.T, obj object.Object, expected bool) bool {
+	result, ok := obj.(*object.Boolean)
+	if !ok {
+		t.Errorf("object is not Boolean. got=%T (%+v)", obj, obj)
+		return false
+	}
+
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%t, want=%t", result.Value, expected)
+		return false
+	}
+
+	return true
+}
+
+func testNullObject(t *testing.T, obj object.Object) bool {
+	if obj != NULL {
+		t.Errorf("object is not NULL. got=%T (%+v)", obj, obj)
+		return false
+	}
+
+	return true
+}
+
+func TestEvalIntegerExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"5", 5},
+		{"10", 10},
+		{"-5", -5},
+		{"-10", -10},
+		{"5 + 5 + 5 + 5 - 10", 10},
+		{"2 * 2 * 2 * 2 * 2", 32},
+		{"-50 + 100 + -50", 0},
+		{"5 * 2 + 10", 20},
+		{"5 + 2 * 10", 25},
+		{"20 + 2 * -10", 0},
+		{"50 / 2 * 2 + 10", 60},
+		{"2 * (5 + 10)", 30},
+		{"3 * 3 * 3 + 10", 37},
+		{"3 * (3 * 3) + 10", 37},
+		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"7 % 3", 1},
+		{"-7 % 3", -1},
+		{"2 + 7 % 4 * 2", 8},
+		{"12 & 10", 8},
+		{"12 | 10", 14},
+		{"12 ^ 10", 6},
+		{"~5", -6},
+		{"1 << 4", 16},
+		{"-16 >> 2", -4},
+		{"1 | 2 ^ 3 & 4 << 1", 3},
+		{"~0 & 0xff", 255},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testIntegerObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestEvalFloatExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // The Inspect of the result
+	}{
+		{"3.14", "3.14"},
+		{"-.5", "-0.5"},
+		{"1.5 + 1.5", "3.0"},
+		{"1 + 0.5", "1.5"},
+		{"7 / 2.0", "3.5"},
+		{"7 / 2", "3"},
+		{"2.5 * 4 - 1", "9.0"},
+		{"1e3 / 1e-3", "1e+06"},
+		{"1.0 / 0", "+Inf"},
+		{"-1.0 / 0", "-Inf"},
+		{"1 == 1.0", "true"},
+		{"0.1 + 0.2 == 0.3", "false"},
+		{"1.5 < 2", "true"},
+		{"2 > 2.5", "false"},
+		{"2.0 != 2", "false"},
+		{"if (0.0) { 1 } else { 2 }", "1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%q: wrong result. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestEvalBigIntegerExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // The Inspect of the result
+	}{
+		{"9223372036854775807 + 1", "9223372036854775808"},
+		{"-9223372036854775808 - 1", "-9223372036854775809"},
+		{"9223372036854775808 - 1", "9223372036854775807"},
+		{"-(-9223372036854775807 - 1)", "9223372036854775808"},
+		{"123456789012345678901234567890 * 10", "1234567890123456789012345678900"},
+		{"99999999999999999999 / 3", "33333333333333333333"},
+		{"99999999999999999999 == 99999999999999999999", "true"},
+		{"99999999999999999999 > 9223372036854775807", "true"},
+		{"99999999999999999999 == 1e20", "false"},
+		{"[1, 2][99999999999999999999]", "null"},
+		{`{99999999999999999999: "big"}[99999999999999999998 + 1]`, "big"},
+		{"fn(f) { f(f, 25) }(fn(self, n) { if (n < 2) { 1 } else { n * self(self, n - 1) } })", "15511210043330985984000000"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%q: wrong result. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestEvalBooleanExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"true", true},
+		{"false", false},
+		{"1 < 2", true},
+		{"1 > 2", false},
+		{"1 < 1", false},
+		{"1 > 1", false},
+		{"1 == 1", true},
+		{"1 != 1", false},
+		{"1 == 2", false},
+		{"1 != 2", true},
+		{"true == true", true},
+		{"false == false", true},
+		{"true == false", false},
+		{"true != false", true},
+		{"(1 < 2) == true", true},
+		{"(1 > 2) == true", false},
+		{"1 <= 2", true},
+		{"2 <= 2", true},
+		{"3 <= 2", false},
+		{"1 >= 2", false},
+		{"2 >= 2", true},
+		{"1.5 >= 1", true},
+		{"1 < 2 == 2 >= 1", true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestLogicalExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"true && true", true},
+		{"true && false", false},
+		{"false && true", false},
+		{"false || true", true},
+		{"false || false", false},
+		{"1 < 2 && 2 < 3", true},
+		{"1 > 2 || 2 > 3", false},
+		// The result is the operand that decided it
+		{"1 && 2", 2},
+		{"0 || 5", 0},
+		{"false || 5", 5},
+		{"if (false) { 1 } || 7", 7},
+		{"if (false) { 1 } && 7", nil},
+		// The right operand is not evaluated when the left one decides the result
+		{"false && undefined", false},
+		{"true || undefined", true},
+		{"false || true && false", false},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestBangOperator(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"!true", false},
+		{"!false", true},
+		{"!5", false},
+		{"!!true", true},
+		{"!!false", false},
+		{"!!5", true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestIfElseExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"if (true) { 10 }", 10},
+		{"if (false) { 10 }", nil},
+		{"if (1) { 10 }", 10},
+		{"if (1 < 2) { 10 }", 10},
+		{"if (1 > 2) { 10 }", nil},
+		{"if (1 > 2) { 10 } else { 20 }", 20},
+		{"if (1 < 2) { 10 } else { 20 }", 10},
+		{"if (1 > 2) { 10 } else if (2 > 1) { 20 } else { 30 }", 20},
+		{"if (1 > 2) { 10 } else if (2 > 3) { 20 } else { 30 }", 30},
+		{"if (1 > 2) { 10 } else if (2 > 3) { 20 }", nil},
+		{"if (false) { 1 } else if (false) { 2 } else if (true) { 3 } else { 4 }", 3},
+		// A block ending in a statement without a value is null, whatever came before
+		{"if (true) { 5; let x = 1 }", nil},
+		{"if (true) { 5; while (false) { } }", nil},
+		{"if (true) { 5; for (x in []) { } }", nil},
+		{"fn() { 5; let x = 1 }()", nil},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestReturnStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"return 10;", 10},
+		{"return 10; 9;", 10},
+		{"return 2 * 5; 9;", 10},
+		{"9; return 2 * 5; 9;", 10},
+		{"if (10 > 1) { if (10 > 1) { return 10; } return 1; }", 10},
+		{"fn() { return; 9 }()", nil},
+		{"fn() { if (true) { return } 9 }()", nil},
+		{"fn(x) { if (x) { 1 } else if (true) { return 2; } 3 }(false)", 2},
+		{"fn() { let x = if (true) { return 5 }; 10 }()", 5},
+		{"fn() { [1, if (true) { return 6 }, 3]; 10 }()", 6},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestLetStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let a = 5; a;", 5},
+		{"let a = 5 * 5; a;", 25},
+		{"let a = 5; let b = a; b;", 5},
+		{"let a = 5; let b = a; let c = a + b + 5; c;", 15},
+		{"let a = 5\nlet b = a * 2\nb", 10},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestWhileStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let i = 0; while (i < 10) { let i = i + 1 }; i", 10},
+		{"let i = 0; while (false) { let i = i + 1 }; i", 0},
+		{"let i = 0; while (true) { let i = i + 1; if (i == 5) { break } }; i", 5},
+		{"let i = 0; let sum = 0; while (i < 10) { let i = i + 1; if (i % 2 == 0) { continue; } let sum = sum + i }; sum", 25},
+		{"let i = 0; let n = 0; while (i < 3) { let i = i + 1; let j = 0; while (true) { let j = j + 1; let n = n + 1; if (j == 2) { break } } }; n", 6},
+		{"fn() { let i = 0; while (true) { let i = i + 1; if (i > 3) { return i; } } }()", 4},
+		{"fn() { while (true) { break } 7 }()", 7},
+		{"let i = 0; while (true) { i += 1; let x = if (i > 3) { break } else { 1 } }; i", 4},
+		{"let i = 0; while (true) { i += (if (i == 2) { break } else { 1 }) }; i", 2},
+		{"let i = 0; let n = 0; while (i < 5) { i += 1; n += [if (i > 2) { continue } else { i }][0] }; n", 3},
+		{"let i = 0; while (i < 3) { i += 1; while ([false, if (i == 2) { break }][0]) { } }; i", 2},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestForInStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let sum = 0; for (x in [1, 2, 3]) { let sum = sum + x }; sum", 6},
+		{"let sum = 0; for (i, x in [10, 20, 30]) { let sum = sum + i * x }; sum", 80},
+		{"let sum = 0; for (x in 1..5) { let sum = sum + x }; sum", 10},
+		{"let sum = 0; for (x in 1..=5) { let sum = sum + x }; sum", 15},
+		{"let sum = 0; for (x in 5..1) { let sum = sum + x }; sum", 0},
+		{`let n = 0; for (c in "héllo") { let n = n + 1 }; n`, 5},
+		{`let last = 0; for (i, c in "héllo") { let last = i }; last`, 4},
+		{`let sum = 0; for (k in {"a": 1, "b": 2}) { let sum = sum + len(k) }; sum`, 2},
+		{`let sum = 0; for (k, v in {"a": 1, "b": 2}) { let sum = sum + v }; sum`, 3},
+		{"let sum = 0; for (x in 0..100) { if (x % 2 == 0) { continue } if (x > 10) { break } let sum = sum + x }; sum", 25},
+		{"let n = 0; for (i in 0..3) { for (j in 0..3) { if (j == 1) { break } let n = n + 1 } }; n", 3},
+		{"fn(xs) { for (x in xs) { if (x > 2) { return x } } 0 }([1, 2, 3, 4])", 3},
+		{"fn() { for (x in 0..1000000) { if (x == 5) { return x } } }()", 5},
+		{"for (x in [7, 8]) { }; x", 8},
+		{"let n = 0; for (x in 0..5) { n = n + (if (x == 3) { continue } else { x }) }; n", 7},
+		{"let n = 0; for (x in 0..5) { n = len([x, if (x == 2) { break }]) + x }; n", 3},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestAssignExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let x = 1; x = 2; x", 2},
+		{"let x = 1; x = x + 1", 2},
+		{"let a = 1; let b = 2; a = b = 5; a + b", 10},
+		{"let x = 10; x += 5; x -= 3; x *= 2; x /= 4; x %= 4", 2},
+		{"let i = 0; let sum = 0; while (i < 5) { i += 1; sum += i }; sum", 15},
+		{"let f = fn(n) { n += 1; n }; f(1)", 2},
+		{"let count = 0; let inc = fn() { count += 1 }; inc(); inc(); count", 2},
+		{"let a = [1, 2, 3]; a[0] = 9; a[0] + a[1]", 11},
+		{"let a = [1, 2, 3]; a[1] += 10; a[1]", 12},
+		{"let a = [1, 2]; let b = a; b[0] = 5; a[0]", 5},
+		{"let a = [[1, 2]]; a[0][1] *= 3; a[0][1]", 6},
+		{`let h = {"a": 1}; h["a"] += 1; h["b"] = 3; h["a"] + h["b"]`, 5},
+		{"let a = [0, 0]; let i = 0; a[i = 1] += 7; a[1] + i", 8},
+		{"fn(a) { let inc = fn() { a += 1 }; inc(); inc(); a }(1)", 3},
+		{"fn() { let x = 1; let f = fn() { x }; x = 5; f() }()", 5},
+		{"let make = fn() { let n = 0; [fn() { n += 1 }, fn() { n }] }; let c = make(); c[0](); c[0](); c[1]()", 2},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestRanges(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"0..5", "0..5"},
+		{"-1..=1 + 2", "-1..=3"},
+		{"let r = 1..3; r", "1..3"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%q: wrong range. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestErrorHandling(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"5 + true;", "type mismatch: INTEGER + BOOLEAN"},
+		{"5 + true; 5;", "type mismatch: INTEGER + BOOLEAN"},
+		{"-true", "unknown operator: -BOOLEAN"},
+		{"true + false;", "unknown operator: BOOLEAN + BOOLEAN"},
+		{"5; true + false; 5", "unknown operator: BOOLEAN + BOOLEAN"},
+		{"if (10 > 1) { true + false; }", "unknown operator: BOOLEAN + BOOLEAN"},
+		{"if (10 > 1) { if (10 > 1) { true + false; } 10 }", "unknown operator: BOOLEAN + BOOLEAN"},
+		{"foobar", "identifier not found: foobar"},
+		{"let a = foobar; a", "identifier not found: foobar"},
+		{"while (foobar) { 1 }", "identifier not found: foobar"},
+		{"while (true) { 1 + true; }", "type mismatch: INTEGER + BOOLEAN"},
+		{"for (x in 5) { x }", "not iterable: INTEGER"},
+		{"for (x in undefined) { x }", "identifier not found: undefined"},
+		{"for (x in [1]) { x + true }", "type mismatch: INTEGER + BOOLEAN"},
+		{"1.5..2", "unknown operator: FLOAT .. INTEGER"},
+		{`"a"..=2`, "type mismatch: STRING ..= INTEGER"},
+		{"0..99999999999999999999", "range bound too large: 0 .. 99999999999999999999"},
+		{"10 / 0", "division by zero: 10 / 0"},
+		{"10 % 0", "division by zero: 10 % 0"},
+		{"1.5 & 1", "unknown operator: FLOAT & INTEGER"},
+		{"~1.5", "unknown operator: ~FLOAT"},
+		{"~true", "unknown operator: ~BOOLEAN"},
+		{"true | 1", "type mismatch: BOOLEAN | INTEGER"},
+		{"1 << -1", "negative shift count: 1 << -1"},
+		{"true && undefined", "identifier not found: undefined"},
+		{"undefined || true", "identifier not found: undefined"},
+		{"5(1)", "not a function: INTEGER"},
+		{"fn(x) { x }(1, 2)", "wrong number of arguments: want=1, got=2"},
+		{"fn(f) { f(f) }(fn(f) { f(f) })", "stack overflow"},
+		{"let f = fn(n) { if (n == 0) { 0 } else { f(n - 1) } }; f(3000000)", "stack overflow"},
+		{`"Hello" - "World"`, "unknown operator: STRING - STRING"},
+		{`"Hello" + 1`, "type mismatch: STRING + INTEGER"},
+		{`1[0]`, "index operator not supported: INTEGER"},
+		{`{"name": "Monkey"}[fn(x) { x }];`, "unusable as hash key: FUNCTION"},
+		{`{fn(x) { x }: 1}`, "unusable as hash key: FUNCTION"},
+		{"x = 1", "identifier not found: x"},
+		{"len += 1", "cannot assign to builtin: len"},
+		{"fn() { x = 1; let x = 2 }()", "identifier not found: x"},
+		{"let x = 1; x += true", "type mismatch: INTEGER + BOOLEAN"},
+		{"let a = [1]; a[1] = 2", "index out of range: 1"},
+		{"let a = [1]; a[-1] += 2", "type mismatch: NULL + INTEGER"},
+		{`let s = "ab"; s[0] = "c"`, "index assignment not supported: STRING"},
+		{"let h = {}; h[fn() {}] = 1", "unusable as hash key: FUNCTION"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestFunctionObject(t *testing.T) {
+	input := "fn(x) { x + 2; };"
+
+	evaluated := testEval(input)
+	fn, ok := evaluated.(*object.Function)
+	if !ok {
+		t.Fatalf("object is not Function. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	if len(fn.Parameters) != 1 {
+		t.Fatalf("function has wrong parameters. Parameters=%+v", fn.Parameters)
+	}
+
+	if fn.Parameters[0].String() != "x" {
+		t.Fatalf("parameter is not 'x'. got=%q", fn.Parameters[0])
+	}
+
+	expectedBody := "(x + 2)"
+
+	if fn.Body.String() != expectedBody {
+		t.Fatalf("body is not %q. got=%q", expectedBody, fn.Body.String())
+	}
+}
+
+func TestFunctionApplication(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"fn(x) { x; }(5)", 5},
+		{"fn(x, y) { x + y; }(5, 5)", 10},
+		{"fn(x, y) { x * y }(5, 5 + 5)", 50},
+		{"fn() { 5 }()", 5},
+		{"fn(x) { x; }(fn(y) { y }(5))", 5},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestClosures(t *testing.T) {
+	input := "fn(x) { fn(y) { x + y } }(2)(3)"
+
+	testIntegerObject(t, testEval(input), 5)
+
+	input = `
+let newAdder = fn(x) {
+	fn(y) { x + y };
+};
+
+let addTwo = newAdder(2);
+addTwo(2);`
+
+	testIntegerObject(t, testEval(input), 4)
+}
+
+func TestStringLiteral(t *testing.T) {
+	input := `"Hello World!"`
+
+	evaluated := testEval(input)
+	str, ok := evaluated.(*object.String)
+	if !ok {
+		t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	if str.Value != "Hello World!" {
+		t.Errorf("String has wrong value. got=%q", str.Value)
+	}
+}
+
+func TestStringConcatenation(t *testing.T) {
+	input := `"Hello" + " " + "World!"`
+
+	evaluated := testEval(input)
+	str, ok := evaluated.(*object.String)
+	if !ok {
+		t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	if str.Value != "Hello World!" {
+		t.Errorf("String has wrong value. got=%q", str.Value)
+	}
+}
+
+func TestStringComparison(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{`"a" == "a"`, true},
+		{`"a" == "b"`, false},
+		{`"a" != "b"`, true},
+		{`"a" + "b" == "ab"`, true},
+	}
+
+	for _, tt := range tests {
+		testBooleanObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestArrayLiterals(t *testing.T) {
+	input := "[1, 2 * 2, 3 + 3]"
+
+	evaluated := testEval(input)
+	result, ok := evaluated.(*object.Array)
+	if !ok {
+		t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	if len(result.Elements) != 3 {
+		t.Fatalf("array has wrong num of elements. got=%d", len(result.Elements))
+	}
+
+	testIntegerObject(t, result.Elements[0], 1)
+	testIntegerObject(t, result.Elements[1], 4)
+	testIntegerObject(t, result.Elements[2], 6)
+}
+
+func TestArrayIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"[1, 2, 3][0]", 1},
+		{"[1, 2, 3][1]", 2},
+		{"[1, 2, 3][2]", 3},
+		{"[1, 2, 3][1 + 1];", 3},
+		{"fn(myArray) { myArray[0] + myArray[1] + myArray[2] }([1, 2, 3])", 6},
+		{"fn(myArray, i) { myArray[i] }([1, 2, 3], 2)", 3},
+		{"[[1, 2], [3, 4]][1][0]", 3},
+		{"[1, 2, 3][3]", nil},
+		{"[1, 2, 3][-1]", nil},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestHashLiterals(t *testing.T) {
+	input := `
+	{
+		"one": 10 - 9,
+		"tw" + "o": 1 + 1,
+		"thr" + "ee": 6 / 2,
+		4: 4,
+		true: 5,
+		false: 6
+	}`
+
+	evaluated := testEval(input)
+	result, ok := evaluated.(*object.Hash)
+	if !ok {
+		t.Fatalf("Eval didn't return Hash. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	expected := map[object.HashKey]int64{
+		(&object.String{Value: "one"}).HashKey():   1,
+		(&object.String{Value: "two"}).HashKey():   2,
+		(&object.String{Value: "three"}).HashKey(): 3,
+		(&object.Integer{Value: 4}).HashKey():      4,
+		TRUE.HashKey():                             5,
+		FALSE.HashKey():                            6,
+	}
+
+	if len(result.Pairs) != len(expected) {
+		t.Fatalf("Hash has wrong num of pairs. got=%d", len(result.Pairs))
+	}
+
+	for expectedKey, expectedValue := range expected {
+		pair, ok := result.Pairs[expectedKey]
+		if !ok {
+			t.Errorf("no pair for given key in Pairs")
+		}
+
+		testIntegerObject(t, pair.Value, expectedValue)
+	}
+
+	expectedInspect := "{one: 1, two: 2, three: 3, 4: 4, true: 5, false: 6}"
+	if result.Inspect() != expectedInspect {
+		t.Errorf("Inspect() wrong. expected=%q, got=%q", expectedInspect, result.Inspect())
+	}
+}
+
+func TestHashIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`{"foo": 5}["foo"]`, 5},
+		{`{"foo": 5}["bar"]`, nil},
+		{`fn(key) { {"foo": 5}[key] }("foo")`, 5},
+		{`{}["foo"]`, nil},
+		{`{5: 5}[5]`, 5},
+		{`{true: 5}[true]`, 5},
+		{`{false: 5}[false]`, 5},
+		{`{"a": 1, "a": 2}["a"]`, 2},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestBuiltinFunctions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`len("")`, 0},
+		{`len("four")`, 4},
+		{`len("hello world")`, 11},
+		{`len("héllo")`, 5},
+		{`len([1, 2, 3])`, 3},
+		{`len({"a": 1})`, 1},
+		{`len(1)`, "argument to `len` not supported, got INTEGER"},
+		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
+		{`puts()`, nil},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		case nil:
+			testNullObject(t, evaluated)
+		}
+	}
+}
@@ -6,15 +6,50 @@
  */
 package lexer
 
-import "github.com/vtallen/go-interpreter/token"
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/vtallen/go-interpreter/diagnostic"
+	"github.com/vtallen/go-interpreter/token"
+)
 
 type Lexer struct {
-	input        string
-	position     int  // current position in input (points to the current char)
-	readPosition int  // current reading position in input (after current char, the next char to be read)
-	ch           byte // The current character under examination (char at position in input)
+	r    io.Reader // Where the input comes from, nil once all of it has been read
+	buf  []byte    // The part of the input that has been read and may still be needed
+	base int       // Offset in the input of buf[0]
+	mark int       // Offset of the first byte the lexer still needs, the start of the token being read
+
+	filename     string // name of the file input was read from, used in the positions of tokens
+	position     int    // current position in input (points to the current char)
+	readPosition int    // current reading position in input (after current char, the next char to be read)
+	ch           rune   // The current character under examination (char at position in input)
+	line         int    // line of the current char, starting at 1
+	column       int    // column of the current char in characters, not bytes, starting at 1
+
+	errors []diagnostic.Diagnostic // Problems found in the input, such as strings that are never closed
+
+	mode Mode // Options set with SetMode
 }
 
+/*
+* Type: Mode
+*
+* Description: Options that change what the lexer produces, combined with |
+ */
+type Mode uint
+
+const (
+	ScanComments Mode = 1 << iota // Return comments as COMMENT tokens instead of skipping them
+)
+
+// The number of bytes the lexer asks its reader for at a time
+const chunkSize = 4096
+
 /*
 * Function: New
 *
@@ -25,11 +60,117 @@ type Lexer struct {
 * Description: Creates a new Lexer object with the given input
  */
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	return NewFile("", input)
+}
+
+/*
+* Function: NewReader
+*
+* Parameters: r io.Reader - Where to read the source code to be lexed from
+*
+* Returns: *Lexer - A pointer to a new Lexer object
+*
+* Description: Creates a new Lexer object that reads its input from r as tokens are asked for. Only the token being
+*              read and a few kilobytes after it are kept in memory, so files of any size and pipes can be lexed.
+*              The tokens are exactly the ones New would produce for the same input
+ */
+func NewReader(r io.Reader) *Lexer {
+	return NewFileReader("", r)
+}
+
+/*
+* Function: NewFile
+*
+* Parameters: filename string - The name of the file input was read from
+*             input    string - The input source code to be lexed
+*
+* Returns: *Lexer - A pointer to a new Lexer object
+*
+* Description: Creates a new Lexer object with the given input. filename is recorded in the position of every token.
+*              A byte order mark at the very start of the input is skipped
+ */
+func NewFile(filename string, input string) *Lexer {
+	return NewFileReader(filename, strings.NewReader(input))
+}
+
+/*
+* Function: NewFileReader
+*
+* Parameters: filename string    - The name of the file r reads from
+*             r        io.Reader - Where to read the source code to be lexed from
+*
+* Returns: *Lexer - A pointer to a new Lexer object
+*
+* Description: Like NewReader, but filename is recorded in the position of every token
+ */
+func NewFileReader(filename string, r io.Reader) *Lexer {
+	l := &Lexer{r: r, filename: filename, line: 1}
+	if l.available(len(byteOrderMark)-1) && string(l.buf[:len(byteOrderMark)]) == byteOrderMark {
+		l.readPosition = len(byteOrderMark)
+	}
 	l.readChar() // Put the lexer into a usable state before NextToken can be called
 	return l
 }
 
+/*
+* Function: Lexer.available
+*
+* Parameters: offset int - An offset in the input, at or after the start of the token being read
+*
+* Returns: bool - True if the input has a byte at offset, false if it ends before it
+*
+* Description: Reads more of the input until the byte at offset is in the buffer or there is nothing left to read
+ */
+func (l *Lexer) available(offset int) bool {
+	for offset-l.base >= len(l.buf) {
+		if l.r == nil {
+			return false
+		}
+		l.fill()
+	}
+	return true
+}
+
+/*
+* Function: Lexer.fill
+*
+* Parameters: None
+*
+* Returns: None
+*
+* Description: Reads the next chunk of the input into the buffer. The bytes before the token being read are thrown
+*              away first, so the buffer only grows past a few chunks for a token that is longer than that, such as
+*              a very long string. A read error other than io.EOF is reported and ends the input
+ */
+func (l *Lexer) fill() {
+	if n := l.mark - l.base; n > 0 {
+		l.buf = l.buf[:copy(l.buf, l.buf[n:])]
+		l.base = l.mark
+	}
+
+	if cap(l.buf)-len(l.buf) < chunkSize {
+		grown := make([]byte, len(l.buf), 2*cap(l.buf)+chunkSize)
+		copy(grown, l.buf)
+		l.buf = grown
+	}
+
+	n, err := l.r.Read(l.buf[len(l.buf) : len(l.buf)+chunkSize])
+	l.buf = l.buf[:len(l.buf)+n]
+
+	if err != nil {
+		if err != io.EOF {
+			l.errorSpan(diagnostic.ReadError, diagnostic.Span{Start: l.pos(), End: l.pos()},
+				"error reading input: %v", err)
+		}
+		l.r = nil
+	}
+}
+
+// text returns the input from offset from up to offset to, which must both be at or after the start of the token
+func (l *Lexer) text(from, to int) string {
+	return string(l.buf[from-l.base : to-l.base])
+}
+
 /*
 * Function: Lexer.readChar
 *
@@ -38,22 +179,115 @@ func New(input string) *Lexer {
 * Returns: None
 *
 * Description: Reads the next character in the input string and advances the lexer's position in the input string.
+*              The input is decoded as UTF-8, so a character may be several bytes long. Bytes that are not valid
+*              UTF-8 are reported and read as utf8.RuneError, one byte at a time
 *
  */
 func (l *Lexer) readChar() {
+	// Once the lexer has reached the end of the input it stays there, so the position of EOF does not change no matter
+	// how many times NextToken is called
+	if l.readPosition > l.position && l.atEOF() {
+		return
+	}
+
+	// Keep track of the line and column of the character we are moving to
+	if l.ch == '\n' {
+		l.line += 1
+		l.column = 1
+	} else {
+		l.column += 1
+	}
+
 	// This if statement checks if the readPosition is greater than or equal to the length of the input string.
 	// If it is, then the lexer has reached the end of the input and sets the current character to 0,
 	// which is the ASCII code for the "NUL" character and has no meaning in Monkey.
 	// Otherwise, it sets the current character to the character at the current readPosition in the input string.
-	if l.readPosition >= len(l.input) {
-		l.ch = 0
-	} else {
-		l.ch = l.input[l.readPosition]
-	}
+	var width int
+	l.ch, width = l.decode(l.readPosition)
 
 	l.position = l.readPosition // Move the lexer to the next character
 
-	l.readPosition += 1 // Increment the "pointer" to the next character
+	l.readPosition += width // Increment the "pointer" to the next character
+
+	if l.ch == utf8.RuneError && width == 1 {
+		l.error(diagnostic.InvalidUTF8, l.pos(), "invalid UTF-8 encoding: byte %#x", l.buf[l.position-l.base])
+	}
+}
+
+// A byte order mark is allowed at the start of the input, some editors on Windows put one there
+const byteOrderMark = "\uFEFF"
+
+/*
+* Function: Lexer.Errors
+*
+* Parameters: None
+*
+* Returns: []diagnostic.Diagnostic - The errors found in the input so far, in the order they were found
+*
+* Description: Returns the errors found while reading tokens. The lexer keeps going after an error, so these are
+*              only complete once NextToken has returned EOF
+ */
+func (l *Lexer) Errors() []diagnostic.Diagnostic {
+	return l.errors
+}
+
+/*
+* Function: Lexer.error
+*
+* Parameters: code   diagnostic.Code - The kind of problem found
+*             start  token.Position  - Where the problem starts, it ends after the current character
+*             format string          - Format string for the message
+*             a      ...interface{}  - Arguments for format
+*
+* Returns: None
+*
+* Description: Records an error found in the input
+ */
+func (l *Lexer) error(code diagnostic.Code, start token.Position, format string, a ...interface{}) {
+	l.errorSpan(code, diagnostic.Span{Start: start, End: l.endPos()}, format, a...)
+}
+
+// errorSpan records an error covering span, for problems found after the lexer has moved past them
+func (l *Lexer) errorSpan(code diagnostic.Code, span diagnostic.Span, format string, a ...interface{}) {
+	l.errors = append(l.errors, diagnostic.Diagnostic{
+		Severity: diagnostic.Error,
+		Code:     code,
+		Message:  fmt.Sprintf(format, a...),
+		Span:     span,
+	})
+}
+
+/*
+* Function: Lexer.SetMode
+*
+* Parameters: mode Mode - The options to lex the rest of the input with
+*
+* Returns: None
+ */
+func (l *Lexer) SetMode(mode Mode) {
+	l.mode = mode
+}
+
+/*
+* Function: Lexer.Mode
+*
+* Parameters: None
+*
+* Returns: Mode - The options the lexer is using
+ */
+func (l *Lexer) Mode() Mode {
+	return l.mode
+}
+
+/*
+* Function: Lexer.atEOF
+*
+* Parameters: None
+*
+* Returns: bool - True if the lexer has read past the last character of the input
+ */
+func (l *Lexer) atEOF() bool {
+	return !l.available(l.position)
 }
 
 /*
@@ -61,19 +295,20 @@ func (l *Lexer) readChar() {
 *
 * Parameters: None
 *
-* Returns: string - The identifier that was read from the input string (it can contain a-z, A-Z and _)
+* Returns: string - The identifier that was read from the input string
 *
-* Description: Reads an identifier from the input string and returns it. An identifier is defined as
-*              a sequence of characters that can contain a-z, A-Z and _ without any whitespace
+* Description: Reads an identifier from the input string and returns it. Like in Go, an identifier is a letter
+*              followed by any number of letters and digits, where _ counts as a letter and any Unicode letter or
+*              decimal digit can be used, so café and x1 are identifiers
 *
  */
 func (l *Lexer) readIdentifier() string {
 	position := l.position
-	for isLetter(l.ch) {
+	for isLetter(l.ch) || isUnicodeDigit(l.ch) {
 		l.readChar()
 	}
 
-	return l.input[position:l.position]
+	return l.text(position, l.position)
 }
 
 /*
@@ -81,19 +316,288 @@ func (l *Lexer) readIdentifier() string {
 *
 * Parameters: None
 *
-* Returns: string - The number that was read from the input string (contains characters from 0-9)
+* Returns: string          - The number that was read from the input string, spelled the way it was written
+*          token.TokenType - token.FLOAT if the number has a fraction or an exponent, token.INT otherwise
 *
-* Description: Reads a number of an arbitrary length from the input string and returns it.
+* Description: Reads a number of an arbitrary length from the input string and returns it. A '.' is part of the
+*              number unless another '.' follows it, so the range 1..5 is not mistaken for a float, and an 'e'
+*              (optionally followed by a sign) always starts an exponent. A '.' or an exponent without digits after
+*              it makes a malformed number literal. Integers starting with 0x, 0o or 0b are read in base 16, 8 or 2,
+*              any other integer may not start with 0 unless it is 0 itself, and '_' may be used to separate digits.
+*              Malformed numbers are reported but still returned whole, so the parser does not trip over the rest
+*              of them
 *
  */
+func (l *Lexer) readNumber() (string, token.TokenType) {
+	position := l.position
+	start := l.pos()
+
+	if _, ok := integerBases[l.peekChar()]; l.ch == '0' && ok {
+		return l.readPrefixedInteger(), token.INT
+	}
+
+	tokenType := token.TokenType(token.INT)
+	missingDigits := ""
 
-func (l *Lexer) readNumber() string {
+	l.readDigits()
+
+	if l.ch == '.' && l.peekChar() != '.' {
+		tokenType = token.FLOAT
+		l.readChar()
+		if !l.readDigits() {
+			missingDigits = "fraction"
+		}
+	}
+
+	if l.ch == 'e' || l.ch == 'E' {
+		tokenType = token.FLOAT
+		l.readChar()
+		if l.ch == '+' || l.ch == '-' {
+			l.readChar()
+		}
+		if !l.readDigits() && missingDigits == "" {
+			missingDigits = "exponent"
+		}
+	}
+
+	literal := l.text(position, l.position)
+
+	if missingDigits != "" {
+		l.errorSpan(diagnostic.InvalidNumber, l.spanIn(start, 0, len(literal)),
+			"malformed number literal %s, its %s has no digits", literal, missingDigits)
+		return literal, tokenType
+	}
+
+	// A leading 0 would make 017 read as octal, so it is refused rather than guessing which base was meant
+	if tokenType == token.INT && len(literal) > 1 && literal[0] == '0' {
+		l.errorSpan(diagnostic.InvalidNumber, l.spanIn(start, 0, len(literal)),
+			"integer literal %s has a leading zero, use 0o for octal", literal)
+		return literal, tokenType
+	}
+
+	l.checkUnderscores(literal, start, isDigit, false)
+
+	return literal, tokenType
+}
+
+// readDigits reads decimal digits along with the underscores separating them, reporting whether there were any
+func (l *Lexer) readDigits() bool {
+	found := false
+	for isDigit(l.ch) || l.ch == '_' {
+		found = found || isDigit(l.ch)
+		l.readChar()
+	}
+	return found
+}
+
+type integerBase struct {
+	name    string             // Used in error messages
+	isDigit func(ch rune) bool // Reports whether a character is a digit in the base
+}
+
+var (
+	hexadecimal = integerBase{"hexadecimal", isHexDigit}
+	octal       = integerBase{"octal", func(ch rune) bool { return '0' <= ch && ch <= '7' }}
+	binary      = integerBase{"binary", func(ch rune) bool { return ch == '0' || ch == '1' }}
+)
+
+// The bases integers can be written in, by the letter following their leading 0
+var integerBases = map[rune]integerBase{
+	'x': hexadecimal, 'X': hexadecimal,
+	'o': octal, 'O': octal,
+	'b': binary, 'B': binary,
+}
+
+/*
+* Function: Lexer.readPrefixedInteger
+*
+* Parameters: None
+*
+* Returns: string - The integer that was read, including its 0x, 0o or 0b prefix
+*
+* Description: Reads every ASCII letter and digit following the prefix, so a stray digit such as the 2 in 0b102 is
+*              reported as part of the number instead of becoming a number of its own
+*
+ */
+func (l *Lexer) readPrefixedInteger() string {
 	position := l.position
-	for isDigit(l.ch) {
+	start := l.pos()
+	base := integerBases[l.peekChar()]
+
+	l.readChar()
+	l.readChar()
+
+	for isASCIILetter(l.ch) || isDigit(l.ch) {
 		l.readChar()
 	}
 
-	return l.input[position:l.position]
+	literal := l.text(position, l.position)
+
+	digits := 0
+	for i := 2; i < len(literal); i++ {
+		switch {
+		case literal[i] == '_':
+		case base.isDigit(rune(literal[i])):
+			digits++
+		default:
+			l.errorSpan(diagnostic.InvalidNumber, l.spanIn(start, i, i+1),
+				"invalid digit %q in %s literal", rune(literal[i]), base.name)
+			return literal
+		}
+	}
+
+	if digits == 0 {
+		l.errorSpan(diagnostic.InvalidNumber, l.spanIn(start, 0, len(literal)), "%s literal has no digits", base.name)
+		return literal
+	}
+
+	l.checkUnderscores(literal, start, base.isDigit, true)
+
+	return literal
+}
+
+/*
+* Function: Lexer.checkUnderscores
+*
+* Parameters: literal  string              - The number to check
+*             start    token.Position      - Where the number starts
+*             isDigit  func(ch rune) bool  - Reports whether a character is a digit of the number
+*             prefixed bool                - Whether the number starts with 0x, 0o or 0b
+*
+* Returns: None
+*
+* Description: Reports the first '_' that does not sit in between two digits. Like in Go, the prefix of a number
+*              counts as a digit, so 0x_FF is allowed
+*
+ */
+func (l *Lexer) checkUnderscores(literal string, start token.Position, isDigit func(ch rune) bool, prefixed bool) {
+	for i := 0; i < len(literal); i++ {
+		if literal[i] != '_' {
+			continue
+		}
+
+		afterDigit := i > 0 && isDigit(rune(literal[i-1])) || prefixed && i == 2
+		beforeDigit := i+1 < len(literal) && isDigit(rune(literal[i+1]))
+
+		if !afterDigit || !beforeDigit {
+			l.errorSpan(diagnostic.InvalidNumber, l.spanIn(start, i, i+1), "'_' must separate successive digits")
+			return
+		}
+	}
+}
+
+// spanIn returns the span from the from-th to the to-th byte of a token starting at start, which fits on one line
+// and is ASCII, so bytes and columns line up
+func (l *Lexer) spanIn(start token.Position, from, to int) diagnostic.Span {
+	at := func(n int) token.Position {
+		pos := start
+		pos.Offset += n
+		pos.Column += n
+		return pos
+	}
+
+	return diagnostic.Span{Start: at(from), End: at(to)}
+}
+
+/*
+* Function: Lexer.readString
+*
+* Parameters: None
+*
+* Returns: string - The value of the string literal starting at the current character, with escape sequences replaced
+*
+* Description: Reads a double quoted string literal and leaves the lexer on the closing quote. The escape sequences
+*              \n, \t, \r, \", \\ and \u{...} (a unicode code point given in hex) are supported. A string that is
+*              not closed before the end of the input is reported as an error and contains everything up to the end
+*
+ */
+func (l *Lexer) readString() string {
+	start := l.pos()
+
+	var out strings.Builder
+
+	for {
+		l.readChar()
+
+		switch {
+		case l.ch == '"':
+			return out.String()
+		case l.ch == 0 && l.atEOF():
+			l.error(diagnostic.UnterminatedString, start, "string literal not terminated")
+			return out.String()
+		case l.ch == '\\':
+			l.readEscape(&out)
+		default:
+			out.WriteRune(l.ch)
+		}
+	}
+}
+
+/*
+* Function: Lexer.readEscape
+*
+* Parameters: out *strings.Builder - Where to write the character the escape sequence stands for
+*
+* Returns: None
+*
+* Description: Reads the escape sequence starting at the \ under the lexer and leaves the lexer on its last
+*              character. Unknown escape sequences are reported as errors and kept as they were written
+*
+ */
+func (l *Lexer) readEscape(out *strings.Builder) {
+	start := l.pos()
+
+	l.readChar()
+
+	switch l.ch {
+	case 'n':
+		out.WriteByte('\n')
+	case 't':
+		out.WriteByte('\t')
+	case 'r':
+		out.WriteByte('\r')
+	case '"':
+		out.WriteByte('"')
+	case '\\':
+		out.WriteByte('\\')
+	case 'u':
+		if l.peekChar() != '{' {
+			l.error(diagnostic.InvalidEscape, start, "invalid escape sequence: \\u must be followed by {")
+			out.WriteString("\\u")
+			return
+		}
+		l.readChar()
+
+		digits := ""
+		for isHexDigit(l.peekChar()) {
+			l.readChar()
+			digits += string(l.ch)
+		}
+
+		if l.peekChar() != '}' {
+			l.error(diagnostic.InvalidEscape, start, "invalid escape sequence: \\u{%s is missing its closing }", digits)
+			out.WriteString("\\u{" + digits)
+			return
+		}
+		l.readChar()
+
+		code, err := strconv.ParseUint(digits, 16, 32)
+		if err != nil || len(digits) > 6 || !utf8.ValidRune(rune(code)) {
+			l.error(diagnostic.InvalidEscape, start, "invalid escape sequence: \\u{%s} is not a valid unicode code point", digits)
+			out.WriteString("\\u{" + digits + "}")
+			return
+		}
+
+		out.WriteRune(rune(code))
+	default:
+		if l.ch == 0 && l.atEOF() {
+			// Leave it to readString to report the string is not terminated
+			return
+		}
+		l.error(diagnostic.InvalidEscape, start, "invalid escape sequence: \\%c", l.ch)
+		out.WriteByte('\\')
+		out.WriteRune(l.ch)
+	}
 }
 
 /*
@@ -111,50 +615,162 @@ func (l *Lexer) skipWhitespace() {
 	}
 }
 
+// atComment reports whether the lexer is looking at the start of a line or block comment
+func (l *Lexer) atComment() bool {
+	return l.ch == '/' && (l.peekChar() == '/' || l.peekChar() == '*')
+}
+
+/*
+* Function: Lexer.readComment
+*
+* Parameters: None
+*
+* Returns: string - The comment, including the characters that open and close it
+*
+* Description: Reads a comment starting at the current character. A line comment runs up to the end of the line,
+*              the newline is not part of it. Block comments nest, so a block of code that already contains a
+*              comment can be commented out
+*
+ */
+func (l *Lexer) readComment() string {
+	position := l.position
+	start := l.pos()
+
+	if l.peekChar() == '/' {
+		for l.ch != '\n' && !l.atEOF() {
+			l.readChar()
+		}
+		return l.text(position, l.position)
+	}
+
+	l.readChar()
+	l.readChar()
+
+	depth := 1
+	for depth > 0 {
+		switch {
+		case l.atEOF():
+			l.errorSpan(diagnostic.UnterminatedComment, diagnostic.Span{Start: start, End: l.pos()},
+				"comment not terminated")
+			return l.text(position, l.position)
+		case l.ch == '/' && l.peekChar() == '*':
+			depth++
+			l.readChar()
+		case l.ch == '*' && l.peekChar() == '/':
+			depth--
+			l.readChar()
+		}
+		l.readChar()
+	}
+
+	return l.text(position, l.position)
+}
+
 /*
 * Function: Lexer.peekChar
 *
 * Parameters: None
 *
-* Retrurns: byte - the next char in the input if it exists, 0 otherwise
+* Retrurns: rune - the next char in the input if it exists, 0 otherwise
 *
 * Description: Looks at the next char in the input and returns it
 *
  */
-func (l *Lexer) peekChar() byte {
-	if l.readPosition >= len(l.input) {
-		return 0
-	} else {
-		return l.input[l.readPosition]
+func (l *Lexer) peekChar() rune {
+	return l.charAt(l.readPosition)
+}
+
+// charAt returns the char starting at offset in the input, 0 if offset is past the end of the input
+func (l *Lexer) charAt(offset int) rune {
+	ch, _ := l.decode(offset)
+	return ch
+}
+
+// decode returns the char starting at offset in the input and its length in bytes, 0 and 1 past the end of the input
+func (l *Lexer) decode(offset int) (rune, int) {
+	if !l.available(offset) {
+		return 0, 1
+	}
+	l.available(offset + utf8.UTFMax - 1)
+	return utf8.DecodeRune(l.buf[offset-l.base:])
+}
+
+/*
+* Function: Lexer.pos
+*
+* Parameters: None
+*
+* Returns: token.Position - The position of the current character
+*
+* Description: Returns the position of the character the lexer is currently looking at
+ */
+func (l *Lexer) pos() token.Position {
+	return token.Position{Filename: l.filename, Offset: l.position, Line: l.line, Column: l.column}
+}
+
+/*
+* Function: Lexer.endPos
+*
+* Parameters: None
+*
+* Returns: token.Position - The position immediately after the current character
+ */
+func (l *Lexer) endPos() token.Position {
+	end := l.pos()
+	if !l.atEOF() {
+		end.Offset = l.readPosition
+		end.Column += 1
 	}
+	return end
 }
 
 /*
 * Function isLetter
 *
-* Parameters: ch byte - The character to check if it is a letter
+* Parameters: ch rune - The character to check if it is a letter
 *
-* Returns: bool - True if the character is in a-z, A-Z, or is _, false otherwise
+* Returns: bool - True if the character is a Unicode letter or is _, false otherwise
 *
-* Description: Checks if the given character is a letter (a-z, A-Z) or an underscore
+* Description: Checks if the given character can start an identifier
  */
-func isLetter(ch byte) bool {
+func isLetter(ch rune) bool {
+	return isASCIILetter(ch) || ch >= utf8.RuneSelf && unicode.IsLetter(ch)
+}
+
+// isASCIILetter reports whether ch is in a-z, A-Z or is _
+func isASCIILetter(ch rune) bool {
 	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
 }
 
 /*
 * Function: isDigit
 *
-* Parameters: ch byte - The character to check if it is a digit
+* Parameters: ch rune - The character to check if it is a digit
 *
 * Returns: bool - True if the character is in 0-9, false otherwise
 *
-* Description: Checks if the given character is a number (0-9)
+* Description: Checks if the given character is a number (0-9). Numbers are only ever written with these digits
  */
-func isDigit(ch byte) bool {
+func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
 
+// isUnicodeDigit reports whether ch is a decimal digit in any script, which may be used in identifiers
+func isUnicodeDigit(ch rune) bool {
+	return isDigit(ch) || ch >= utf8.RuneSelf && unicode.IsDigit(ch)
+}
+
+/*
+* Function: isHexDigit
+*
+* Parameters: ch rune - The character to check if it is a hexadecimal digit
+*
+* Returns: bool - True if the character is in 0-9, a-f or A-F, false otherwise
+ */
+func isHexDigit(ch rune) bool {
+	return isDigit(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
+
 /*
 * Function: Lexer.NextToken
 *
@@ -170,6 +786,22 @@ func (l *Lexer) NextToken() token.Token {
 
 	l.skipWhitespace()
 
+	for l.atComment() {
+		l.mark = l.position
+		start := l.pos()
+		comment := l.readComment()
+
+		if l.mode&ScanComments != 0 {
+			return token.Token{Type: token.COMMENT, Literal: comment, Pos: start, End: l.pos()}
+		}
+
+		l.skipWhitespace()
+	}
+
+	// Nothing before the token is needed anymore, so the buffer can forget it
+	l.mark = l.position
+	start := l.pos()
+
 	switch l.ch {
 	case '=':
 		if l.peekChar() == '=' {
@@ -180,9 +812,9 @@ func (l *Lexer) NextToken() token.Token {
 			tok = newToken(token.ASSIGN, l.ch)
 		}
 	case '+':
-		tok = newToken(token.PLUS, l.ch)
+		tok = l.twoCharToken('=', token.PLUS_ASSIGN, token.PLUS)
 	case '-':
-		tok = newToken(token.MINUS, l.ch)
+		tok = l.twoCharToken('=', token.MINUS_ASSIGN, token.MINUS)
 	case '!':
 		if l.peekChar() == '=' {
 			ch := l.ch
@@ -192,17 +824,49 @@ func (l *Lexer) NextToken() token.Token {
 			tok = newToken(token.BANG, l.ch)
 		}
 	case '/':
-		tok = newToken(token.SLASH, l.ch)
+		tok = l.twoCharToken('=', token.SLASH_ASSIGN, token.SLASH)
 	case '*':
-		tok = newToken(token.ASTERISK, l.ch)
+		tok = l.twoCharToken('=', token.ASTERISK_ASSIGN, token.ASTERISK)
+	case '%':
+		tok = l.twoCharToken('=', token.PERCENT_ASSIGN, token.PERCENT)
 	case '<':
-		tok = newToken(token.LT, l.ch)
+		if l.peekChar() == '<' {
+			tok = l.twoCharToken('<', token.SHIFT_LEFT, token.LT)
+		} else {
+			tok = l.twoCharToken('=', token.LT_EQ, token.LT)
+		}
 	case '>':
-		tok = newToken(token.GT, l.ch)
+		if l.peekChar() == '>' {
+			tok = l.twoCharToken('>', token.SHIFT_RIGHT, token.GT)
+		} else {
+			tok = l.twoCharToken('=', token.GT_EQ, token.GT)
+		}
+	case '&':
+		tok = l.twoCharToken('&', token.AND, token.BIT_AND)
+	case '|':
+		tok = l.twoCharToken('|', token.OR, token.BIT_OR)
+	case '^':
+		tok = newToken(token.BIT_XOR, l.ch)
+	case '~':
+		tok = newToken(token.BIT_NOT, l.ch)
+	case '.':
+		if l.peekChar() == '.' {
+			l.readChar()
+			tok = l.twoCharToken('=', token.DOTDOT_EQ, token.DOTDOT)
+			tok.Literal = "." + tok.Literal
+		} else if isDigit(l.peekChar()) {
+			tok.Literal, tok.Type = l.readNumber()
+			tok.Pos, tok.End = start, l.pos()
+			return tok
+		} else {
+			tok = newToken(token.ILLIGAL, l.ch)
+		}
 	case ';':
 		tok = newToken(token.SEMICOLON, l.ch)
 	case ',':
 		tok = newToken(token.COMMA, l.ch)
+	case ':':
+		tok = newToken(token.COLON, l.ch)
 	case '(':
 		tok = newToken(token.LPAREN, l.ch)
 	case ')':
@@ -211,43 +875,79 @@ func (l *Lexer) NextToken() token.Token {
 		tok = newToken(token.LBRACE, l.ch)
 	case '}':
 		tok = newToken(token.RBRACE, l.ch)
+	case '[':
+		tok = newToken(token.LBRACKET, l.ch)
+	case ']':
+		tok = newToken(token.RBRACKET, l.ch)
+	case '"':
+		tok.Type = token.STRING
+		tok.Literal = l.readString()
 	case 0:
-		tok.Literal = ""
-		tok.Type = token.EOF
+		if l.atEOF() {
+			tok.Literal = ""
+			tok.Type = token.EOF
+		} else {
+			// A NUL written in the source is not the end of it, everything after it still has to be read
+			l.error(diagnostic.InvalidCharacter, start, "invalid character NUL (U+0000)")
+			tok = token.Token{Type: token.ILLIGAL, Literal: l.text(l.position, l.readPosition)}
+		}
 	default:
 		if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
 			tok.Type = token.LookupIdent(tok.Literal)
+			tok.Pos, tok.End = start, l.pos()
 			// An early return because readIdentifier advaces the readPostition and position fields of
 			// the lexer past the last character of the identifier/reserved word so we do not need to call readChar again
 			return tok
 		} else if isDigit(l.ch) {
-			tok.Type = token.INT
-			tok.Literal = l.readNumber()
+			tok.Literal, tok.Type = l.readNumber()
+			tok.Pos, tok.End = start, l.pos()
 			// This early return is done for the same reason as the previous early return
 			return tok
 		} else {
-			tok = newToken(token.ILLIGAL, l.ch)
+			// Keep the bytes as they were written, a byte that is not valid UTF-8 would otherwise become U+FFFD
+			tok = token.Token{Type: token.ILLIGAL, Literal: l.text(l.position, l.readPosition)}
 		}
 	}
 
 	// Move the lexer to the next character
 	l.readChar()
 
+	tok.Pos, tok.End = start, l.pos()
+
 	return tok
 }
 
+/*
+* Function: Lexer.twoCharToken
+*
+* Parameters: next       rune            - The character that makes the current character a two character operator
+*             twoChar    token.TokenType - The type of the token when next follows the current character
+*             singleChar token.TokenType - The type of the token when it does not
+*
+* Returns: token.Token - The token starting at the current character, leaving the lexer on its last character
+ */
+func (l *Lexer) twoCharToken(next rune, twoChar, singleChar token.TokenType) token.Token {
+	if l.peekChar() != next {
+		return newToken(singleChar, l.ch)
+	}
+
+	ch := l.ch
+	l.readChar()
+	return token.Token{Type: twoChar, Literal: string(ch) + string(l.ch)}
+}
+
 /*
 * Function: newToken
 *
 * Parameters: tokenType token.TokenType - The type of token to create
-*             ch        byte            - The character to create the token from
+*             ch        rune            - The character to create the token from
 *
 * Returns: token.Token - A new token of the given type and character
 *
 * Description: Helper function that creates a new token of the given type and character
 *
  */
-func newToken(tokenType token.TokenType, ch byte) token.Token {
+func newToken(tokenType token.TokenType, ch rune) token.Token {
 	return token.Token{Type: tokenType, Literal: string(ch)}
 }
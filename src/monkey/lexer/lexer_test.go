@@ -8,8 +8,13 @@
 package lexer
 
 import (
+	"errors"
+	"io"
+	"strings"
 	"testing"
+	"testing/iotest"
 
+	"github.com/vtallen/go-interpreter/diagnostic"
 	"github.com/vtallen/go-interpreter/token"
 )
 
@@ -20,7 +25,7 @@ func TestNextToken(t *testing.T) {
             x + y;
             };
             let result = add(five, ten);
-            !-/*5;
+            !-/ *5;
             5 < 10 > 5;
             if (5 < 10) {
             return true;
@@ -29,6 +34,12 @@ func TestNextToken(t *testing.T) {
             }
             10 == 10;
             10 != 9;
+            "foobar"
+            "foo bar"
+            [1, 2];
+            {"foo": "bar"}
+            while (x) { break; continue; }
+            for (k, v in h) {}
             `
 
 	tests := []struct {
@@ -109,6 +120,39 @@ func TestNextToken(t *testing.T) {
 		{token.NOT_EQ, "!="},
 		{token.INT, "9"},
 		{token.SEMICOLON, ";"},
+		{token.STRING, "foobar"},
+		{token.STRING, "foo bar"},
+		{token.LBRACKET, "["},
+		{token.INT, "1"},
+		{token.COMMA, ","},
+		{token.INT, "2"},
+		{token.RBRACKET, "]"},
+		{token.SEMICOLON, ";"},
+		{token.LBRACE, "{"},
+		{token.STRING, "foo"},
+		{token.COLON, ":"},
+		{token.STRING, "bar"},
+		{token.RBRACE, "}"},
+		{token.WHILE, "while"},
+		{token.LPAREN, "("},
+		{token.IDENT, "x"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.BREAK, "break"},
+		{token.SEMICOLON, ";"},
+		{token.CONTINUE, "continue"},
+		{token.SEMICOLON, ";"},
+		{token.RBRACE, "}"},
+		{token.FOR, "for"},
+		{token.LPAREN, "("},
+		{token.IDENT, "k"},
+		{token.COMMA, ","},
+		{token.IDENT, "v"},
+		{token.IN, "in"},
+		{token.IDENT, "h"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.RBRACE, "}"},
 		{token.EOF, ""},
 	}
 
@@ -126,3 +170,642 @@ func TestNextToken(t *testing.T) {
 		}
 	}
 }
+
+func TestOperators(t *testing.T) {
+	input := "a <= b >= c < d > e && f || g % h & | <== ^ ~x << >> <<= >>= 0..5 1..=n 1.5..2 . .5"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "a"},
+		{token.LT_EQ, "<="},
+		{token.IDENT, "b"},
+		{token.GT_EQ, ">="},
+		{token.IDENT, "c"},
+		{token.LT, "<"},
+		{token.IDENT, "d"},
+		{token.GT, ">"},
+		{token.IDENT, "e"},
+		{token.AND, "&&"},
+		{token.IDENT, "f"},
+		{token.OR, "||"},
+		{token.IDENT, "g"},
+		{token.PERCENT, "%"},
+		{token.IDENT, "h"},
+		{token.BIT_AND, "&"},
+		{token.BIT_OR, "|"},
+		{token.LT_EQ, "<="},
+		{token.ASSIGN, "="},
+		{token.BIT_XOR, "^"},
+		{token.BIT_NOT, "~"},
+		{token.IDENT, "x"},
+		{token.SHIFT_LEFT, "<<"},
+		{token.SHIFT_RIGHT, ">>"},
+		{token.SHIFT_LEFT, "<<"},
+		{token.ASSIGN, "="},
+		{token.SHIFT_RIGHT, ">>"},
+		{token.ASSIGN, "="},
+		{token.INT, "0"},
+		{token.DOTDOT, ".."},
+		{token.INT, "5"},
+		{token.INT, "1"},
+		{token.DOTDOT_EQ, "..="},
+		{token.IDENT, "n"},
+		{token.FLOAT, "1.5"},
+		{token.DOTDOT, ".."},
+		{token.INT, "2"},
+		{token.ILLIGAL, "."},
+		{token.FLOAT, ".5"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - token wrong. expected=%s %q, got=%s %q",
+				i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestAssignmentOperators(t *testing.T) {
+	input := "x = 1; x += 2 -= 3 *= 4 /= 5 %= 6; x+=y == z; a / = b"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.PLUS_ASSIGN, "+="},
+		{token.INT, "2"},
+		{token.MINUS_ASSIGN, "-="},
+		{token.INT, "3"},
+		{token.ASTERISK_ASSIGN, "*="},
+		{token.INT, "4"},
+		{token.SLASH_ASSIGN, "/="},
+		{token.INT, "5"},
+		{token.PERCENT_ASSIGN, "%="},
+		{token.INT, "6"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.PLUS_ASSIGN, "+="},
+		{token.IDENT, "y"},
+		{token.EQ, "=="},
+		{token.IDENT, "z"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.SLASH, "/"},
+		{token.ASSIGN, "="},
+		{token.IDENT, "b"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - token wrong. expected=%s %q, got=%s %q",
+				i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestTokenPositions(t *testing.T) {
+	input := "let x = 10;\n  x + y"
+
+	tests := []struct {
+		expectedType token.TokenType
+		expectedPos  token.Position
+		expectedEnd  token.Position
+	}{
+		{token.LET, token.Position{Filename: "test.mk", Offset: 0, Line: 1, Column: 1}, token.Position{Filename: "test.mk", Offset: 3, Line: 1, Column: 4}},
+		{token.IDENT, token.Position{Filename: "test.mk", Offset: 4, Line: 1, Column: 5}, token.Position{Filename: "test.mk", Offset: 5, Line: 1, Column: 6}},
+		{token.ASSIGN, token.Position{Filename: "test.mk", Offset: 6, Line: 1, Column: 7}, token.Position{Filename: "test.mk", Offset: 7, Line: 1, Column: 8}},
+		{token.INT, token.Position{Filename: "test.mk", Offset: 8, Line: 1, Column: 9}, token.Position{Filename: "test.mk", Offset: 10, Line: 1, Column: 11}},
+		{token.SEMICOLON, token.Position{Filename: "test.mk", Offset: 10, Line: 1, Column: 11}, token.Position{Filename: "test.mk", Offset: 11, Line: 1, Column: 12}},
+		{token.IDENT, token.Position{Filename: "test.mk", Offset: 14, Line: 2, Column: 3}, token.Position{Filename: "test.mk", Offset: 15, Line: 2, Column: 4}},
+		{token.PLUS, token.Position{Filename: "test.mk", Offset: 16, Line: 2, Column: 5}, token.Position{Filename: "test.mk", Offset: 17, Line: 2, Column: 6}},
+		{token.IDENT, token.Position{Filename: "test.mk", Offset: 18, Line: 2, Column: 7}, token.Position{Filename: "test.mk", Offset: 19, Line: 2, Column: 8}},
+		{token.EOF, token.Position{Filename: "test.mk", Offset: 19, Line: 2, Column: 8}, token.Position{Filename: "test.mk", Offset: 19, Line: 2, Column: 8}},
+		// Asking for more tokens after EOF keeps returning EOF at the same position
+		{token.EOF, token.Position{Filename: "test.mk", Offset: 19, Line: 2, Column: 8}, token.Position{Filename: "test.mk", Offset: 19, Line: 2, Column: 8}},
+	}
+
+	l := NewFile("test.mk", input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Pos != tt.expectedPos {
+			t.Fatalf("tests[%d] - pos wrong. expected=%+v, got=%+v", i, tt.expectedPos, tok.Pos)
+		}
+
+		if tok.End != tt.expectedEnd {
+			t.Fatalf("tests[%d] - end wrong. expected=%+v, got=%+v", i, tt.expectedEnd, tok.End)
+		}
+	}
+}
+
+func TestStrings(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedLiteral string
+		expectedErrors  []string
+	}{
+		{`"foobar"`, "foobar", nil},
+		{`"foo bar"`, "foo bar", nil},
+		{`""`, "", nil},
+		{`"a\nb\tc\rd"`, "a\nb\tc\rd", nil},
+		{`"say \"hi\""`, `say "hi"`, nil},
+		{`"back\\slash"`, `back\slash`, nil},
+		{`"\u{48}\u{e9}\u{1F600}"`, "Hé😀", nil},
+		{`"multi
+line"`, "multi\nline", nil},
+		{`"bad \q escape"`, `bad \q escape`, []string{`invalid escape sequence: \q`}},
+		{`"\u{110000}"`, `\u{110000}`, []string{`invalid escape sequence: \u{110000} is not a valid unicode code point`}},
+		{`"\u{41"`, `\u{41`, []string{`invalid escape sequence: \u{41 is missing its closing }`}},
+		{`"\u41"`, `\u41`, []string{`invalid escape sequence: \u must be followed by {`}},
+		{`"never closed`, "never closed", []string{"string literal not terminated"}},
+		{`"ends in escape\`, "ends in escape", []string{"string literal not terminated"}},
+		{`"☕ très 😀"`, "☕ très 😀", nil},
+		{"\"bad \xff byte\"", "bad \uFFFD byte", []string{"invalid UTF-8 encoding: byte 0xff"}},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+
+		tok := l.NextToken()
+		if tok.Type != token.STRING {
+			t.Fatalf("input %q - tokentype wrong. expected=%q, got=%q", tt.input, token.STRING, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Errorf("input %q - literal wrong. expected=%q, got=%q", tt.input, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok := l.NextToken(); tok.Type != token.EOF {
+			t.Errorf("input %q - expected EOF after string, got=%q", tt.input, tok.Type)
+		}
+
+		errors := l.Errors()
+		if len(errors) != len(tt.expectedErrors) {
+			t.Fatalf("input %q - wrong number of errors. expected=%d, got=%d (%v)", tt.input, len(tt.expectedErrors), len(errors), errors)
+		}
+
+		for i, msg := range tt.expectedErrors {
+			if errors[i].Message != msg {
+				t.Errorf("input %q - errors[%d] wrong. expected=%q, got=%q", tt.input, i, msg, errors[i].Message)
+			}
+		}
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []token.Token // Only Type and Literal are checked
+	}{
+		{"1234", []token.Token{{Type: token.INT, Literal: "1234"}}},
+		{"3.14", []token.Token{{Type: token.FLOAT, Literal: "3.14"}}},
+		{".5", []token.Token{{Type: token.FLOAT, Literal: ".5"}}},
+		{"1e-9", []token.Token{{Type: token.FLOAT, Literal: "1e-9"}}},
+		{"2.5E+10", []token.Token{{Type: token.FLOAT, Literal: "2.5E+10"}}},
+		{"6e3", []token.Token{{Type: token.FLOAT, Literal: "6e3"}}},
+		{"0", []token.Token{{Type: token.INT, Literal: "0"}}},
+		{"0.25", []token.Token{{Type: token.FLOAT, Literal: "0.25"}}},
+		{"0e5", []token.Token{{Type: token.FLOAT, Literal: "0e5"}}},
+		// A '.' followed by another '.' is a range, not a fraction
+		{"1..5", []token.Token{{Type: token.INT, Literal: "1"}, {Type: token.DOTDOT, Literal: ".."}, {Type: token.INT, Literal: "5"}}},
+		{"0xFF", []token.Token{{Type: token.INT, Literal: "0xFF"}}},
+		{"0XaB", []token.Token{{Type: token.INT, Literal: "0XaB"}}},
+		{"0o755", []token.Token{{Type: token.INT, Literal: "0o755"}}},
+		{"0b1010", []token.Token{{Type: token.INT, Literal: "0b1010"}}},
+		{"1_000_000", []token.Token{{Type: token.INT, Literal: "1_000_000"}}},
+		{"0x_dead_beef", []token.Token{{Type: token.INT, Literal: "0x_dead_beef"}}},
+		{"1_000.000_1e1_0", []token.Token{{Type: token.FLOAT, Literal: "1_000.000_1e1_0"}}},
+		{"0b1+0", []token.Token{
+			{Type: token.INT, Literal: "0b1"},
+			{Type: token.PLUS, Literal: "+"},
+			{Type: token.INT, Literal: "0"},
+		}},
+		{"-.5*2", []token.Token{
+			{Type: token.MINUS, Literal: "-"},
+			{Type: token.FLOAT, Literal: ".5"},
+			{Type: token.ASTERISK, Literal: "*"},
+			{Type: token.INT, Literal: "2"},
+		}},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+
+		for i, expected := range tt.expected {
+			tok := l.NextToken()
+			if tok.Type != expected.Type || tok.Literal != expected.Literal {
+				t.Errorf("input %q - tokens[%d] wrong. expected=%s %q, got=%s %q",
+					tt.input, i, expected.Type, expected.Literal, tok.Type, tok.Literal)
+			}
+		}
+
+		if tok := l.NextToken(); tok.Type != token.EOF {
+			t.Errorf("input %q - expected EOF, got=%s %q", tt.input, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestMalformedNumbers(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedLiteral string
+		expectedError   string
+		expectedSpan    [2]int // The columns the error starts and ends at
+	}{
+		{"0x", "0x", "hexadecimal literal has no digits", [2]int{1, 3}},
+		{"0b_", "0b_", "binary literal has no digits", [2]int{1, 4}},
+		{"0b102", "0b102", "invalid digit '2' in binary literal", [2]int{5, 6}},
+		{"0o78", "0o78", "invalid digit '8' in octal literal", [2]int{4, 5}},
+		{"0xFG", "0xFG", "invalid digit 'G' in hexadecimal literal", [2]int{4, 5}},
+		{"1__0", "1__0", "'_' must separate successive digits", [2]int{2, 3}},
+		{"1_", "1_", "'_' must separate successive digits", [2]int{2, 3}},
+		{"0x1_", "0x1_", "'_' must separate successive digits", [2]int{4, 5}},
+		{"1_.5", "1_.5", "'_' must separate successive digits", [2]int{2, 3}},
+		{"017", "017", "integer literal 017 has a leading zero, use 0o for octal", [2]int{1, 4}},
+		{"08", "08", "integer literal 08 has a leading zero, use 0o for octal", [2]int{1, 3}},
+		{"0_1", "0_1", "integer literal 0_1 has a leading zero, use 0o for octal", [2]int{1, 4}},
+		{"1.", "1.", "malformed number literal 1., its fraction has no digits", [2]int{1, 3}},
+		{"1e", "1e", "malformed number literal 1e, its exponent has no digits", [2]int{1, 3}},
+		{"1e+", "1e+", "malformed number literal 1e+, its exponent has no digits", [2]int{1, 4}},
+		{"2.5E_", "2.5E_", "malformed number literal 2.5E_, its exponent has no digits", [2]int{1, 6}},
+		{"3.e5", "3.e5", "malformed number literal 3.e5, its fraction has no digits", [2]int{1, 5}},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+
+		tok := l.NextToken()
+		if tok.Literal != tt.expectedLiteral {
+			t.Errorf("input %q - literal wrong. expected=%q, got=%q", tt.input, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok := l.NextToken(); tok.Type != token.EOF {
+			t.Errorf("input %q - expected EOF, got=%s %q", tt.input, tok.Type, tok.Literal)
+		}
+
+		errors := l.Errors()
+		if len(errors) != 1 {
+			t.Fatalf("input %q - expected 1 error, got=%d (%v)", tt.input, len(errors), errors)
+		}
+
+		if errors[0].Code != diagnostic.InvalidNumber || errors[0].Message != tt.expectedError {
+			t.Errorf("input %q - error wrong. expected=%q, got=%s", tt.input, tt.expectedError, errors[0].Error())
+		}
+
+		span := [2]int{errors[0].Span.Start.Column, errors[0].Span.End.Column}
+		if span != tt.expectedSpan {
+			t.Errorf("input %q - span wrong. expected=%v, got=%v", tt.input, tt.expectedSpan, span)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// leading
+let x = 5; // trailing
+/* block /* nested */ still comment */ x / 2
+/**/`
+
+	skipped := []token.TokenType{
+		token.LET, token.IDENT, token.ASSIGN, token.INT, token.SEMICOLON,
+		token.IDENT, token.SLASH, token.INT, token.EOF,
+	}
+
+	l := New(input)
+	for i, expected := range skipped {
+		if tok := l.NextToken(); tok.Type != expected {
+			t.Fatalf("skipped[%d] - tokentype wrong. expected=%q, got=%q", i, expected, tok.Type)
+		}
+	}
+
+	kept := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.COMMENT, "// leading"},
+		{token.LET, "let"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INT, "5"},
+		{token.SEMICOLON, ";"},
+		{token.COMMENT, "// trailing"},
+		{token.COMMENT, "/* block /* nested */ still comment */"},
+		{token.IDENT, "x"},
+		{token.SLASH, "/"},
+		{token.INT, "2"},
+		{token.COMMENT, "/**/"},
+		{token.EOF, ""},
+	}
+
+	l = New(input)
+	l.SetMode(ScanComments)
+	for i, tt := range kept {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("kept[%d] - token wrong. expected=%s %q, got=%s %q",
+				i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+	}
+
+	if len(l.Errors()) != 0 {
+		t.Errorf("unexpected errors: %v", l.Errors())
+	}
+}
+
+func TestUnterminatedComment(t *testing.T) {
+	l := New("1 /* open /* nested */")
+	l.SetMode(ScanComments)
+
+	l.NextToken()
+	tok := l.NextToken()
+	if tok.Type != token.COMMENT || tok.Literal != "/* open /* nested */" {
+		t.Errorf("token wrong. got=%s %q", tok.Type, tok.Literal)
+	}
+
+	if tok := l.NextToken(); tok.Type != token.EOF {
+		t.Errorf("expected EOF, got=%s %q", tok.Type, tok.Literal)
+	}
+
+	errors := l.Errors()
+	if len(errors) != 1 || errors[0].Code != diagnostic.UnterminatedComment {
+		t.Fatalf("expected 1 %s error, got=%v", diagnostic.UnterminatedComment, errors)
+	}
+
+	span := [2]int{errors[0].Span.Start.Column, errors[0].Span.End.Column}
+	if span != [2]int{3, 23} {
+		t.Errorf("span wrong. expected=[3 23], got=%v", span)
+	}
+}
+
+func TestUnicodeIdentifiers(t *testing.T) {
+	input := "let café = x1 + π_2;\n日本語 ٣ 😀 a٣"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedColumn  int
+	}{
+		{token.LET, "let", 1},
+		{token.IDENT, "café", 5},
+		{token.ASSIGN, "=", 10},
+		{token.IDENT, "x1", 12},
+		{token.PLUS, "+", 15},
+		{token.IDENT, "π_2", 17},
+		{token.SEMICOLON, ";", 20},
+		{token.IDENT, "日本語", 1},
+		// Digits from other scripts may follow a letter, but can not start an identifier or a number
+		{token.ILLIGAL, "٣", 5},
+		{token.ILLIGAL, "😀", 7},
+		{token.IDENT, "a٣", 9},
+		{token.EOF, "", 11},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - token wrong. expected=%s %q, got=%s %q",
+				i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+
+		if tok.Pos.Column != tt.expectedColumn {
+			t.Errorf("tests[%d] - column wrong. expected=%d, got=%d", i, tt.expectedColumn, tok.Pos.Column)
+		}
+	}
+
+	if len(l.Errors()) != 0 {
+		t.Errorf("unexpected errors: %v", l.Errors())
+	}
+}
+
+func TestInvalidUTF8(t *testing.T) {
+	l := New("a \xff\xfeb é")
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "a"},
+		{token.ILLIGAL, "\xff"},
+		{token.ILLIGAL, "\xfe"},
+		{token.IDENT, "b"},
+		{token.IDENT, "é"},
+		{token.EOF, ""},
+	}
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - token wrong. expected=%s %q, got=%s %q",
+				i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+	}
+
+	errors := l.Errors()
+	if len(errors) != 2 {
+		t.Fatalf("expected 2 errors, got=%d (%v)", len(errors), errors)
+	}
+
+	for i, err := range errors {
+		if err.Code != diagnostic.InvalidUTF8 {
+			t.Errorf("errors[%d] - code wrong. expected=%s, got=%s", i, diagnostic.InvalidUTF8, err.Code)
+		}
+
+		if err.Span.Start.Offset != 2+i || err.Span.End.Offset != 3+i {
+			t.Errorf("errors[%d] - span wrong. got=%d-%d", i, err.Span.Start.Offset, err.Span.End.Offset)
+		}
+	}
+}
+
+func TestNulIsNotEOF(t *testing.T) {
+	l := New("puts(1)\x00puts(2)")
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "puts"},
+		{token.LPAREN, "("},
+		{token.INT, "1"},
+		{token.RPAREN, ")"},
+		{token.ILLIGAL, "\x00"},
+		{token.IDENT, "puts"},
+		{token.LPAREN, "("},
+		{token.INT, "2"},
+		{token.RPAREN, ")"},
+		{token.EOF, ""},
+	}
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - token wrong. expected=%s %q, got=%s %q",
+				i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+	}
+
+	errors := l.Errors()
+	if len(errors) != 1 {
+		t.Fatalf("expected 1 error, got=%d (%v)", len(errors), errors)
+	}
+
+	if errors[0].Code != diagnostic.InvalidCharacter {
+		t.Errorf("code wrong. expected=%s, got=%s", diagnostic.InvalidCharacter, errors[0].Code)
+	}
+
+	if errors[0].Span.Start.Offset != 7 || errors[0].Span.End.Offset != 8 {
+		t.Errorf("span wrong. got=%d-%d", errors[0].Span.Start.Offset, errors[0].Span.End.Offset)
+	}
+}
+
+func TestByteOrderMark(t *testing.T) {
+	l := New("\uFEFFlet")
+
+	tok := l.NextToken()
+	if tok.Type != token.LET {
+		t.Fatalf("tokentype wrong. expected=%q, got=%q", token.LET, tok.Type)
+	}
+
+	if tok.Pos.Offset != 3 || tok.Pos.Column != 1 {
+		t.Errorf("pos wrong. expected offset 3, column 1, got=%+v", tok.Pos)
+	}
+}
+
+func TestReaderMatchesString(t *testing.T) {
+	inputs := []string{
+		"let add = fn(x, y) { x + y; };\nadd(1, 2) != [3, 4][0];",
+		`{"key": "value with \"escapes\" and \u{1F600}", "ends in": "newline\n"}`,
+		"0x_FF 1_000 3.14e-2 .5 1e999 0b102 1__0",
+		"// line comment\nx /* block /* nested */ comment */ y /* never closed",
+		"let café = \"☕\"; ٣ \xff\xfe 日本語",
+		"\uFEFFlet bom = true;",
+		`"never closed`,
+		"",
+		// A long token crosses several chunks of the reader
+		`"` + strings.Repeat("long string ", 2*chunkSize) + `"`,
+	}
+
+	readers := map[string]func(io.Reader) io.Reader{
+		"one byte": iotest.OneByteReader,
+		"half":     iotest.HalfReader,
+		"data err": iotest.DataErrReader,
+	}
+
+	for _, input := range inputs {
+		for _, mode := range []Mode{0, ScanComments} {
+			for name, wrap := range readers {
+				expected := New(input)
+				expected.SetMode(mode)
+
+				l := NewReader(wrap(strings.NewReader(input)))
+				l.SetMode(mode)
+
+				for i := 0; ; i++ {
+					want, got := expected.NextToken(), l.NextToken()
+					if want != got {
+						t.Fatalf("%s reader, input %.40q - tokens[%d] wrong. expected=%+v, got=%+v",
+							name, input, i, want, got)
+					}
+
+					if want.Type == token.EOF {
+						break
+					}
+				}
+
+				if len(l.Errors()) != len(expected.Errors()) {
+					t.Fatalf("%s reader, input %.40q - wrong number of errors. expected=%v, got=%v",
+						name, input, expected.Errors(), l.Errors())
+				}
+
+				for i, err := range expected.Errors() {
+					if l.Errors()[i].Error() != err.Error() || l.Errors()[i].Span != err.Span {
+						t.Errorf("%s reader, input %.40q - errors[%d] wrong. expected=%v, got=%v",
+							name, input, i, err, l.Errors()[i])
+					}
+				}
+			}
+		}
+	}
+}
+
+// repeatReader produces the same text over and over, up to a total of size bytes, without ever holding all of it
+type repeatReader struct {
+	text string
+	size int
+	read int
+}
+
+func (r *repeatReader) Read(p []byte) (int, error) {
+	if r.read >= r.size {
+		return 0, io.EOF
+	}
+
+	n := 0
+	for n < len(p) && r.read < r.size {
+		p[n] = r.text[r.read%len(r.text)]
+		n++
+		r.read++
+	}
+
+	return n, nil
+}
+
+func TestReaderBuffersBoundedInput(t *testing.T) {
+	const line = "let x = fn(a) { a * 2 }; // double\n"
+	const lines = 100000
+
+	l := NewReader(&repeatReader{text: line, size: len(line) * lines})
+
+	count := 0
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		if cap(l.buf) > 4*chunkSize {
+			t.Fatalf("buffer grew to %d bytes after %d tokens", cap(l.buf), count)
+		}
+		count++
+	}
+
+	if count != 13*lines {
+		t.Errorf("wrong number of tokens. expected=%d, got=%d", 13*lines, count)
+	}
+
+	if len(l.Errors()) != 0 {
+		t.Errorf("unexpected errors: %v", l.Errors())
+	}
+}
+
+func TestReaderError(t *testing.T) {
+	r := io.MultiReader(strings.NewReader("let x"), iotest.ErrReader(errors.New("disk on fire")))
+	l := NewReader(r)
+
+	for _, expected := range []token.TokenType{token.LET, token.IDENT, token.EOF} {
+		if tok := l.NextToken(); tok.Type != expected {
+			t.Fatalf("tokentype wrong. expected=%q, got=%q", expected, tok.Type)
+		}
+	}
+
+	errs := l.Errors()
+	if len(errs) != 1 || errs[0].Code != diagnostic.ReadError || errs[0].Message != "error reading input: disk on fire" {
+		t.Fatalf("expected a %s error, got=%v", diagnostic.ReadError, errs)
+	}
+}
@@ -1,20 +1,260 @@
+/*
+* File: main.go
+*
+* Description: Entry point of the monkey command. Runs Monkey programs from files, the command line or stdin, and
+*              starts the REPL when used interactively
+*
+ */
+
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/user"
 
+	"github.com/vtallen/go-interpreter/ast"
+	"github.com/vtallen/go-interpreter/compiler"
+	"github.com/vtallen/go-interpreter/diagnostic"
+	"github.com/vtallen/go-interpreter/evaluator"
+	"github.com/vtallen/go-interpreter/lexer"
+	"github.com/vtallen/go-interpreter/object"
+	"github.com/vtallen/go-interpreter/parser"
 	"github.com/vtallen/go-interpreter/repl"
+	"github.com/vtallen/go-interpreter/vm"
+)
+
+// Exit codes of the monkey command
+const (
+	exitOK    = 0 // The program ran without errors
+	exitError = 1 // The program could not be parsed or stopped with a runtime error
+	exitUsage = 2 // The command line was not valid
 )
 
+const usage = `Usage:
+  monkey [--engine=eval|vm]                          start the REPL, or run the program on stdin when it is not a terminal
+  monkey run [--engine=eval|vm] <file> [args...]     run the program in file
+  monkey [--engine=eval|vm] -e <program> [args...]   run program and print its result
+
+The args are available to the program as the array args.
+
+--engine picks what runs programs: eval walks the syntax tree, vm compiles to bytecode and runs it on the virtual
+machine. The default is eval. The REPL always uses eval.
+`
+
+/*
+* Type: engine
+*
+* Description: Runs a parsed program with args bound to the array args, anything the program prints goes to
+*              stdout. Returns the value of the program or the runtime error it stopped with
+ */
+type engine func(program *ast.Program, args *object.Array, stdout io.Writer) (object.Object, error)
+
+var engines = map[string]engine{
+	"eval": runEvaluator,
+	"vm":   runVM,
+}
+
 func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+/*
+* Function: run
+*
+* Parameters: arguments []string  - The command line arguments, without the name of the command
+*             stdin     *os.File  - Standard input
+*             stdout    io.Writer - Where the output of the program goes
+*             stderr    io.Writer - Where errors go
+*
+* Returns: int - The exit code of the command
+*
+* Description: Decides what to run based on the command line and whether stdin is a terminal
+ */
+func run(arguments []string, stdin *os.File, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("monkey", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() { fmt.Fprint(stderr, usage) }
+
+	expr := flags.String("e", "", "run `program` and print its result")
+	engineName := flags.String("engine", "eval", "run programs with `engine`, eval or vm")
+
+	if err := flags.Parse(arguments); err != nil {
+		return parseFailure(err)
+	}
+
+	isSet := func(name string) bool {
+		set := false
+		flags.Visit(func(f *flag.Flag) { set = set || f.Name == name })
+		return set
+	}
+
+	args := flags.Args()
+
+	// Flags may also come after run, up to the name of the file
+	if len(args) > 0 && args[0] == "run" {
+		if err := flags.Parse(args[1:]); err != nil {
+			return parseFailure(err)
+		}
+		args = append([]string{"run"}, flags.Args()...)
+	}
+
+	engine, ok := engines[*engineName]
+	if !ok {
+		fmt.Fprintf(stderr, "monkey: unknown engine %q\n", *engineName)
+		flags.Usage()
+		return exitUsage
+	}
+
+	switch {
+	case isSet("e"):
+		return execute("-e", *expr, args, true, engine, stdout, stderr)
+
+	case len(args) > 0 && args[0] == "run":
+		if len(args) < 2 {
+			fmt.Fprintln(stderr, "monkey run: missing file to run")
+			flags.Usage()
+			return exitUsage
+		}
+
+		source, err := os.ReadFile(args[1])
+		if err != nil {
+			fmt.Fprintf(stderr, "monkey run: %s\n", err)
+			return exitError
+		}
+
+		return execute(args[1], string(source), args[2:], false, engine, stdout, stderr)
+
+	case len(args) > 0:
+		fmt.Fprintf(stderr, "monkey: unknown command %q\n", args[0])
+		flags.Usage()
+		return exitUsage
+
+	case !isTerminal(stdin):
+		source, err := io.ReadAll(stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "monkey: %s\n", err)
+			return exitError
+		}
+
+		return execute("<stdin>", string(source), nil, false, engine, stdout, stderr)
+
+	default:
+		greet(stdout)
+		repl.Start(stdin, stdout)
+		return exitOK
+	}
+}
+
+// parseFailure returns the exit code for a command line the flag package rejected, asking for help is not an error
+func parseFailure(err error) int {
+	if err == flag.ErrHelp {
+		return exitOK
+	}
+	return exitUsage
+}
+
+/*
+* Function: execute
+*
+* Parameters: filename    string    - The name to report errors with
+*             source      string    - The program to run
+*             args        []string  - The arguments to pass to the program as the array args
+*             printResult bool      - Whether to print the value the program evaluates to
+*             run         engine    - What runs the program
+*             stdout      io.Writer - Where the result of the program and anything it prints goes
+*             stderr      io.Writer - Where errors go
+*
+* Returns: int - The exit code of the command
+*
+* Description: Parses and runs a program, reporting any errors on stderr
+ */
+func execute(filename, source string, args []string, printResult bool, run engine, stdout, stderr io.Writer) int {
+	l := lexer.NewFile(filename, source)
+	p := parser.New(l)
+
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		diagnostic.RenderAll(stderr, source, p.Errors())
+		return exitError
+	}
+
+	result, err := run(program, argsArray(args), stdout)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: ERROR: %s\n", filename, err)
+		return exitError
+	}
+
+	if printResult && result != nil && result.Type() != object.NULL_OBJ {
+		fmt.Fprintln(stdout, result.Inspect())
+	}
+
+	return exitOK
+}
+
+// runEvaluator runs a program by walking its syntax tree
+func runEvaluator(program *ast.Program, args *object.Array, stdout io.Writer) (object.Object, error) {
+	env := object.NewEnvironment()
+	env.SetOutput(stdout)
+	env.Set("args", args)
+
+	evaluated := evaluator.Eval(program, env)
+	if errObj, ok := evaluated.(*object.Error); ok {
+		return nil, fmt.Errorf("%s", errObj.Message)
+	}
+
+	return evaluated, nil
+}
+
+// runVM compiles a program to bytecode and runs it on the virtual machine
+func runVM(program *ast.Program, args *object.Array, stdout io.Writer) (object.Object, error) {
+	symbolTable := compiler.NewGlobalSymbolTable()
+	globals := make([]object.Object, vm.GlobalsSize)
+	globals[symbolTable.Define("args").Index] = args
+
+	comp := compiler.NewWithState(symbolTable, []object.Object{})
+	if err := comp.Compile(program); err != nil {
+		return nil, err
+	}
+
+	machine := vm.NewWithGlobalsState(comp.Bytecode(), globals)
+	machine.SetOutput(stdout)
+
+	if err := machine.Run(); err != nil {
+		return nil, err
+	}
+
+	return machine.LastPoppedStackElem(), nil
+}
+
+// argsArray converts the command line arguments into the array of strings programs see as args
+func argsArray(args []string) *object.Array {
+	elements := []object.Object{}
+	for _, arg := range args {
+		elements = append(elements, &object.String{Value: arg})
+	}
+
+	return &object.Array{Elements: elements}
+}
+
+// isTerminal reports whether f is an interactive terminal rather than a file or a pipe
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+func greet(out io.Writer) {
 	user, err := user.Current()
 	if err != nil {
 		panic(err)
 	}
 
-	fmt.Printf("Hello %s! This is the Monkey programming language!\n", user.Username)
-	fmt.Printf("Enter commands\n")
-	repl.Start(os.Stdin, os.Stdout)
+	fmt.Fprintf(out, "Hello %s! This is the Monkey programming language!\n", user.Username)
+	fmt.Fprintf(out, "Enter commands\n")
 }
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// stdinFile returns a file to use as stdin containing input. Files are never terminals, so they are read as a program
+func stdinFile(t *testing.T, input string) *os.File {
+	path := filepath.Join(t.TempDir(), "stdin")
+	if err := os.WriteFile(path, []byte(input), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+
+	return f
+}
+
+func TestRun(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "script.mk")
+	if err := os.WriteFile(script, []byte(`len(args) + 1`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	broken := filepath.Join(t.TempDir(), "broken.mk")
+	if err := os.WriteFile(broken, []byte("add(1, 2;"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	nul := filepath.Join(t.TempDir(), "nul.mk")
+	if err := os.WriteFile(nul, []byte("puts(1)\x00puts(2)"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args           []string
+		stdin          string
+		expectedCode   int
+		expectedStdout string
+		expectedStderr string // Only checked to be contained in stderr
+	}{
+		{[]string{"-e", "1 + 2"}, "", exitOK, "3\n", ""},
+		{[]string{"-e", `"hi " + args[0]`, "there"}, "", exitOK, "hi there\n", ""},
+		{[]string{"-e", "if (false) { 1 }"}, "", exitOK, "", ""},
+		{[]string{"-e", "1 +"}, "", exitError, "", "-e:1:4: error[P0002]: no prefix parse functions for EOF found"},
+		{[]string{"-e", "1 + true"}, "", exitError, "", "-e: ERROR: type mismatch: INTEGER + BOOLEAN"},
+		{[]string{"-e", "let x = 1; x += 2"}, "", exitOK, "3\n", ""},
+		{[]string{"-e", "f() = 1"}, "", exitError, "", "-e:1:1: error[P0006]: cannot assign to f()"},
+		{[]string{"run", script, "a", "b"}, "", exitOK, "", ""},
+		{[]string{"run", broken}, "", exitError, "", "broken.mk:1:9: error[P0001]"},
+		{[]string{"run", nul}, "", exitError, "", "nul.mk:1:8: error[L0007]: invalid character NUL (U+0000)"},
+		{[]string{"run", filepath.Join(t.TempDir(), "missing.mk")}, "", exitError, "", "monkey run:"},
+		{[]string{"run"}, "", exitUsage, "", "missing file to run"},
+		{[]string{"jump"}, "", exitUsage, "", `unknown command "jump"`},
+		{[]string{"-x"}, "", exitUsage, "", "flag provided but not defined: -x"},
+		{nil, "len([1, 2, 3])", exitOK, "", ""},
+		{nil, "foobar", exitError, "", "<stdin>: ERROR: identifier not found: foobar"},
+		{[]string{"--engine=vm", "-e", "1 + 2"}, "", exitOK, "3\n", ""},
+		{[]string{"--engine=vm", "-e", "let x = 5"}, "", exitOK, "", ""},
+		{[]string{"--engine=vm", "-e", "1 + true"}, "", exitError, "", "-e: ERROR: type mismatch: INTEGER + BOOLEAN"},
+		{[]string{"run", "--engine=vm", script, "a"}, "", exitOK, "", ""},
+		{[]string{"-engine", "vm"}, "foobar", exitError, "", "<stdin>: ERROR: identifier not found: foobar"},
+		{[]string{"--engine=jit", "-e", "1"}, "", exitUsage, "", `unknown engine "jit"`},
+	}
+
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+
+		code := run(tt.args, stdinFile(t, tt.stdin), &stdout, &stderr)
+
+		if code != tt.expectedCode {
+			t.Errorf("args %q: exit code wrong. expected=%d, got=%d (stderr=%q)", tt.args, tt.expectedCode, code, stderr.String())
+		}
+
+		if stdout.String() != tt.expectedStdout {
+			t.Errorf("args %q: stdout wrong. expected=%q, got=%q", tt.args, tt.expectedStdout, stdout.String())
+		}
+
+		if !strings.Contains(stderr.String(), tt.expectedStderr) {
+			t.Errorf("args %q: stderr wrong. expected to contain %q, got=%q", tt.args, tt.expectedStderr, stderr.String())
+		}
+	}
+}
+
+func TestEnginesAgree(t *testing.T) {
+	programs := []string{
+		"1 + 2 * 3 - 4 / 2",
+		`"mon" + "key"`,
+		"[1, 2, 3][1] + {\"a\": 5}[\"a\"]",
+		"if (1 < 2) { 10 } else { 20 }",
+		"if (1 > 2) { 10 }",
+		"fn(a) { fn(b) { a * b } }(6)(7)",
+		"fn(fib) { fib(fib, 10) }(fn(self, n) { if (n < 2) { n } else { self(self, n - 1) + self(self, n - 2) } })",
+		`puts("a", [1, {"b": true}]); len("four")`,
+		"!-5 == false",
+		"1.5 * 2 + 1 / 4.0",
+		"[1.0 / 0, -.5, 2 == 2.0, 3 < 2.5]",
+		"-1.5 - 1",
+		"[9223372036854775807 + 1, -(-9223372036854775807 - 1), 99999999999999999999 / 7, 2e19 < 20000000000000000001]",
+		"len(args)",
+		"1 / 0",
+		"5 + true; puts(1)",
+		"-\"a\"",
+		"fn(a) { a }(1, 2)",
+		`len(1, 2)`,
+		"[7 % 3, -7 % 3, 7.5 % 2, 1 <= 1, 2 >= 3, 1 < 2 && 3 > 2, 0 || 5, false || if (false) { 1 }, puts(1) && puts(2)]",
+		"false && 1 / 0; true || 1 / 0; 5 % 0",
+		"[12 & 10, 12 | 10, 12 ^ 10, ~5, 1 << 70, -1 >> 99, (1 << 64) & ~0xff]",
+		"1 << -1",
+		"let double = fn(x) { return x * 2; }; let a = double(21); a",
+		"let f = fn() { return; 1 }; f()",
+		"let i = 0; while (i < 10) { let i = i + 1; if (i % 2 == 0) { continue } if (i > 7) { break } puts(i) }; i",
+		"fn() { while (true) { 1 / 0 } }()",
+		"let i = 0; while (i < 2) { let i = i + 1 }",
+		`for (x in [1, "a"]) { puts(x) }`,
+		`for (i, c in "hé") { puts(i, c) }; for (k, v in {"x": 1, 2: [3]}) { puts(k, v) }; len("é")`,
+		"[0..3, 1..=2, 5..1]",
+		"let n = 0; for (x in 0..=100) { if (x % 3 != 0) { continue } let n = n + x }; n",
+		"for (x in 1) { }",
+		"1.0..2",
+		"let sign = fn(n) { if (n < 0) { -1 } else if (n > 0) { 1 } else { 0 } }; [sign(-5), sign(0), sign(5)]",
+		"let f = fn(n) { if (n == 0) { 0 } else { f(n - 1) } }; f(3000000)",
+		"let x = 5",
+		"7; let y = 2",
+		"fn() { 1 }",
+		"let f = fn(a, b) { a + b }; [f, len]",
+		"let g = fn() { let a = 42; a }; let f = fn() { if (false) { let z = 1 }; z }; g(); f()",
+		"fn() { if (false) { let z = 1 }; z + 1 }()",
+		"if (false) { let q = 1 }; q",
+		"let x = 1; x = x + 1; x += 10; x",
+		`let a = [1, 2, 3]; let h = {"k": a}; h["k"][0] = 9; a[1] *= 5; h["n"] = a[2] -= 1; [a, h]`,
+		"let fib = [0, 1]; let i = 0; while (i < 8) { let next = fib[0] + fib[1]; fib[0] = fib[1]; fib[1] = next; i += 1 }; fib",
+		"let n = 0; let inc = fn(by) { n += by }; for (x in 1..=4) { inc(x) }; n",
+		"undefined = 1",
+		"len = 1",
+		"fn() { let c = 0; fn() { c += 1 } }()()",
+		"let a = [1]; a[1] = 2",
+		"let a = [1]; a[3] += 1",
+		"let i = 0; while (i < 5000) { i += 1; [1, 2, if (true) { continue }] }; i",
+		"let i = 0; for (x in 0..5000) { i = 1 + (if (true) { continue } else { 0 }) }; i",
+		"let i = 0; while (true) { i += 1; let x = if (i > 3) { break } else { 1 } }; i",
+		"let i = 0; while (i < 5) { i += 1; puts(if (i > 2) { continue } else { i }) }",
+		"let i = 0; while (true) { i += (if (i == 2) { break } else { 1 }) }; i",
+		"fn() { let x = if (true) { return 5 }; 10 }()",
+		"for (k in []) {}; k + 1",
+		"let f = fn() { for (k in []) {}; k }; f()",
+		"let a = [1]; a[0] = a; a",
+		`let h = {}; h["h"] = h; puts(h); [h, h]`,
+		"fn() { let x = 1; let f = fn() { x }; x = 5; f() }()",
+		"fn() { let x = 1; let f = fn() { x }; let x = 2; f() }()",
+		"fn() { let fs = [0, 0]; for (i in 0..2) { fs[i] = fn() { i } }; [fs[0](), fs[1]()] }()",
+		"let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } }; let odd = fn(n) { if (n == 0) { false } else { even(n - 1) } }; even(10)",
+		`puts("start"); if (false) { undefined = 1 }`,
+		`puts("start"); undefined = 1`,
+		`puts("start"); len = puts("value")`,
+		`puts("start"); len += puts("value")`,
+		`puts("start"); if (false) { zzz }; zzz`,
+		"let f = fn() { let g = fn() { y }; let y = 5; g() }; f()",
+		"fn() { let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } }; let odd = fn(n) { if (n == 0) { false } else { even(n - 1) } }; even(9) }()",
+		"fn() { let g = fn() { y = 3 }; let y = 1; g(); y }()",
+		"[fn() { 5; let x = 1 }(), if (true) { 5; let x = 1 }, if (true) { 5; while (false) { } }, if (true) { 5; for (x in []) { } }]",
+		"fn() { let g = fn() { y }; g(); let y = 1 }()",
+		`{[1]: puts("side")}`,
+		`{"a": puts("first"), [1]: puts("second")}`,
+		`let k = "b"; {k: 1, k + "c": 2}`,
+		"fn() {} + 1",
+		"{}[fn() {}]",
+		"-fn() {}",
+		"[1][fn() {}]",
+		"let f = fn(n) { if (n == 0) { 0 } else { 1 + f(n - 1) } }; [f(1023), f(1024)]",
+		"let f = fn(a, b, c, n) { let x = a + b; let y = c * x; if (n == 0) { y } else { 1 + f(a, b, c, n - 1) } }; f(1, 2, 3, 1023)",
+		"let f = fn(a, b, c, n) { let x = a + b; let y = c * x; if (n == 0) { y } else { 1 + f(a, b, c, n - 1) } }; f(1, 2, 3, 1024)",
+	}
+
+	for _, program := range programs {
+		var evalStdout, evalStderr, vmStdout, vmStderr bytes.Buffer
+
+		evalCode := run([]string{"--engine=eval", "-e", program, "x"}, stdinFile(t, ""), &evalStdout, &evalStderr)
+		vmCode := run([]string{"--engine=vm", "-e", program, "x"}, stdinFile(t, ""), &vmStdout, &vmStderr)
+
+		if evalCode != vmCode {
+			t.Errorf("%q: exit codes differ. eval=%d, vm=%d", program, evalCode, vmCode)
+		}
+
+		if evalStdout.String() != vmStdout.String() {
+			t.Errorf("%q: stdout differs. eval=%q, vm=%q", program, evalStdout.String(), vmStdout.String())
+		}
+
+		if evalStderr.String() != vmStderr.String() {
+			t.Errorf("%q: stderr differs. eval=%q, vm=%q", program, evalStderr.String(), vmStderr.String())
+		}
+	}
+}
@@ -0,0 +1,78 @@
+/*
+* File: object/builtins.go
+*
+* Description: Contains the builtin functions such as len and puts, listed in the order the compiler numbers them
+*
+ */
+
+package object
+
+import (
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+/*
+* Variable: Builtins
+*
+* Description: Every builtin function along with its name. The compiler refers to builtins by their index in this
+*              slice, so new builtins must be added at the end
+ */
+var Builtins = []struct {
+	Name    string
+	Builtin *Builtin
+}{
+	// len returns the number of characters in a string, elements in an array or pairs in a hash
+	{
+		"len",
+		&Builtin{Fn: func(out io.Writer, args ...Object) Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			switch arg := args[0].(type) {
+			case *String:
+				return &Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
+			case *Array:
+				return &Integer{Value: int64(len(arg.Elements))}
+			case *Hash:
+				return &Integer{Value: int64(len(arg.Pairs))}
+			default:
+				return newError("argument to `len` not supported, got %s", args[0].Type())
+			}
+		}},
+	},
+	// puts prints each of its arguments on its own line
+	{
+		"puts",
+		&Builtin{Fn: func(out io.Writer, args ...Object) Object {
+			for _, arg := range args {
+				fmt.Fprintln(out, arg.Inspect())
+			}
+
+			return NULL
+		}},
+	},
+}
+
+/*
+* Function: GetBuiltinByName
+*
+* Parameters: name string - The name of the builtin
+*
+* Returns: *Builtin - The builtin called name, nil if there is none
+ */
+func GetBuiltinByName(name string) *Builtin {
+	for _, def := range Builtins {
+		if def.Name == name {
+			return def.Builtin
+		}
+	}
+
+	return nil
+}
+
+func newError(format string, a ...interface{}) *Error {
+	return &Error{Message: fmt.Sprintf(format, a...)}
+}
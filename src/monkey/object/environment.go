@@ -0,0 +1,187 @@
+/*
+* File: object/environment.go
+*
+* Description: Contains the environment used to keep track of bindings while evaluating Monkey programs
+*
+ */
+
+package object
+
+import (
+	"io"
+	"os"
+	"sort"
+)
+
+/*
+* Struct: Environment
+*
+* Description: Maps names to values. Every function call gets its own environment that is enclosed by the
+*              environment the function was defined in
+ */
+type Environment struct {
+	store  map[string]Object
+	outer  *Environment // The enclosing environment, nil for the global environment
+	output io.Writer    // Where the program prints to, only set on the global environment
+	depth  int          // Number of function calls active when this environment was created, counting its own
+}
+
+/*
+* Function: NewEnvironment
+*
+* Parameters: none
+*
+* Returns: *Environment - Pointer to a new, empty environment
+*
+* Description: Creates a new top level environment
+ */
+func NewEnvironment() *Environment {
+	s := make(map[string]Object)
+	return &Environment{store: s, outer: nil}
+}
+
+/*
+* Function: NewEnclosedEnvironment
+*
+* Parameters: outer *Environment - The environment to enclose
+*
+* Returns: *Environment - Pointer to a new environment that falls back to outer for lookups
+*
+* Description: Creates a new environment used when calling a function
+ */
+func NewEnclosedEnvironment(outer *Environment) *Environment {
+	env := NewEnvironment()
+	env.outer = outer
+	return env
+}
+
+/*
+* Function: NewCallEnvironment
+*
+* Parameters: outer  *Environment - The environment the function being called was defined in
+*             caller *Environment - The environment the function is called from
+*
+* Returns: *Environment - Pointer to a new environment that falls back to outer for lookups
+*
+* Description: Creates the environment of a function call. Lookups go to outer like NewEnclosedEnvironment, while
+*              the call depth is counted from caller, so recursion that never ends can be stopped
+ */
+func NewCallEnvironment(outer, caller *Environment) *Environment {
+	env := NewEnclosedEnvironment(outer)
+	env.depth = caller.depth + 1
+	return env
+}
+
+// Depth returns the number of function calls that were active when e was created, 0 for the global environment
+func (e *Environment) Depth() int {
+	return e.depth
+}
+
+/*
+* Function: Environment.Get
+*
+* Parameters: name string - The name to look up
+*
+* Returns: Object - The value bound to name
+*          bool   - True if name was found in this environment or any enclosing environment
+*
+* Description: Looks up a name, walking outwards through the enclosing environments until it is found
+ */
+func (e *Environment) Get(name string) (Object, bool) {
+	obj, ok := e.store[name]
+	if !ok && e.outer != nil {
+		obj, ok = e.outer.Get(name)
+	}
+	return obj, ok
+}
+
+/*
+* Function: Environment.Set
+*
+* Parameters: name string - The name to bind
+*             val  Object - The value to bind to name
+*
+* Returns: Object - val
+*
+* Description: Binds name to val in this environment
+ */
+func (e *Environment) Set(name string, val Object) Object {
+	e.store[name] = val
+	return val
+}
+
+/*
+* Function: Environment.Owner
+*
+* Parameters: name string - The name to look up
+*
+* Returns: *Environment - The environment name is bound in, nil if it is not bound anywhere
+*
+* Description: Walks outwards through the enclosing environments like Get, but returns where name was found so the
+*              binding can be changed there
+ */
+func (e *Environment) Owner(name string) *Environment {
+	if _, ok := e.store[name]; ok {
+		return e
+	}
+
+	if e.outer != nil {
+		return e.outer.Owner(name)
+	}
+
+	return nil
+}
+
+/*
+* Function: Environment.SetOutput
+*
+* Parameters: w io.Writer - Where the program should print to
+*
+* Returns: none
+*
+* Description: Sets where builtin functions such as puts write to. Only the output of the global environment is
+*              used, environments of function calls share it
+ */
+func (e *Environment) SetOutput(w io.Writer) {
+	e.output = w
+}
+
+/*
+* Function: Environment.Output
+*
+* Parameters: none
+*
+* Returns: io.Writer - Where the program prints to, os.Stdout unless changed with SetOutput
+ */
+func (e *Environment) Output() io.Writer {
+	if e.outer != nil {
+		return e.outer.Output()
+	}
+
+	if e.output == nil {
+		return os.Stdout
+	}
+
+	return e.output
+}
+
+/*
+* Function: Environment.Names
+*
+* Parameters: none
+*
+* Returns: []string - The names bound in this environment, sorted
+*
+* Description: Lists the names bound directly in this environment. Names bound in enclosing environments are not
+*              included
+ */
+func (e *Environment) Names() []string {
+	names := make([]string, 0, len(e.store))
+	for name := range e.store {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
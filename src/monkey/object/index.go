@@ -0,0 +1,51 @@
+/*
+* File: object/index.go
+*
+* Description: Contains SetIndex, which stores a value in an array or a hash for assignments such as a[0] = 1
+*              and h["k"] += 1
+*
+ */
+
+package object
+
+import "fmt"
+
+/*
+* Function: SetIndex
+*
+* Parameters: container Object - The array or hash being assigned into
+*             index     Object - The index or key to assign to
+*             value     Object - The value to store
+*
+* Returns: error - Non nil if value can not be stored in container under index
+*
+* Description: Changes container in place, so every binding that refers to the same array or hash sees the new
+*              value. An array index has to refer to an element that already exists, arrays do not grow by
+*              assigning past their end. A hash gets a new pair when it does not have the key yet
+ */
+func SetIndex(container, index, value Object) error {
+	switch container := container.(type) {
+	case *Array:
+		integer, ok := index.(*Integer)
+		switch {
+		case ok && integer.Value >= 0 && integer.Value < int64(len(container.Elements)):
+			container.Elements[integer.Value] = value
+			return nil
+		case isInteger(index):
+			return fmt.Errorf("index out of range: %s", index.Inspect())
+		default:
+			return fmt.Errorf("unusable as array index: %s", index.Type())
+		}
+
+	case *Hash:
+		key, ok := index.(Hashable)
+		if !ok {
+			return fmt.Errorf("unusable as hash key: %s", index.Type())
+		}
+		container.Set(key, value)
+		return nil
+
+	default:
+		return fmt.Errorf("index assignment not supported: %s", container.Type())
+	}
+}
@@ -0,0 +1,200 @@
+/*
+* File: object/iter.go
+*
+* Description: Contains the range values made by .. and ..=, and the iterators a for-in loop uses to step through
+*              arrays, strings, hashes and ranges one element at a time
+*
+ */
+
+package object
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+/*
+* Struct: Range
+*
+* Implements: Object
+*
+* Description: The integers from Start up to End, produced by start..end or start..=end. Only the bounds are
+*              stored, the integers in between are made one at a time while the range is iterated over. A range
+*              whose end comes before its start is empty, it does not count down
+ */
+type Range struct {
+	Start     int64
+	End       int64
+	Inclusive bool // True if End itself is part of the range, as in start..=end
+}
+
+func (r *Range) Type() ObjectType { return RANGE_OBJ }
+func (r *Range) Inspect() string {
+	if r.Inclusive {
+		return fmt.Sprintf("%d..=%d", r.Start, r.End)
+	}
+	return fmt.Sprintf("%d..%d", r.Start, r.End)
+}
+
+/*
+* Function: NewRange
+*
+* Parameters: operator string - Either .. or ..=
+*             left     Object - The start of the range
+*             right    Object - The end of the range
+*
+* Returns: Object - The *Range from left to right
+*          error  - Non nil if either bound is not an integer that fits in 64 bits
+ */
+func NewRange(operator string, left, right Object) (Object, error) {
+	leftInt, leftIsInt := left.(*Integer)
+	rightInt, rightIsInt := right.(*Integer)
+
+	switch {
+	case leftIsInt && rightIsInt:
+		return &Range{Start: leftInt.Value, End: rightInt.Value, Inclusive: operator == "..="}, nil
+	case isInteger(left) && isInteger(right):
+		return nil, fmt.Errorf("range bound too large: %s %s %s", left.Inspect(), operator, right.Inspect())
+	case IsNumber(left) && IsNumber(right):
+		return nil, fmt.Errorf("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	case left.Type() != right.Type():
+		return nil, fmt.Errorf("type mismatch: %s %s %s", left.Type(), operator, right.Type())
+	default:
+		return nil, fmt.Errorf("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+/*
+* Struct: Iterator
+*
+* Implements: Object
+*
+* Description: Walks over the elements of an array, string, hash or range for a for loop. The virtual machine
+*              keeps it on the stack while the loop runs, Monkey programs never get hold of one
+ */
+type Iterator struct {
+	next  func() (key, value Object, ok bool)
+	keyed bool // True for hashes, where a loop with one variable gets the key instead of the value
+}
+
+func (it *Iterator) Type() ObjectType { return ITERATOR_OBJ }
+func (it *Iterator) Inspect() string  { return "iterator" }
+
+/*
+* Function: NewIterator
+*
+* Parameters: obj Object - The value to iterate over
+*
+* Returns: *Iterator - An iterator positioned before the first element of obj
+*          error     - Non nil if obj can not be iterated over
+*
+* Description: Arrays give their elements with their index, strings give every character as a string of its own
+*              with its index counted in characters, hashes give their pairs in the order the keys were added and
+*              ranges give their integers counting from 0
+ */
+func NewIterator(obj Object) (*Iterator, error) {
+	switch obj := obj.(type) {
+	case *Array:
+		elements := obj.Elements
+		i := 0
+
+		return &Iterator{next: func() (Object, Object, bool) {
+			if i >= len(elements) {
+				return nil, nil, false
+			}
+			i++
+			return &Integer{Value: int64(i - 1)}, elements[i-1], true
+		}}, nil
+
+	case *String:
+		value := obj.Value
+		offset, i := 0, 0
+
+		return &Iterator{next: func() (Object, Object, bool) {
+			if offset >= len(value) {
+				return nil, nil, false
+			}
+			_, size := utf8.DecodeRuneInString(value[offset:])
+			ch := &String{Value: value[offset : offset+size]}
+			offset += size
+			i++
+			return &Integer{Value: int64(i - 1)}, ch, true
+		}}, nil
+
+	case *Hash:
+		pairs := obj.Pairs
+		keys := obj.Keys
+		i := 0
+
+		return &Iterator{keyed: true, next: func() (Object, Object, bool) {
+			if i >= len(keys) {
+				return nil, nil, false
+			}
+			pair := pairs[keys[i]]
+			i++
+			return pair.Key, pair.Value, true
+		}}, nil
+
+	case *Range:
+		return rangeIterator(obj), nil
+
+	default:
+		return nil, fmt.Errorf("not iterable: %s", obj.Type())
+	}
+}
+
+// rangeIterator counts through r without ever going past its end, so a range ending at the largest integer stops
+func rangeIterator(r *Range) *Iterator {
+	last := r.End
+	if !r.Inclusive {
+		last--
+	}
+
+	done := r.Start > r.End || r.Start == r.End && !r.Inclusive
+	current := r.Start
+	var i int64
+
+	return &Iterator{next: func() (Object, Object, bool) {
+		if done {
+			return nil, nil, false
+		}
+
+		value := current
+		if current == last {
+			done = true
+		} else {
+			current++
+		}
+		i++
+
+		return &Integer{Value: i - 1}, &Integer{Value: value}, true
+	}}
+}
+
+/*
+* Function: Iterator.Next
+*
+* Parameters: count int - The number of variables the loop binds, 1 or 2
+*
+* Returns: first  Object - What the first variable is bound to
+*          second Object - What the second variable is bound to, nil when count is 1
+*          ok     bool   - False once every element has been visited
+*
+* Description: With two variables the loop gets the index or key and the value. With one it gets the value, except
+*              for a hash where it gets the key, so for (x in [7, 8]) binds 7 and 8 and for (k in h) binds the keys
+ */
+func (it *Iterator) Next(count int) (first, second Object, ok bool) {
+	key, value, ok := it.next()
+	if !ok {
+		return nil, nil, false
+	}
+
+	if count == 2 {
+		return key, value, true
+	}
+
+	if it.keyed {
+		return key, nil, true
+	}
+	return value, nil, true
+}
@@ -0,0 +1,357 @@
+/*
+* File: object/numeric.go
+*
+* Description: Contains the arithmetic, comparison and bitwise operators on numbers. Integers that overflow int64
+*              are promoted to big integers, and an operation mixing an integer with a float is done on floats
+*
+ */
+
+package object
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+)
+
+/*
+* Function: IsNumber
+*
+* Parameters: obj Object - The value to check
+*
+* Returns: bool - True if obj is an integer, of either size, or a float
+ */
+func IsNumber(obj Object) bool {
+	switch obj.(type) {
+	case *Integer, *BigInt, *Float:
+		return true
+	default:
+		return false
+	}
+}
+
+/*
+* Function: NumericOperation
+*
+* Parameters: operator string - The infix operator to apply
+*             left     Object - The left operand, must be a number
+*             right    Object - The right operand, must be a number
+*
+* Returns: Object - The result of the operation
+*          error  - Non nil if the operator does not apply to numbers or an integer is divided by zero
+*
+* Description: Two integers give an integer, which becomes a BigInt instead of wrapping around when it does not
+*              fit in 64 bits. As soon as one of the operands is a float, the other one is converted and the result
+*              is a float, following IEEE 754 so dividing a float by zero gives an infinity. Comparisons are always
+*              exact, integers are not converted to floats to compare them. Like / truncates towards zero, the
+*              result of % has the sign of the left operand, so -7 % 3 is -1. The bitwise operators and shifts only
+*              apply to integers, which behave as if they were written in two's complement with infinitely many
+*              sign bits, so >> keeps the sign and << never loses bits
+ */
+func NumericOperation(operator string, left, right Object) (Object, error) {
+	leftInt, leftIsInt := left.(*Integer)
+	rightInt, rightIsInt := right.(*Integer)
+
+	if leftIsInt && rightIsInt {
+		return integerOperation(operator, leftInt.Value, rightInt.Value)
+	}
+
+	if isInteger(left) && isInteger(right) {
+		return bigIntegerOperation(operator, toBig(left), toBig(right))
+	}
+
+	switch operator {
+	case "<", ">", "<=", ">=", "==", "!=":
+		return compareNumbers(operator, left, right), nil
+	}
+
+	leftVal, rightVal := toFloat(left), toFloat(right)
+
+	switch operator {
+	case "+":
+		return &Float{Value: leftVal + rightVal}, nil
+	case "-":
+		return &Float{Value: leftVal - rightVal}, nil
+	case "*":
+		return &Float{Value: leftVal * rightVal}, nil
+	case "/":
+		return &Float{Value: leftVal / rightVal}, nil
+	case "%":
+		return &Float{Value: math.Mod(leftVal, rightVal)}, nil
+	default:
+		return nil, fmt.Errorf("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+/*
+* Function: NegateNumber
+*
+* Parameters: obj Object - The value to negate
+*
+* Returns: Object - -obj
+*          error  - Non nil if obj is not a number
+*
+* Description: Applies the prefix - operator. The smallest Integer has no positive counterpart that fits in 64
+*              bits, so negating it gives a BigInt
+ */
+func NegateNumber(obj Object) (Object, error) {
+	switch obj := obj.(type) {
+	case *Integer:
+		if obj.Value == math.MinInt64 {
+			return NewInteger(new(big.Int).Neg(big.NewInt(obj.Value))), nil
+		}
+		return &Integer{Value: -obj.Value}, nil
+	case *BigInt:
+		return NewInteger(new(big.Int).Neg(obj.Value)), nil
+	case *Float:
+		return &Float{Value: -obj.Value}, nil
+	default:
+		return nil, fmt.Errorf("unknown operator: -%s", obj.Type())
+	}
+}
+
+/*
+* Function: BitwiseNot
+*
+* Parameters: obj Object - The value to complement
+*
+* Returns: Object - ~obj, which is -obj - 1
+*          error  - Non nil if obj is not an integer
+ */
+func BitwiseNot(obj Object) (Object, error) {
+	switch obj := obj.(type) {
+	case *Integer:
+		return &Integer{Value: ^obj.Value}, nil
+	case *BigInt:
+		return NewInteger(new(big.Int).Not(obj.Value)), nil
+	default:
+		return nil, fmt.Errorf("unknown operator: ~%s", obj.Type())
+	}
+}
+
+// Left shifts by more bits than this are refused, the result would take up an unreasonable amount of memory
+const maxShiftCount = 1 << 16
+
+// integerOperation does integer arithmetic in 64 bits, moving over to bigIntegerOperation when the result overflows
+func integerOperation(operator string, leftVal, rightVal int64) (Object, error) {
+	switch operator {
+	case "+":
+		result := leftVal + rightVal
+		if (leftVal >= 0) == (rightVal >= 0) && (result >= 0) != (leftVal >= 0) {
+			break
+		}
+		return &Integer{Value: result}, nil
+	case "-":
+		result := leftVal - rightVal
+		if (leftVal >= 0) != (rightVal >= 0) && (result >= 0) != (leftVal >= 0) {
+			break
+		}
+		return &Integer{Value: result}, nil
+	case "*":
+		result := leftVal * rightVal
+		if leftVal != 0 && (result/leftVal != rightVal || leftVal == -1 && rightVal == math.MinInt64) {
+			break
+		}
+		return &Integer{Value: result}, nil
+	case "/":
+		if rightVal == 0 {
+			return nil, fmt.Errorf("division by zero: %d / %d", leftVal, rightVal)
+		}
+		if leftVal == math.MinInt64 && rightVal == -1 {
+			break
+		}
+		return &Integer{Value: leftVal / rightVal}, nil
+	case "%":
+		if rightVal == 0 {
+			return nil, fmt.Errorf("division by zero: %d %% %d", leftVal, rightVal)
+		}
+		// Go defines MinInt64 % -1 as 0, so unlike / this can not overflow
+		return &Integer{Value: leftVal % rightVal}, nil
+	case "&":
+		return &Integer{Value: leftVal & rightVal}, nil
+	case "|":
+		return &Integer{Value: leftVal | rightVal}, nil
+	case "^":
+		return &Integer{Value: leftVal ^ rightVal}, nil
+	case "<<":
+		if rightVal < 0 {
+			return nil, fmt.Errorf("negative shift count: %d << %d", leftVal, rightVal)
+		}
+		if rightVal < 64 && (leftVal<<rightVal)>>rightVal == leftVal {
+			return &Integer{Value: leftVal << rightVal}, nil
+		}
+	case ">>":
+		if rightVal < 0 {
+			return nil, fmt.Errorf("negative shift count: %d >> %d", leftVal, rightVal)
+		}
+		return &Integer{Value: leftVal >> rightVal}, nil
+	case "<":
+		return NativeBoolToBooleanObject(leftVal < rightVal), nil
+	case ">":
+		return NativeBoolToBooleanObject(leftVal > rightVal), nil
+	case "<=":
+		return NativeBoolToBooleanObject(leftVal <= rightVal), nil
+	case ">=":
+		return NativeBoolToBooleanObject(leftVal >= rightVal), nil
+	case "==":
+		return NativeBoolToBooleanObject(leftVal == rightVal), nil
+	case "!=":
+		return NativeBoolToBooleanObject(leftVal != rightVal), nil
+	default:
+		return nil, fmt.Errorf("unknown operator: %s %s %s", INTEGER_OBJ, operator, INTEGER_OBJ)
+	}
+
+	// The result overflowed
+	return bigIntegerOperation(operator, big.NewInt(leftVal), big.NewInt(rightVal))
+}
+
+func bigIntegerOperation(operator string, leftVal, rightVal *big.Int) (Object, error) {
+	switch operator {
+	case "+":
+		return NewInteger(new(big.Int).Add(leftVal, rightVal)), nil
+	case "-":
+		return NewInteger(new(big.Int).Sub(leftVal, rightVal)), nil
+	case "*":
+		return NewInteger(new(big.Int).Mul(leftVal, rightVal)), nil
+	case "/":
+		if rightVal.Sign() == 0 {
+			return nil, fmt.Errorf("division by zero: %s / %s", leftVal, rightVal)
+		}
+		// Quo truncates towards zero like the / of Integers does, Div would round towards negative infinity
+		return NewInteger(new(big.Int).Quo(leftVal, rightVal)), nil
+	case "%":
+		if rightVal.Sign() == 0 {
+			return nil, fmt.Errorf("division by zero: %s %% %s", leftVal, rightVal)
+		}
+		// Rem goes with Quo, its result has the sign of leftVal
+		return NewInteger(new(big.Int).Rem(leftVal, rightVal)), nil
+	case "&":
+		return NewInteger(new(big.Int).And(leftVal, rightVal)), nil
+	case "|":
+		return NewInteger(new(big.Int).Or(leftVal, rightVal)), nil
+	case "^":
+		return NewInteger(new(big.Int).Xor(leftVal, rightVal)), nil
+	case "<<":
+		if rightVal.Sign() < 0 {
+			return nil, fmt.Errorf("negative shift count: %s << %s", leftVal, rightVal)
+		}
+		if rightVal.Cmp(big.NewInt(maxShiftCount)) > 0 {
+			return nil, fmt.Errorf("shift count too large: %s << %s", leftVal, rightVal)
+		}
+		return NewInteger(new(big.Int).Lsh(leftVal, uint(rightVal.Int64()))), nil
+	case ">>":
+		if rightVal.Sign() < 0 {
+			return nil, fmt.Errorf("negative shift count: %s >> %s", leftVal, rightVal)
+		}
+		// Shifting by more bits than leftVal has leaves only its sign, Rsh rounds towards negative infinity
+		if !rightVal.IsInt64() || rightVal.Int64() > int64(leftVal.BitLen()) {
+			if leftVal.Sign() < 0 {
+				return &Integer{Value: -1}, nil
+			}
+			return &Integer{Value: 0}, nil
+		}
+		return NewInteger(new(big.Int).Rsh(leftVal, uint(rightVal.Int64()))), nil
+	case "<":
+		return NativeBoolToBooleanObject(leftVal.Cmp(rightVal) < 0), nil
+	case ">":
+		return NativeBoolToBooleanObject(leftVal.Cmp(rightVal) > 0), nil
+	case "<=":
+		return NativeBoolToBooleanObject(leftVal.Cmp(rightVal) <= 0), nil
+	case ">=":
+		return NativeBoolToBooleanObject(leftVal.Cmp(rightVal) >= 0), nil
+	case "==":
+		return NativeBoolToBooleanObject(leftVal.Cmp(rightVal) == 0), nil
+	case "!=":
+		return NativeBoolToBooleanObject(leftVal.Cmp(rightVal) != 0), nil
+	default:
+		return nil, fmt.Errorf("unknown operator: %s %s %s", INTEGER_OBJ, operator, INTEGER_OBJ)
+	}
+}
+
+/*
+* Function: compareNumbers
+*
+* Parameters: operator string - One of <, >, <=, >=, == and !=
+*             left     Object - The left operand, at least one of the operands is a float
+*             right    Object - The right operand
+*
+* Returns: *Boolean - The result of the comparison
+*
+* Description: The operands are compared as big.Floats, which hold any integer or float exactly. NaN is not equal
+*              to anything, not even itself, and is neither smaller nor larger than anything
+ */
+func compareNumbers(operator string, left, right Object) *Boolean {
+	if isNaN(left) || isNaN(right) {
+		return NativeBoolToBooleanObject(operator == "!=")
+	}
+
+	cmp := toBigFloat(left).Cmp(toBigFloat(right))
+
+	switch operator {
+	case "<":
+		return NativeBoolToBooleanObject(cmp < 0)
+	case ">":
+		return NativeBoolToBooleanObject(cmp > 0)
+	case "<=":
+		return NativeBoolToBooleanObject(cmp <= 0)
+	case ">=":
+		return NativeBoolToBooleanObject(cmp >= 0)
+	case "==":
+		return NativeBoolToBooleanObject(cmp == 0)
+	default:
+		return NativeBoolToBooleanObject(cmp != 0)
+	}
+}
+
+func toBig(obj Object) *big.Int {
+	switch obj := obj.(type) {
+	case *Integer:
+		return big.NewInt(obj.Value)
+	case *BigInt:
+		return obj.Value
+	default:
+		return nil
+	}
+}
+
+// toBigFloat converts a number that is not NaN to a big.Float without rounding it
+func toBigFloat(obj Object) *big.Float {
+	switch obj := obj.(type) {
+	case *Integer:
+		return new(big.Float).SetInt64(obj.Value)
+	case *BigInt:
+		return new(big.Float).SetInt(obj.Value)
+	case *Float:
+		return new(big.Float).SetFloat64(obj.Value)
+	default:
+		return nil
+	}
+}
+
+func toFloat(obj Object) float64 {
+	switch obj := obj.(type) {
+	case *Integer:
+		return float64(obj.Value)
+	case *BigInt:
+		f, _ := new(big.Float).SetInt(obj.Value).Float64()
+		return f
+	case *Float:
+		return obj.Value
+	default:
+		return math.NaN()
+	}
+}
+
+func isInteger(obj Object) bool {
+	switch obj.(type) {
+	case *Integer, *BigInt:
+		return true
+	default:
+		return false
+	}
+}
+
+func isNaN(obj Object) bool {
+	f, ok := obj.(*Float)
+	return ok && math.IsNaN(f.Value)
+}
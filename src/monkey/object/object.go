@@ -0,0 +1,561 @@
+/*
+* File: object/object.go
+*
+* Description: Defines the object system used to represent values while evaluating Monkey programs
+*
+ */
+
+package object
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/vtallen/go-interpreter/ast"
+	"github.com/vtallen/go-interpreter/code"
+)
+
+type ObjectType string
+
+const (
+	INTEGER_OBJ      = "INTEGER"
+	FLOAT_OBJ        = "FLOAT"
+	STRING_OBJ       = "STRING"
+	BOOLEAN_OBJ      = "BOOLEAN"
+	NULL_OBJ         = "NULL"
+	RETURN_VALUE_OBJ = "RETURN_VALUE"
+	BREAK_OBJ        = "BREAK"
+	CONTINUE_OBJ     = "CONTINUE"
+	ERROR_OBJ        = "ERROR"
+	FUNCTION_OBJ     = "FUNCTION"
+	ARRAY_OBJ        = "ARRAY"
+	HASH_OBJ         = "HASH"
+	BUILTIN_OBJ      = "BUILTIN"
+	RANGE_OBJ        = "RANGE"
+	ITERATOR_OBJ     = "ITERATOR"
+	CELL_OBJ         = "CELL"
+
+	COMPILED_FUNCTION_OBJ = "COMPILED_FUNCTION"
+)
+
+/*
+* Interface: Object
+*
+* Description: Every value produced while evaluating a Monkey program implements this interface
+ */
+type Object interface {
+	Type() ObjectType // The type of the value, used by the evaluator to decide what to do with it
+	Inspect() string  // A human readable representation of the value
+}
+
+/*
+* Struct: Integer
+*
+* Implements: Object
+*
+* Description: Represents an integer value
+ */
+type Integer struct {
+	Value int64
+}
+
+func (i *Integer) Type() ObjectType { return INTEGER_OBJ }
+func (i *Integer) Inspect() string  { return fmt.Sprintf("%d", i.Value) }
+
+/*
+* Struct: BigInt
+*
+* Implements: Object
+*
+* Description: Represents an integer too large for an Integer. Arithmetic switches to a BigInt when a result does not
+*              fit in 64 bits and back to an Integer when it does, so a BigInt never holds a value an Integer could.
+*              It reports itself as an INTEGER, Monkey programs can not tell the two apart
+ */
+type BigInt struct {
+	Value *big.Int
+}
+
+func (b *BigInt) Type() ObjectType { return INTEGER_OBJ }
+func (b *BigInt) Inspect() string  { return b.Value.String() }
+
+/*
+* Function: NewInteger
+*
+* Parameters: value *big.Int - The value of the integer
+*
+* Returns: Object - An *Integer if value fits in 64 bits, a *BigInt otherwise
+ */
+func NewInteger(value *big.Int) Object {
+	if value.IsInt64() {
+		return &Integer{Value: value.Int64()}
+	}
+	return &BigInt{Value: value}
+}
+
+/*
+* Struct: Float
+*
+* Implements: Object
+*
+* Description: Represents a 64 bit floating point value
+ */
+type Float struct {
+	Value float64
+}
+
+func (f *Float) Type() ObjectType { return FLOAT_OBJ }
+
+// Inspect always includes a '.' or an exponent, so 2.0 does not look like the integer 2
+func (f *Float) Inspect() string {
+	s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eIN") {
+		s += ".0"
+	}
+	return s
+}
+
+/*
+* Struct: String
+*
+* Implements: Object
+*
+* Description: Represents a string value
+ */
+type String struct {
+	Value string
+}
+
+func (s *String) Type() ObjectType { return STRING_OBJ }
+func (s *String) Inspect() string  { return s.Value }
+
+/*
+* Struct: Boolean
+*
+* Implements: Object
+*
+* Description: Represents a boolean value
+ */
+type Boolean struct {
+	Value bool
+}
+
+func (b *Boolean) Type() ObjectType { return BOOLEAN_OBJ }
+func (b *Boolean) Inspect() string  { return fmt.Sprintf("%t", b.Value) }
+
+/*
+* Struct: Null
+*
+* Implements: Object
+*
+* Description: Represents the absence of a value
+ */
+type Null struct{}
+
+func (n *Null) Type() ObjectType { return NULL_OBJ }
+func (n *Null) Inspect() string  { return "null" }
+
+// There is only ever one true, false and null, so they are shared instead of allocating new objects every time.
+// The evaluator and the virtual machine both use these, so the two can be compared by pointer
+var (
+	NULL  = &Null{}
+	TRUE  = &Boolean{Value: true}
+	FALSE = &Boolean{Value: false}
+)
+
+func NativeBoolToBooleanObject(input bool) *Boolean {
+	if input {
+		return TRUE
+	}
+	return FALSE
+}
+
+/*
+* Struct: ReturnValue
+*
+* Implements: Object
+*
+* Description: Wraps the value of a return statement so the evaluator knows to stop evaluating the enclosing block
+ */
+type ReturnValue struct {
+	Value Object
+}
+
+func (rv *ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJ }
+func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
+
+/*
+* Struct: Break
+*
+* Implements: Object
+*
+* Description: Produced by a break statement so the evaluator stops evaluating the enclosing blocks up to the loop
+ */
+type Break struct{}
+
+func (b *Break) Type() ObjectType { return BREAK_OBJ }
+func (b *Break) Inspect() string  { return "break" }
+
+/*
+* Struct: Continue
+*
+* Implements: Object
+*
+* Description: Produced by a continue statement so the evaluator stops evaluating the enclosing blocks up to the
+*              loop, which then checks its condition again
+ */
+type Continue struct{}
+
+func (c *Continue) Type() ObjectType { return CONTINUE_OBJ }
+func (c *Continue) Inspect() string  { return "continue" }
+
+/*
+* Struct: Error
+*
+* Implements: Object
+*
+* Description: Represents a runtime error. Errors stop evaluation the same way a return value does
+ */
+type Error struct {
+	Message string
+}
+
+func (e *Error) Type() ObjectType { return ERROR_OBJ }
+func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
+
+/*
+* Struct: Function
+*
+* Implements: Object
+*
+* Description: Represents a function value. Env is the environment the function was defined in, which is what
+*              allows functions to act as closures
+ */
+type Function struct {
+	Parameters []*ast.Identifier
+	Body       *ast.BlockStatement
+	Env        *Environment
+}
+
+func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
+func (f *Function) Inspect() string  { return InspectFunction(f.Parameters, f.Body) }
+
+/*
+* Function: InspectFunction
+*
+* Parameters: parameters []*ast.Identifier  - The parameters of the function
+*             body       *ast.BlockStatement - The body of the function
+*
+* Returns: string - The function as Monkey prints it
+*
+* Description: Used for the functions of the evaluator and the compiled functions of the virtual machine alike, so
+*              a function prints the same whichever engine runs the program
+ */
+func InspectFunction(parameters []*ast.Identifier, body *ast.BlockStatement) string {
+	var out bytes.Buffer
+
+	params := []string{}
+	for _, p := range parameters {
+		params = append(params, p.String())
+	}
+
+	out.WriteString("fn(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") {\n")
+	out.WriteString(body.String())
+	out.WriteString("\n}")
+
+	return out.String()
+}
+
+/*
+* Type: BuiltinFunction
+*
+* Description: The signature of functions that are implemented in Go instead of Monkey. out is where the function
+*              writes anything it prints
+ */
+type BuiltinFunction func(out io.Writer, args ...Object) Object
+
+/*
+* Struct: Builtin
+*
+* Implements: Object
+*
+* Description: Represents a function that is built into the interpreter, such as len or puts
+ */
+type Builtin struct {
+	Fn BuiltinFunction
+}
+
+func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
+func (b *Builtin) Inspect() string  { return "builtin function" }
+
+/*
+* Struct: CompiledFunction
+*
+* Implements: Object
+*
+* Description: Represents a function compiled to bytecode. NumLocals is the number of local bindings, including the
+*              parameters, the virtual machine has to make room for on the stack when the function is called
+ */
+type CompiledFunction struct {
+	Instructions  code.Instructions
+	NumLocals     int
+	NumParameters int
+	LocalNames    []string // The names of the locals, indexed by slot, for error messages
+	FreeNames     []string // The names of the free variables, indexed like the Free of a closure
+	Literal       string   // The function literal as the evaluator prints it, empty for the main program
+}
+
+func (cf *CompiledFunction) Type() ObjectType { return COMPILED_FUNCTION_OBJ }
+func (cf *CompiledFunction) Inspect() string {
+	if cf.Literal != "" {
+		return cf.Literal
+	}
+	return fmt.Sprintf("CompiledFunction[%p]", cf)
+}
+
+/*
+* Struct: Closure
+*
+* Implements: Object
+*
+* Description: A compiled function along with the cells of the free variables it uses, captured when the function
+*              literal was evaluated. Every function is wrapped in a closure by the virtual machine, even ones
+*              without free variables. Its type is FUNCTION so errors name it the same way the evaluator does
+ */
+type Closure struct {
+	Fn   *CompiledFunction
+	Free []*Cell
+}
+
+func (c *Closure) Type() ObjectType { return FUNCTION_OBJ }
+func (c *Closure) Inspect() string {
+	if c.Fn.Literal != "" {
+		return c.Fn.Literal
+	}
+	return fmt.Sprintf("Closure[%p]", c)
+}
+
+/*
+* Struct: Cell
+*
+* Implements: Object
+*
+* Description: A variable captured by a closure. While the call that binds the variable is running the cell refers
+*              to the slot of the variable on the stack of the virtual machine, so the call and every closure
+*              sharing the cell see the same value, like the closures of the evaluator sharing an environment.
+*              Once the call returns the cell is closed and keeps the value itself
+ */
+type Cell struct {
+	ref   *Object // Where the value is, the stack slot while the cell is open and value once it is closed
+	value Object
+}
+
+// NewCell returns an open cell for the variable stored in slot
+func NewCell(slot *Object) *Cell {
+	return &Cell{ref: slot}
+}
+
+func (c *Cell) Type() ObjectType { return CELL_OBJ }
+func (c *Cell) Inspect() string  { return fmt.Sprintf("Cell[%p]", c) }
+
+// Get returns the value of the variable, nil if it has not been bound yet
+func (c *Cell) Get() Object { return *c.ref }
+
+// Set gives the variable a new value
+func (c *Cell) Set(val Object) { *c.ref = val }
+
+// Close moves the value out of the stack slot, which is about to be reused by another call
+func (c *Cell) Close() {
+	c.value = *c.ref
+	c.ref = &c.value
+}
+
+/*
+* Struct: Array
+*
+* Implements: Object
+*
+* Description: Represents an ordered list of values
+ */
+type Array struct {
+	Elements []Object
+}
+
+func (a *Array) Type() ObjectType { return ARRAY_OBJ }
+func (a *Array) Inspect() string  { return a.inspect(map[Object]bool{}) }
+
+// inspect prints the array, with printing holding the arrays and hashes it is nested in so that one containing
+// itself prints as [...] instead of never ending
+func (a *Array) inspect(printing map[Object]bool) string {
+	if printing[a] {
+		return "[...]"
+	}
+	printing[a] = true
+	defer delete(printing, a)
+
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, e := range a.Elements {
+		elements = append(elements, inspectElement(e, printing))
+	}
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+
+	return out.String()
+}
+
+// inspectElement prints a value stored in an array or hash, passing printing on to the arrays and hashes
+func inspectElement(obj Object, printing map[Object]bool) string {
+	switch obj := obj.(type) {
+	case *Array:
+		return obj.inspect(printing)
+	case *Hash:
+		return obj.inspect(printing)
+	default:
+		return obj.Inspect()
+	}
+}
+
+/*
+* Struct: HashKey
+*
+* Description: The value a hash uses to find a key. Two keys that are equal have the same HashKey, which is what
+*              lets two different *String objects with the same value find the same entry
+ */
+type HashKey struct {
+	Type  ObjectType
+	Value uint64
+}
+
+/*
+* Interface: Hashable
+*
+* Description: Implemented by the objects that can be used as the key of a hash
+ */
+type Hashable interface {
+	Object
+	HashKey() HashKey
+}
+
+func (i *Integer) HashKey() HashKey {
+	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
+}
+
+func (b *Boolean) HashKey() HashKey {
+	var value uint64
+
+	if b.Value {
+		value = 1
+	} else {
+		value = 0
+	}
+
+	return HashKey{Type: b.Type(), Value: value}
+}
+
+// A BigInt is never equal to an Integer, so hashing its bytes can not separate keys that should be the same
+func (b *BigInt) HashKey() HashKey {
+	h := fnv.New64a()
+	if b.Value.Sign() < 0 {
+		h.Write([]byte{'-'})
+	}
+	h.Write(b.Value.Bytes())
+
+	return HashKey{Type: b.Type(), Value: h.Sum64()}
+}
+
+func (s *String) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(s.Value))
+
+	return HashKey{Type: s.Type(), Value: h.Sum64()}
+}
+
+/*
+* Struct: HashPair
+*
+* Description: A key and the value stored under it. The original key is kept so it can be printed
+ */
+type HashPair struct {
+	Key   Object
+	Value Object
+}
+
+/*
+* Struct: Hash
+*
+* Implements: Object
+*
+* Description: Represents a map from hashable keys to values. Keys remembers the order the keys were first added in,
+*              so hashes print the same way every time
+ */
+type Hash struct {
+	Pairs map[HashKey]HashPair
+	Keys  []HashKey
+}
+
+/*
+* Function: NewHash
+*
+* Parameters: none
+*
+* Returns: *Hash - Pointer to a new, empty hash
+ */
+func NewHash() *Hash {
+	return &Hash{Pairs: make(map[HashKey]HashPair)}
+}
+
+/*
+* Function: Hash.Set
+*
+* Parameters: key   Hashable - The key to store value under
+*             value Object   - The value to store
+*
+* Returns: none
+*
+* Description: Stores value under key, replacing the value already stored under an equal key
+ */
+func (h *Hash) Set(key Hashable, value Object) {
+	hashKey := key.HashKey()
+
+	if _, ok := h.Pairs[hashKey]; !ok {
+		h.Keys = append(h.Keys, hashKey)
+	}
+
+	h.Pairs[hashKey] = HashPair{Key: key, Value: value}
+}
+
+func (h *Hash) Type() ObjectType { return HASH_OBJ }
+func (h *Hash) Inspect() string  { return h.inspect(map[Object]bool{}) }
+
+// inspect prints the hash, a hash that contains itself prints as {...} like an array does
+func (h *Hash) inspect(printing map[Object]bool) string {
+	if printing[h] {
+		return "{...}"
+	}
+	printing[h] = true
+	defer delete(printing, h)
+
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for _, key := range h.Keys {
+		pair := h.Pairs[key]
+		pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), inspectElement(pair.Value, printing)))
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
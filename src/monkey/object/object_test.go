@@ -0,0 +1,377 @@
+package object
+
+import (
+	"math"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestStringHashKey(t *testing.T) {
+	hello1 := &String{Value: "Hello World"}
+	hello2 := &String{Value: "Hello World"}
+	diff1 := &String{Value: "My name is johnny"}
+	diff2 := &String{Value: "My name is johnny"}
+
+	if hello1.HashKey() != hello2.HashKey() {
+		t.Errorf("strings with same content have different hash keys")
+	}
+
+	if diff1.HashKey() != diff2.HashKey() {
+		t.Errorf("strings with same content have different hash keys")
+	}
+
+	if hello1.HashKey() == diff1.HashKey() {
+		t.Errorf("strings with different content have same hash keys")
+	}
+}
+
+func TestHashKeysDoNotCollideAcrossTypes(t *testing.T) {
+	one := &Integer{Value: 1}
+	yes := &Boolean{Value: true}
+
+	if one.HashKey() == yes.HashKey() {
+		t.Errorf("1 and true have the same hash key")
+	}
+}
+
+func TestHashKeepsInsertionOrder(t *testing.T) {
+	h := NewHash()
+	h.Set(&String{Value: "b"}, &Integer{Value: 1})
+	h.Set(&String{Value: "a"}, &Integer{Value: 2})
+	h.Set(&String{Value: "b"}, &Integer{Value: 3})
+
+	if h.Inspect() != "{b: 3, a: 2}" {
+		t.Errorf("h.Inspect() wrong. got=%q", h.Inspect())
+	}
+}
+
+func TestInspectCycles(t *testing.T) {
+	a := &Array{Elements: []Object{&Integer{Value: 1}}}
+	a.Elements = append(a.Elements, a)
+
+	h := NewHash()
+	h.Set(&String{Value: "self"}, h)
+	h.Set(&String{Value: "a"}, a)
+
+	shared := &Array{}
+	twice := &Array{Elements: []Object{shared, shared}}
+
+	tests := []struct {
+		obj      Object
+		expected string
+	}{
+		{a, "[1, [...]]"},
+		{h, "{self: {...}, a: [1, [...]]}"},
+		{twice, "[[], []]"},
+	}
+
+	for _, tt := range tests {
+		if tt.obj.Inspect() != tt.expected {
+			t.Errorf("Inspect wrong. expected=%q, got=%q", tt.expected, tt.obj.Inspect())
+		}
+	}
+}
+
+func TestFloatInspect(t *testing.T) {
+	tests := []struct {
+		value    float64
+		expected string
+	}{
+		{3.14, "3.14"},
+		{2, "2.0"},
+		{-0.5, "-0.5"},
+		{1e21, "1e+21"},
+		{1e-9, "1e-09"},
+		{math.Inf(1), "+Inf"},
+		{math.NaN(), "NaN"},
+	}
+
+	for _, tt := range tests {
+		f := &Float{Value: tt.value}
+		if f.Inspect() != tt.expected {
+			t.Errorf("Inspect of %v wrong. expected=%q, got=%q", tt.value, tt.expected, f.Inspect())
+		}
+	}
+}
+
+func bigInt(s string) *BigInt {
+	value, _ := new(big.Int).SetString(s, 10)
+	return &BigInt{Value: value}
+}
+
+func TestNumericOperation(t *testing.T) {
+	tests := []struct {
+		left     Object
+		operator string
+		right    Object
+		expected string // The Inspect of the result, or the error message
+	}{
+		{&Integer{Value: 7}, "/", &Integer{Value: 2}, "3"},
+		{&Integer{Value: 7}, "/", &Float{Value: 2}, "3.5"},
+		{&Float{Value: 0.1}, "+", &Float{Value: 0.2}, "0.30000000000000004"},
+		{&Float{Value: 1.5}, "*", &Integer{Value: 2}, "3.0"},
+		{&Float{Value: 1}, "/", &Integer{Value: 0}, "+Inf"},
+		{&Integer{Value: 1}, "/", &Integer{Value: 0}, "division by zero: 1 / 0"},
+		{&Integer{Value: 1}, "==", &Float{Value: 1}, "true"},
+		{&Float{Value: 1.5}, ">", &Integer{Value: 1}, "true"},
+		{&Integer{Value: 2}, "<", &Float{Value: 1.5}, "false"},
+		// 2^53 + 1 can not be represented as a float, converting it would make the two equal
+		{&Integer{Value: 9007199254740993}, "==", &Float{Value: 9007199254740992}, "false"},
+		{&Integer{Value: 9007199254740993}, ">", &Float{Value: 9007199254740992}, "true"},
+		{&Integer{Value: math.MaxInt64}, "<", &Float{Value: math.MaxInt64}, "true"},
+		{&Float{Value: math.NaN()}, "==", &Float{Value: math.NaN()}, "false"},
+		{&Float{Value: math.NaN()}, "!=", &Integer{Value: 1}, "true"},
+		{&Integer{Value: 1}, "<", &Float{Value: math.NaN()}, "false"},
+		// Integers that overflow become BigInts
+		{&Integer{Value: math.MaxInt64}, "+", &Integer{Value: 1}, "9223372036854775808"},
+		{&Integer{Value: math.MinInt64}, "-", &Integer{Value: 1}, "-9223372036854775809"},
+		{&Integer{Value: math.MaxInt64}, "*", &Integer{Value: 2}, "18446744073709551614"},
+		{&Integer{Value: -1}, "*", &Integer{Value: math.MinInt64}, "9223372036854775808"},
+		{&Integer{Value: math.MinInt64}, "/", &Integer{Value: -1}, "9223372036854775808"},
+		{bigInt("18446744073709551616"), "/", &Integer{Value: -3}, "-6148914691236517205"},
+		{bigInt("-18446744073709551616"), "/", &Integer{Value: 0}, "division by zero: -18446744073709551616 / 0"},
+		{bigInt("18446744073709551616"), ">", &Integer{Value: math.MaxInt64}, "true"},
+		{bigInt("18446744073709551616"), "==", bigInt("18446744073709551616"), "true"},
+		{bigInt("18446744073709551616"), "==", &Float{Value: 18446744073709551616}, "true"},
+		{bigInt("18446744073709551617"), ">", &Float{Value: 18446744073709551616}, "true"},
+		{bigInt("18446744073709551616"), "*", &Float{Value: 0.5}, "9.223372036854776e+18"},
+		// % truncates like /, so the result has the sign of the left operand
+		{&Integer{Value: 7}, "%", &Integer{Value: 3}, "1"},
+		{&Integer{Value: -7}, "%", &Integer{Value: 3}, "-1"},
+		{&Integer{Value: 7}, "%", &Integer{Value: -3}, "1"},
+		{&Integer{Value: math.MinInt64}, "%", &Integer{Value: -1}, "0"},
+		{&Integer{Value: 1}, "%", &Integer{Value: 0}, "division by zero: 1 % 0"},
+		{bigInt("-18446744073709551617"), "%", &Integer{Value: 10}, "-7"},
+		{bigInt("18446744073709551616"), "%", &Integer{Value: 0}, "division by zero: 18446744073709551616 % 0"},
+		{&Float{Value: 7.5}, "%", &Integer{Value: 2}, "1.5"},
+		{&Float{Value: -7.5}, "%", &Float{Value: 2}, "-1.5"},
+		{&Integer{Value: 2}, "<=", &Integer{Value: 2}, "true"},
+		{&Integer{Value: 2}, ">=", &Integer{Value: 3}, "false"},
+		{bigInt("18446744073709551616"), ">=", bigInt("18446744073709551616"), "true"},
+		{&Float{Value: 1.5}, "<=", &Integer{Value: 1}, "false"},
+		{&Float{Value: math.NaN()}, "<=", &Float{Value: math.NaN()}, "false"},
+		{&Integer{Value: 1}, ">=", &Float{Value: math.NaN()}, "false"},
+		// Bitwise operators work on two's complement with as many sign bits as needed
+		{&Integer{Value: 12}, "&", &Integer{Value: 10}, "8"},
+		{&Integer{Value: 12}, "|", &Integer{Value: 10}, "14"},
+		{&Integer{Value: 12}, "^", &Integer{Value: 10}, "6"},
+		{&Integer{Value: -1}, "&", &Integer{Value: 255}, "255"},
+		{&Integer{Value: 1}, "<<", &Integer{Value: 62}, "4611686018427387904"},
+		{&Integer{Value: 1}, "<<", &Integer{Value: 63}, "9223372036854775808"},
+		{&Integer{Value: -1}, "<<", &Integer{Value: 63}, "-9223372036854775808"},
+		{&Integer{Value: 3}, "<<", &Integer{Value: 100}, "3802951800684688204490109616128"},
+		{&Integer{Value: -8}, ">>", &Integer{Value: 1}, "-4"},
+		{&Integer{Value: -8}, ">>", &Integer{Value: 100}, "-1"},
+		{&Integer{Value: 8}, ">>", &Integer{Value: 100}, "0"},
+		{&Integer{Value: 1}, "<<", &Integer{Value: -1}, "negative shift count: 1 << -1"},
+		{&Integer{Value: 1}, ">>", &Integer{Value: -1}, "negative shift count: 1 >> -1"},
+		{bigInt("18446744073709551616"), ">>", &Integer{Value: 64}, "1"},
+		{bigInt("-18446744073709551616"), ">>", bigInt("18446744073709551616"), "-1"},
+		{bigInt("18446744073709551617"), "&", &Integer{Value: 0xff}, "1"},
+		{bigInt("18446744073709551616"), "|", &Integer{Value: 1}, "18446744073709551617"},
+		{bigInt("18446744073709551616"), "^", bigInt("18446744073709551616"), "0"},
+		{&Integer{Value: 1}, "<<", &Integer{Value: 1 << 20}, "shift count too large: 1 << 1048576"},
+		{&Float{Value: 1}, "&", &Integer{Value: 1}, "unknown operator: FLOAT & INTEGER"},
+		{&Integer{Value: 1}, "<<", &Float{Value: 1}, "unknown operator: INTEGER << FLOAT"},
+	}
+
+	for _, tt := range tests {
+		result, err := NumericOperation(tt.operator, tt.left, tt.right)
+
+		var got string
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = result.Inspect()
+		}
+
+		if got != tt.expected {
+			t.Errorf("%s %s %s wrong. expected=%q, got=%q", tt.left.Inspect(), tt.operator, tt.right.Inspect(), tt.expected, got)
+		}
+	}
+}
+
+func TestBitwiseNot(t *testing.T) {
+	tests := []struct {
+		operand  Object
+		expected string // The Inspect of the result, or the error message
+	}{
+		{&Integer{Value: 0}, "-1"},
+		{&Integer{Value: 5}, "-6"},
+		{&Integer{Value: math.MinInt64}, "9223372036854775807"},
+		{bigInt("18446744073709551616"), "-18446744073709551617"},
+		{bigInt("-9223372036854775809"), "9223372036854775808"},
+		{&Float{Value: 1}, "unknown operator: ~FLOAT"},
+	}
+
+	for _, tt := range tests {
+		result, err := BitwiseNot(tt.operand)
+
+		var got string
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = result.Inspect()
+		}
+
+		if got != tt.expected {
+			t.Errorf("~%s wrong. expected=%q, got=%q", tt.operand.Inspect(), tt.expected, got)
+		}
+	}
+}
+
+func TestBigIntsShrinkBackToIntegers(t *testing.T) {
+	result, err := NumericOperation("-", bigInt("9223372036854775808"), &Integer{Value: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if integer, ok := result.(*Integer); !ok || integer.Value != math.MaxInt64 {
+		t.Errorf("expected Integer %d, got=%T (%+v)", int64(math.MaxInt64), result, result)
+	}
+
+	negated, err := NegateNumber(&Integer{Value: math.MinInt64})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := negated.(*BigInt); !ok || negated.Inspect() != "9223372036854775808" {
+		t.Errorf("expected BigInt 9223372036854775808, got=%T (%+v)", negated, negated)
+	}
+
+	if negated.Type() != INTEGER_OBJ {
+		t.Errorf("BigInt should look like an integer, got type %s", negated.Type())
+	}
+}
+
+func TestBigIntHashKey(t *testing.T) {
+	a := bigInt("18446744073709551616")
+	b := bigInt("18446744073709551616")
+	negative := bigInt("-18446744073709551616")
+
+	if a.HashKey() != b.HashKey() {
+		t.Errorf("big integers with the same value have different hash keys")
+	}
+
+	if a.HashKey() == negative.HashKey() {
+		t.Errorf("a big integer and its negation have the same hash key")
+	}
+}
+
+func TestNewRange(t *testing.T) {
+	tests := []struct {
+		operator string
+		left     Object
+		right    Object
+		expected string // The Inspect of the result, or the error message
+	}{
+		{"..", &Integer{Value: 0}, &Integer{Value: 5}, "0..5"},
+		{"..=", &Integer{Value: -2}, &Integer{Value: 2}, "-2..=2"},
+		{"..", &Integer{Value: 0}, bigInt("18446744073709551616"), "range bound too large: 0 .. 18446744073709551616"},
+		{"..", &Float{Value: 0.5}, &Integer{Value: 2}, "unknown operator: FLOAT .. INTEGER"},
+		{"..=", &String{Value: "a"}, &Integer{Value: 2}, "type mismatch: STRING ..= INTEGER"},
+	}
+
+	for _, tt := range tests {
+		result, err := NewRange(tt.operator, tt.left, tt.right)
+
+		var got string
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = result.Inspect()
+		}
+
+		if got != tt.expected {
+			t.Errorf("%s %s %s wrong. expected=%q, got=%q", tt.left.Inspect(), tt.operator, tt.right.Inspect(), tt.expected, got)
+		}
+	}
+}
+
+func TestSetIndex(t *testing.T) {
+	tests := []struct {
+		container Object
+		index     Object
+		expected  string // The Inspect of the container afterwards, or the error message
+	}{
+		{&Array{Elements: []Object{&Integer{Value: 1}, &Integer{Value: 2}}}, &Integer{Value: 1}, "[1, 9]"},
+		{&Array{Elements: []Object{&Integer{Value: 1}}}, &Integer{Value: 1}, "index out of range: 1"},
+		{&Array{Elements: []Object{&Integer{Value: 1}}}, &Integer{Value: -1}, "index out of range: -1"},
+		{&Array{}, bigInt("18446744073709551616"), "index out of range: 18446744073709551616"},
+		{&Array{}, &String{Value: "a"}, "unusable as array index: STRING"},
+		{NewHash(), &String{Value: "a"}, "{a: 9}"},
+		{NewHash(), &Array{}, "unusable as hash key: ARRAY"},
+		{&String{Value: "a"}, &Integer{Value: 0}, "index assignment not supported: STRING"},
+	}
+
+	for _, tt := range tests {
+		err := SetIndex(tt.container, tt.index, &Integer{Value: 9})
+
+		var got string
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = tt.container.Inspect()
+		}
+
+		if got != tt.expected {
+			t.Errorf("%s[%s] = 9 wrong. expected=%q, got=%q", tt.container.Inspect(), tt.index.Inspect(), tt.expected, got)
+		}
+	}
+}
+
+func TestIterator(t *testing.T) {
+	h := NewHash()
+	h.Set(&String{Value: "b"}, &Integer{Value: 1})
+	h.Set(&String{Value: "a"}, &Integer{Value: 2})
+
+	tests := []struct {
+		iterable Object
+		count    int
+		expected string // What each step binds, separated by spaces
+	}{
+		{&Array{Elements: []Object{&Integer{Value: 7}, &Integer{Value: 8}}}, 1, "7 8"},
+		{&Array{Elements: []Object{&Integer{Value: 7}, &Integer{Value: 8}}}, 2, "0:7 1:8"},
+		{&Array{}, 1, ""},
+		{&String{Value: "héj"}, 1, "h é j"},
+		{&String{Value: "héj"}, 2, "0:h 1:é 2:j"},
+		{h, 1, "b a"},
+		{h, 2, "b:1 a:2"},
+		{&Range{Start: 1, End: 4}, 1, "1 2 3"},
+		{&Range{Start: 1, End: 4, Inclusive: true}, 2, "0:1 1:2 2:3 3:4"},
+		{&Range{Start: 3, End: 3}, 1, ""},
+		{&Range{Start: 3, End: 3, Inclusive: true}, 1, "3"},
+		{&Range{Start: 5, End: 1}, 1, ""},
+		{&Range{Start: math.MaxInt64 - 1, End: math.MaxInt64, Inclusive: true}, 1, "9223372036854775806 9223372036854775807"},
+	}
+
+	for _, tt := range tests {
+		it, err := NewIterator(tt.iterable)
+		if err != nil {
+			t.Fatalf("NewIterator(%s) failed: %s", tt.iterable.Inspect(), err)
+		}
+
+		steps := []string{}
+		for {
+			first, second, ok := it.Next(tt.count)
+			if !ok {
+				break
+			}
+
+			if tt.count == 2 {
+				steps = append(steps, first.Inspect()+":"+second.Inspect())
+			} else {
+				steps = append(steps, first.Inspect())
+			}
+		}
+
+		if got := strings.Join(steps, " "); got != tt.expected {
+			t.Errorf("iterating over %s with %d variables wrong. expected=%q, got=%q", tt.iterable.Inspect(), tt.count, tt.expected, got)
+		}
+	}
+
+	if _, err := NewIterator(&Integer{Value: 1}); err == nil || err.Error() != "not iterable: INTEGER" {
+		t.Errorf("iterating over an integer gave the wrong error. got=%v", err)
+	}
+}
@@ -8,10 +8,13 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
+	"math/big"
 	"strconv"
 
 	"github.com/vtallen/go-interpreter/ast"
+	"github.com/vtallen/go-interpreter/diagnostic"
 	"github.com/vtallen/go-interpreter/lexer"
 	"github.com/vtallen/go-interpreter/token"
 )
@@ -24,23 +27,52 @@ type (
 const (
 	_ int = iota
 	LOWEST
+	ASSIGN      // = or a compound form such as +=
+	LOGICAL_OR  // ||
+	LOGICAL_AND // &&
+	BIT_OR      // |
+	BIT_XOR     // ^
+	BIT_AND     // &
 	EQUALS      // ==
 	LESSGREATER // > or <
+	RANGE       // .. or ..=
+	SHIFT       // << or >>
 	SUM         // +
 	PRODUCT     // *
-	PREFIX      // -X or !X
+	PREFIX      // -X, !X or ~X
 	CALL        // myFunction(x)
+	INDEX       // array[index]
 )
 
 var precedences = map[token.TokenType]int{
-	token.EQ:       EQUALS,
-	token.NOT_EQ:   EQUALS,
-	token.LT:       LESSGREATER,
-	token.GT:       LESSGREATER,
-	token.PLUS:     SUM,
-	token.MINUS:    SUM,
-	token.SLASH:    PRODUCT,
-	token.ASTERISK: PRODUCT,
+	token.ASSIGN:          ASSIGN,
+	token.PLUS_ASSIGN:     ASSIGN,
+	token.MINUS_ASSIGN:    ASSIGN,
+	token.ASTERISK_ASSIGN: ASSIGN,
+	token.SLASH_ASSIGN:    ASSIGN,
+	token.PERCENT_ASSIGN:  ASSIGN,
+	token.OR:              LOGICAL_OR,
+	token.AND:             LOGICAL_AND,
+	token.BIT_OR:          BIT_OR,
+	token.BIT_XOR:         BIT_XOR,
+	token.BIT_AND:         BIT_AND,
+	token.EQ:              EQUALS,
+	token.NOT_EQ:          EQUALS,
+	token.LT:              LESSGREATER,
+	token.GT:              LESSGREATER,
+	token.LT_EQ:           LESSGREATER,
+	token.GT_EQ:           LESSGREATER,
+	token.DOTDOT:          RANGE,
+	token.DOTDOT_EQ:       RANGE,
+	token.SHIFT_LEFT:      SHIFT,
+	token.SHIFT_RIGHT:     SHIFT,
+	token.PLUS:            SUM,
+	token.MINUS:           SUM,
+	token.SLASH:           PRODUCT,
+	token.ASTERISK:        PRODUCT,
+	token.PERCENT:         PRODUCT,
+	token.LPAREN:          CALL,
+	token.LBRACKET:        INDEX,
 }
 
 /*
@@ -53,8 +85,19 @@ type Parser struct {
 
 	curToken  token.Token // Current token under consideration
 	peekToken token.Token // Next token in the program, used to figure out what to do
+	prevToken token.Token // Token before curToken, kept so the parser can back up by one token
 
-	errors         []string // Any arrors that occur during parsing
+	pending    []token.Token // Tokens pushed back by backup, read again before asking the lexer for more
+	braceDepth int           // Number of braces that are open around the current token
+	loopDepth  int           // Number of loops around the current token, reset inside function literals
+	lexErrors  int           // Number of the lexer's errors that have been copied into errors
+
+	comments      []*ast.CommentGroup // Comment groups read so far, only filled when the lexer keeps comments
+	commentList   []*ast.Comment      // Comments of the group being built, not yet in comments
+	trailingGroup bool                // True if the group being built started on the same line as a token
+	lastTokenEnd  token.Position      // End of the last token read from the lexer that was not a comment
+
+	errors         []diagnostic.Diagnostic // Any errors that occur during parsing
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
 }
@@ -71,30 +114,54 @@ type Parser struct {
 func New(l *lexer.Lexer) *Parser {
 	p := &Parser{
 		l:      l,
-		errors: []string{},
+		errors: []diagnostic.Diagnostic{},
 	}
 
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
+	p.registerPrefix(token.STRING, p.parseStringLiteral)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
+	p.registerPrefix(token.BIT_NOT, p.parsePrefixExpression)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(token.IF, p.parseIfExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
+	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
+	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
 
 	p.infixParseFns = make(map[token.TokenType]infixParseFn)
 	p.registerInfix(token.PLUS, p.parseInfixExpression)
 	p.registerInfix(token.MINUS, p.parseInfixExpression)
 	p.registerInfix(token.SLASH, p.parseInfixExpression)
 	p.registerInfix(token.ASTERISK, p.parseInfixExpression)
+	p.registerInfix(token.PERCENT, p.parseInfixExpression)
 	p.registerInfix(token.EQ, p.parseInfixExpression)
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
+	p.registerInfix(token.LT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.GT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.BIT_AND, p.parseInfixExpression)
+	p.registerInfix(token.BIT_OR, p.parseInfixExpression)
+	p.registerInfix(token.BIT_XOR, p.parseInfixExpression)
+	p.registerInfix(token.SHIFT_LEFT, p.parseInfixExpression)
+	p.registerInfix(token.SHIFT_RIGHT, p.parseInfixExpression)
+	p.registerInfix(token.DOTDOT, p.parseInfixExpression)
+	p.registerInfix(token.DOTDOT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.AND, p.parseLogicalExpression)
+	p.registerInfix(token.OR, p.parseLogicalExpression)
+	p.registerInfix(token.ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.PLUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.MINUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.ASTERISK_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.SLASH_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.PERCENT_ASSIGN, p.parseAssignExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
+	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
 	// Read to tokens, so curToken and peekToken are both set
 	p.nextToken()
 	p.nextToken()
@@ -107,15 +174,42 @@ func New(l *lexer.Lexer) *Parser {
 *
 * Parameters: none
 *
-* Returns: []string - Array of strings containing the errors that occured during parsing
+* Returns: []diagnostic.Diagnostic - The errors that occured during parsing, in the order they were found
 *
 * Description: Returns the errors that occured during parsing
 *
  */
-func (p *Parser) Errors() []string {
+func (p *Parser) Errors() []diagnostic.Diagnostic {
 	return p.errors
 }
 
+/*
+* Function: Parser.Incomplete
+*
+* Parameters: none
+*
+* Returns: bool - True if every error was caused by the input ending too early
+*
+* Description: Reports whether the input parsed so far is the start of a valid program that has not been finished
+*              yet, such as a block whose } has not been typed, a comment that has not been closed or an expression
+*              ending in an infix operator. The REPL uses this to decide whether to wait for more lines before
+*              running the input
+*
+ */
+func (p *Parser) Incomplete() bool {
+	if len(p.errors) == 0 {
+		return false
+	}
+
+	for _, d := range p.errors {
+		if d.Actual != token.EOF && d.Code != diagnostic.UnterminatedString && d.Code != diagnostic.UnterminatedComment {
+			return false
+		}
+	}
+
+	return true
+}
+
 func (p *Parser) peekPrecidence() int {
 	if p, ok := precedences[p.peekToken.Type]; ok {
 		return p
@@ -141,8 +235,77 @@ func (p *Parser) curPrecedence() int {
 * Description: Advances the current token and peek token by one token
  */
 func (p *Parser) nextToken() {
+	p.prevToken = p.curToken
 	p.curToken = p.peekToken
-	p.peekToken = p.l.NextToken()
+
+	if n := len(p.pending); n > 0 {
+		p.peekToken = p.pending[n-1]
+		p.pending = p.pending[:n-1]
+	} else {
+		p.peekToken = p.l.NextToken()
+		for p.peekToken.Type == token.COMMENT {
+			p.addComment(p.peekToken)
+			p.peekToken = p.l.NextToken()
+		}
+		p.endCommentGroup()
+		p.lastTokenEnd = p.peekToken.End
+
+		// Errors found by the lexer are reported along with the parser's own errors
+		if errs := p.l.Errors(); len(errs) > p.lexErrors {
+			p.errors = append(p.errors, errs[p.lexErrors:]...)
+			p.lexErrors = len(errs)
+		}
+	}
+}
+
+/*
+* Function: Parser.addComment
+*
+* Parameters: tok token.Token - A COMMENT token read from the lexer
+*
+* Returns: none
+*
+* Description: Adds a comment to the group being built, starting a new group first if the comment is separated from
+*              the group by a blank line. A group that started after code on the same line only holds the comments
+*              on that line, so a trailing comment does not swallow the comment above the next statement
+ */
+func (p *Parser) addComment(tok token.Token) {
+	if n := len(p.commentList); n > 0 {
+		last := p.commentList[n-1]
+		if tok.Pos.Line > last.End().Line+1 || p.trailingGroup && tok.Pos.Line != last.Pos().Line {
+			p.endCommentGroup()
+		}
+	}
+
+	if len(p.commentList) == 0 {
+		p.trailingGroup = p.lastTokenEnd.IsValid() && p.lastTokenEnd.Line == tok.Pos.Line
+	}
+
+	p.commentList = append(p.commentList, &ast.Comment{Token: tok})
+}
+
+// endCommentGroup moves the group being built, if there is one, into comments
+func (p *Parser) endCommentGroup() {
+	if len(p.commentList) > 0 {
+		p.comments = append(p.comments, &ast.CommentGroup{List: p.commentList})
+		p.commentList = nil
+	}
+}
+
+/*
+* Function: Parser.backup
+*
+* Parameters: none
+*
+* Returns: none
+*
+* Description: Undoes the last call to nextToken. Only the previous token is remembered, so the parser can not back
+*              up twice in a row
+ */
+func (p *Parser) backup() {
+	p.pending = append(p.pending, p.peekToken)
+	p.peekToken = p.curToken
+	p.curToken = p.prevToken
 }
 
 // TODO: Docs
@@ -161,7 +324,8 @@ func (p *Parser) registerInfix(tokenType token.TokenType, fn infixParseFn) {
 *
 * Returns: *ast.Program - Pointer to the AST of the program
 *
-* Description: Parses the program and returns the AST
+* Description: Parses the program and returns the AST. When the lexer was told to keep comments with
+*              lexer.ScanComments, the comments are stored in the program and attached to its statements
 *
  */
 func (p *Parser) ParseProgram() *ast.Program {
@@ -176,30 +340,120 @@ func (p *Parser) ParseProgram() *ast.Program {
 		p.nextToken()
 	}
 
+	if len(p.comments) > 0 {
+		program.Comments = p.comments
+		program.CommentMap = ast.NewCommentMap(program, p.comments)
+	}
+
 	return program
 }
 
+/*
+* Function: Parser.parseStatement
+*
+* Parameters: none
+*
+* Returns: ast.Statement - The statement starting at the current token
+*
+* Description: Parses a single statement, leaving curToken on its last token. When the statement contains a syntax
+*              error the parser skips ahead to the end of the statement, so one mistake does not cause a cascade of
+*              errors in the tokens that follow it
+ */
 func (p *Parser) parseStatement() ast.Statement {
+	errCount := len(p.errors)
+
+	var stmt ast.Statement
 	switch p.curToken.Type {
 	case token.LET:
-		return p.parseLetStatement()
+		stmt = p.parseLetStatement()
 	case token.RETURN:
-		return p.parseReturnStatement()
+		stmt = p.parseReturnStatement()
+	case token.WHILE:
+		stmt = p.parseWhileStatement()
+	case token.FOR:
+		stmt = p.parseForInStatement()
+	case token.BREAK, token.CONTINUE:
+		stmt = p.parseLoopControlStatement()
 	default:
-		return p.parseExpressionStatement()
+		stmt = p.parseExpressionStatement()
+	}
+
+	if len(p.errors) > errCount {
+		p.synchronize()
+
+		if bad, ok := stmt.(*ast.BadStatement); ok {
+			bad.To = p.curToken
+		}
+	}
+
+	return stmt
+}
+
+/*
+* Function: Parser.synchronize
+*
+* Parameters: none
+*
+* Returns: none
+*
+* Description: Skips tokens after a syntax error until the end of the current statement. It stops on a ; or right
+*              before a token that starts a new statement (let, return, fn, while, for), a } closing an enclosing
+*              block, or the end of the input. Braces opened while skipping are skipped along with their closing brace
+ */
+func (p *Parser) synchronize() {
+	depth := 0
+
+	for !p.curTokenIs(token.EOF) {
+		switch p.curToken.Type {
+		case token.LBRACE:
+			depth++
+		case token.RBRACE:
+			if depth > 0 {
+				depth--
+			}
+		case token.SEMICOLON:
+			if depth == 0 {
+				return
+			}
+		}
+
+		if depth == 0 {
+			switch p.peekToken.Type {
+			case token.RBRACE, token.LET, token.RETURN, token.FUNCTION, token.WHILE, token.FOR, token.EOF:
+				return
+			}
+		}
+
+		p.nextToken()
 	}
 }
 
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
-	msg := fmt.Sprintf("no prefix parse functions for %s found", t)
-	p.errors = append(p.errors, msg)
+	p.errors = append(p.errors, diagnostic.Diagnostic{
+		Severity: diagnostic.Error,
+		Code:     diagnostic.NoPrefixParseFn,
+		Message:  fmt.Sprintf("no prefix parse functions for %s found", t),
+		Span:     diagnostic.SpanOf(p.curToken),
+		Actual:   t,
+	})
 }
 
 func (p *Parser) parseExpression(precedence int) ast.Expression {
 	prefix := p.prefixParseFns[p.curToken.Type]
 	if prefix == nil {
-		p.noPrefixParseFnError(p.curToken.Type)
-		return nil
+		// An ILLEGAL token made of bytes that are not valid UTF-8 has already been reported by the lexer
+		if !p.reported(p.curToken) {
+			p.noPrefixParseFnError(p.curToken.Type)
+		}
+
+		bad := &ast.BadExpression{From: p.curToken, To: p.curToken}
+
+		// A } here closes an enclosing block, so give it back instead of swallowing it as part of the expression
+		if p.curTokenIs(token.RBRACE) && p.braceDepth > 0 {
+			p.backup()
+		}
+
+		return bad
 	}
 	leftExp := prefix()
 
@@ -218,12 +472,14 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 }
 
 func (p *Parser) parseGroupedExpression() ast.Expression {
+	lparen := p.curToken
+
 	p.nextToken()
 
 	exp := p.parseExpression(LOWEST)
 
 	if !p.expectPeek(token.RPAREN) {
-		return nil
+		return &ast.BadExpression{From: lparen, To: p.curToken}
 	}
 
 	return exp
@@ -245,10 +501,53 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	lit := &ast.IntegerLiteral{Token: p.curToken}
 
 	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		// Too large for an int64, the literal becomes a BigInt
+		if bigValue, ok := new(big.Int).SetString(p.curToken.Literal, 0); ok {
+			lit.Big = bigValue
+			return lit
+		}
+	}
+
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
-		return nil
+		if p.reported(p.curToken) {
+			// The lexer already explained what is wrong with the literal
+			return &ast.BadExpression{From: p.curToken, To: p.curToken}
+		}
+
+		p.errors = append(p.errors, diagnostic.Diagnostic{
+			Severity: diagnostic.Error,
+			Code:     diagnostic.InvalidInteger,
+			Message:  fmt.Sprintf("could not parse %q as integer", p.curToken.Literal),
+			Span:     diagnostic.SpanOf(p.curToken),
+			Actual:   p.curToken.Type,
+		})
+		return &ast.BadExpression{From: p.curToken, To: p.curToken}
+	}
+
+	lit.Value = value
+
+	return lit
+}
+
+// Floats too large to be represented are an error rather than silently becoming infinity
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	lit := &ast.FloatLiteral{Token: p.curToken}
+
+	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
+	if err != nil {
+		if p.reported(p.curToken) {
+			return &ast.BadExpression{From: p.curToken, To: p.curToken}
+		}
+
+		p.errors = append(p.errors, diagnostic.Diagnostic{
+			Severity: diagnostic.Error,
+			Code:     diagnostic.InvalidFloat,
+			Message:  fmt.Sprintf("could not parse %q as float", p.curToken.Literal),
+			Span:     diagnostic.SpanOf(p.curToken),
+			Actual:   p.curToken.Type,
+		})
+		return &ast.BadExpression{From: p.curToken, To: p.curToken}
 	}
 
 	lit.Value = value
@@ -256,6 +555,10 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	return lit
 }
 
+func (p *Parser) parseStringLiteral() ast.Expression {
+	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+}
+
 func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.Boolean{Token: p.curToken, Value: p.curTokenIs(token.TRUE)}
 }
@@ -264,34 +567,196 @@ func (p *Parser) parseIdentifier() ast.Expression {
 	return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 }
 
-func (p *Parser) parseLetStatement() *ast.LetStatement {
+/*
+* Function: Parser.parseLetStatement
+*
+* Parameters: none
+*
+* Returns: ast.Statement - The let statement starting at the current token, a BadStatement if it is malformed
+*
+* Description: Parses let <name> = <expression>. Like an expression statement, the ; at the end is optional
+ */
+func (p *Parser) parseLetStatement() ast.Statement {
 	stmt := &ast.LetStatement{Token: p.curToken}
 
 	if !p.expectPeek(token.IDENT) {
-		return nil
+		return &ast.BadStatement{From: stmt.Token, To: p.curToken}
 	}
 
 	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 
 	if !p.expectPeek(token.ASSIGN) {
-		return nil
+		return &ast.BadStatement{From: stmt.Token, To: p.curToken}
 	}
 
-	// TODO: Skipping expressions for now
-	for !p.curTokenIs(token.SEMICOLON) {
+	p.nextToken()
+
+	stmt.Value = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
 
 	return stmt
 }
 
-func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
+/*
+* Function: Parser.parseReturnStatement
+*
+* Parameters: none
+*
+* Returns: ast.Statement - The return statement starting at the current token
+*
+* Description: Parses return <expression> or a bare return, whose ReturnValue is nil. The ; at the end is optional
+ */
+func (p *Parser) parseReturnStatement() ast.Statement {
 	stmt := &ast.ReturnStatement{Token: p.curToken}
 
+	// A bare return, which returns null, ends at a ; or at the end of the block or program it is in
+	switch p.peekToken.Type {
+	case token.SEMICOLON:
+		p.nextToken()
+		return stmt
+	case token.RBRACE, token.EOF:
+		return stmt
+	}
+
 	p.nextToken()
 
-	// TODO: Skipping expressions for now
-	for !p.curTokenIs(token.SEMICOLON) {
+	stmt.ReturnValue = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+/*
+* Function: Parser.parseWhileStatement
+*
+* Parameters: none
+*
+* Returns: ast.Statement - The while statement starting at the current token, a BadStatement if it is malformed
+*
+* Description: Parses while (condition) { ... }. The body is parsed with loopDepth raised, so the break and continue
+*              statements inside it are accepted
+ */
+func (p *Parser) parseWhileStatement() ast.Statement {
+	stmt := &ast.WhileStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return &ast.BadStatement{From: stmt.Token, To: p.curToken}
+	}
+
+	p.nextToken()
+	stmt.Condition = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return &ast.BadStatement{From: stmt.Token, To: p.curToken}
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return &ast.BadStatement{From: stmt.Token, To: p.curToken}
+	}
+
+	p.loopDepth++
+	stmt.Body = p.parseBlockStatement()
+	p.loopDepth--
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+/*
+* Function: Parser.parseForInStatement
+*
+* Parameters: none
+*
+* Returns: ast.Statement - The for statement starting at the current token, a BadStatement if it is malformed
+*
+* Description: Parses for (name in iterable) { ... } and for (key, value in iterable) { ... }. Like the body of a
+*              while loop, the body is parsed with loopDepth raised
+ */
+func (p *Parser) parseForInStatement() ast.Statement {
+	stmt := &ast.ForInStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return &ast.BadStatement{From: stmt.Token, To: p.curToken}
+	}
+
+	if !p.expectPeek(token.IDENT) {
+		return &ast.BadStatement{From: stmt.Token, To: p.curToken}
+	}
+	stmt.Variables = append(stmt.Variables, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+
+	if p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+
+		if !p.expectPeek(token.IDENT) {
+			return &ast.BadStatement{From: stmt.Token, To: p.curToken}
+		}
+		stmt.Variables = append(stmt.Variables, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+	}
+
+	if !p.expectPeek(token.IN) {
+		return &ast.BadStatement{From: stmt.Token, To: p.curToken}
+	}
+
+	p.nextToken()
+	stmt.Iterable = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return &ast.BadStatement{From: stmt.Token, To: p.curToken}
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return &ast.BadStatement{From: stmt.Token, To: p.curToken}
+	}
+
+	p.loopDepth++
+	stmt.Body = p.parseBlockStatement()
+	p.loopDepth--
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+/*
+* Function: Parser.parseLoopControlStatement
+*
+* Parameters: none
+*
+* Returns: ast.Statement - The break or continue statement starting at the current token
+*
+* Description: Parses break or continue with an optional ; after it. Using either outside of a loop is an error,
+*              a function literal inside a loop does not count as being in it
+ */
+func (p *Parser) parseLoopControlStatement() ast.Statement {
+	var stmt ast.Statement
+	if p.curTokenIs(token.BREAK) {
+		stmt = &ast.BreakStatement{Token: p.curToken}
+	} else {
+		stmt = &ast.ContinueStatement{Token: p.curToken}
+	}
+
+	if p.loopDepth == 0 {
+		p.errors = append(p.errors, diagnostic.Diagnostic{
+			Severity: diagnostic.Error,
+			Code:     diagnostic.OutsideLoop,
+			Message:  fmt.Sprintf("%s outside of a loop", p.curToken.Literal),
+			Span:     diagnostic.SpanOf(p.curToken),
+			Actual:   p.curToken.Type,
+		})
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
 
@@ -324,10 +789,66 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	return expression
 }
 
+func (p *Parser) parseLogicalExpression(left ast.Expression) ast.Expression {
+	expression := &ast.LogicalExpression{
+		Token:    p.curToken,
+		Operator: p.curToken.Literal,
+		Left:     left,
+	}
+
+	precedence := p.curPrecedence()
+	p.nextToken()
+	expression.Right = p.parseExpression(precedence)
+
+	return expression
+}
+
+/*
+* Function: Parser.parseAssignExpression
+*
+* Parameters: left ast.Expression - The expression before the assignment operator
+*
+* Returns: ast.Expression - The *ast.AssignExpression
+*
+* Description: Parses x = value and the compound forms such as x += value. The value is parsed one precedence level
+*              below ASSIGN so assignment is right associative and a = b = 1 gives both a and b the value 1. Only
+*              names and index expressions can be assigned to, anything else is reported but still parsed so the
+*              parser can carry on
+ */
+func (p *Parser) parseAssignExpression(left ast.Expression) ast.Expression {
+	expression := &ast.AssignExpression{
+		Token:    p.curToken,
+		Operator: p.curToken.Literal,
+		Target:   left,
+	}
+
+	switch left.(type) {
+	case *ast.Identifier, *ast.IndexExpression:
+	case *ast.BadExpression:
+		// Whatever went wrong with the target has been reported already
+	default:
+		p.errors = append(p.errors, diagnostic.Diagnostic{
+			Severity: diagnostic.Error,
+			Code:     diagnostic.InvalidTarget,
+			Message:  fmt.Sprintf("cannot assign to %s", left.String()),
+			Span:     diagnostic.Span{Start: left.Pos(), End: left.End()},
+			Actual:   p.curToken.Type,
+		})
+	}
+
+	p.nextToken()
+	expression.Value = p.parseExpression(ASSIGN - 1)
+
+	return expression
+}
+
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{Token: p.curToken}
 	block.Statements = []ast.Statement{}
 
+	p.braceDepth++
+	defer func() { p.braceDepth-- }()
+
 	p.nextToken()
 
 	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
@@ -338,41 +859,70 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 		p.nextToken()
 	}
 
+	if p.curTokenIs(token.EOF) {
+		p.unexpectedTokenError(token.RBRACE, p.curToken)
+	}
+
+	block.Rbrace = p.curToken
+
 	return block
 }
 
+/*
+* Function: Parser.parseIfExpression
+*
+* Parameters: none
+*
+* Returns: ast.Expression - The parsed *ast.IfExpression, or an *ast.BadExpression if it is malformed
+*
+* Description: Parses if (condition) { ... } with an optional else { ... } or else if. Each else if becomes the
+*              Alternative of the if before it, so if (a) {} else if (b) {} else {} is two nested if expressions
+*
+ */
 func (p *Parser) parseIfExpression() ast.Expression {
 	expression := &ast.IfExpression{Token: p.curToken}
 
 	if !p.expectPeek(token.LPAREN) {
-		return nil
+		return &ast.BadExpression{From: expression.Token, To: p.curToken}
 	}
 
 	p.nextToken()
 	expression.Condition = p.parseExpression(LOWEST)
 
 	if !p.expectPeek(token.RPAREN) {
-		return nil
+		return &ast.BadExpression{From: expression.Token, To: p.curToken}
 	}
 
 	if !p.expectPeek(token.LBRACE) {
-		return nil
+		return &ast.BadExpression{From: expression.Token, To: p.curToken}
 	}
 
 	expression.Consequence = p.parseBlockStatement()
 
-	// TODO: add else if here at some point??? Should be pretty easy
+	if !p.peekTokenIs(token.ELSE) {
+		return expression
+	}
+	p.nextToken()
 
-	if p.peekTokenIs(token.ELSE) {
+	// An else if is parsed as an if expression nested in the alternative, so a chain of them keeps its shape
+	if p.peekTokenIs(token.IF) {
 		p.nextToken()
 
-		if !p.expectPeek(token.LBRACE) {
-			return nil
+		alternative := p.parseIfExpression()
+		if bad, ok := alternative.(*ast.BadExpression); ok {
+			return &ast.BadExpression{From: expression.Token, To: bad.To}
 		}
 
-		expression.Alternative = p.parseBlockStatement()
+		expression.Alternative = alternative
+		return expression
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return &ast.BadExpression{From: expression.Token, To: p.curToken}
 	}
 
+	expression.Alternative = p.parseBlockStatement()
+
 	return expression
 }
 
@@ -384,14 +934,18 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 		return identifiers
 	}
 
-	p.nextToken()
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
 
 	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 	identifiers = append(identifiers, ident)
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
-		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
 		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 		identifiers = append(identifiers, ident)
 	}
@@ -407,54 +961,161 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 	lit := &ast.FunctionLiteral{Token: p.curToken}
 
 	if !p.expectPeek(token.LPAREN) {
-		return nil
+		return &ast.BadExpression{From: lit.Token, To: p.curToken}
 	}
 
 	lit.Parameters = p.parseFunctionParameters()
+	if lit.Parameters == nil {
+		return &ast.BadExpression{From: lit.Token, To: p.curToken}
+	}
 
 	if !p.expectPeek(token.LBRACE) {
-		return nil
+		return &ast.BadExpression{From: lit.Token, To: p.curToken}
 	}
 
+	// A break in the body of a function can not leave a loop the function is defined in
+	loopDepth := p.loopDepth
+	p.loopDepth = 0
 	lit.Body = p.parseBlockStatement()
+	p.loopDepth = loopDepth
 
 	return lit
 }
 
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	exp := &ast.CallExpression{Token: p.curToken, Function: function}
-	exp.Arguments = p.parseCallArguments()
+	exp.Arguments = p.parseExpressionList(token.RPAREN)
+	exp.Rparen = p.curToken
 	return exp
 }
 
-func (p *Parser) parseCallArguments() []ast.Expression {
-	args := []ast.Expression{}
+func (p *Parser) parseArrayLiteral() ast.Expression {
+	array := &ast.ArrayLiteral{Token: p.curToken}
+	array.Elements = p.parseExpressionList(token.RBRACKET)
+	array.Rbracket = p.curToken
+	return array
+}
+
+/*
+* Function: Parser.parseHashLiteral
+*
+* Parameters: none
+*
+* Returns: ast.Expression - The hash literal starting at the current { token
+*
+* Description: Parses a hash literal such as {"name": "monkey", 1: true}. This is only ever called for a { where an
+*              expression is expected, blocks are parsed by parseBlockStatement after the token that starts them
+*              (if, else or fn), so the two never compete for the same {
+*
+ */
+func (p *Parser) parseHashLiteral() ast.Expression {
+	hash := &ast.HashLiteral{Token: p.curToken}
+	hash.Pairs = []ast.HashPair{}
+
+	p.braceDepth++
+	defer func() { p.braceDepth-- }()
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		key := p.parseExpression(LOWEST)
+
+		if !p.expectPeek(token.COLON) {
+			p.skipPastClosingBrace()
+			return &ast.BadExpression{From: hash.Token, To: p.curToken}
+		}
 
-	if p.peekTokenIs(token.RPAREN) {
 		p.nextToken()
-		return args
+		value := p.parseExpression(LOWEST)
+
+		hash.Pairs = append(hash.Pairs, ast.HashPair{Key: key, Value: value})
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			p.skipPastClosingBrace()
+			return &ast.BadExpression{From: hash.Token, To: p.curToken}
+		}
 	}
 
 	p.nextToken()
-	args = append(args, p.parseExpression(LOWEST))
+	hash.Rbrace = p.curToken
 
-	for p.peekTokenIs(token.COMMA) {
+	return hash
+}
+
+/*
+* Function: Parser.skipPastClosingBrace
+*
+* Parameters: none
+*
+* Returns: none
+*
+* Description: Used after an error inside of braces that are not a block. Skips ahead to the } closing them, so
+*              the } is not mistaken for the end of an enclosing block. Stops early at the end of the input
+*
+ */
+func (p *Parser) skipPastClosingBrace() {
+	depth := 1
+
+	for !p.peekTokenIs(token.EOF) {
 		p.nextToken()
+
+		switch p.curToken.Type {
+		case token.LBRACE:
+			depth++
+		case token.RBRACE:
+			depth--
+			if depth == 0 {
+				return
+			}
+		}
+	}
+}
+
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
+
+	p.nextToken()
+	exp.Index = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RBRACKET) {
+		return &ast.BadExpression{From: exp.Token, To: p.curToken}
+	}
+
+	exp.Rbracket = p.curToken
+
+	return exp
+}
+
+/*
+* Function: Parser.parseExpressionList
+*
+* Parameters: end token.TokenType - The token closing the list, such as ) for call arguments
+*
+* Returns: []ast.Expression - The comma separated expressions up to end
+*
+* Description: Parses the expressions of a call's arguments or an array literal, leaving the parser on end
+*
+ */
+func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
+	list := []ast.Expression{}
+
+	if p.peekTokenIs(end) {
 		p.nextToken()
-		args = append(args, p.parseExpression(LOWEST))
+		return list
 	}
 
+	p.nextToken()
+	list = append(list, p.parseExpression(LOWEST))
+
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
-		args = append(args, p.parseExpression(LOWEST))
+		list = append(list, p.parseExpression(LOWEST))
 	}
 
-	if !p.expectPeek(token.RPAREN) {
-		return nil
-	}
+	// The expressions parsed so far are kept even when the list is not closed, so tools still see them
+	p.expectPeek(end)
 
-	return args
+	return list
 }
 
 func (p *Parser) curTokenIs(t token.TokenType) bool {
@@ -475,6 +1136,27 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 	}
 }
 
+/*
+* Function: Parser.reported
+*
+* Parameters: tok token.Token - The token to check
+*
+* Returns: bool - True if an error pointing into tok has already been reported
+*
+* Description: Keeps the parser from piling its own error on top of one the lexer already reported for a token,
+*              such as a malformed number
+ */
+func (p *Parser) reported(tok token.Token) bool {
+	for _, err := range p.errors {
+		start := err.Span.Start
+		if start.Filename == tok.Pos.Filename && start.Offset >= tok.Pos.Offset && start.Offset < tok.End.Offset {
+			return true
+		}
+	}
+
+	return false
+}
+
 /*
 * Function Parser.peekError
 *
@@ -482,10 +1164,44 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 *
 * Returns: none
 *
-* Description: Adds an error to the parser's error array when a token of t was expected and something else was found
+* Description: Adds an error to the parser's error array when the next token was expected to be of type t and
+*              something else was found
 *
  */
 func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("expected next token to be %s, got %s instead", t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	p.unexpectedTokenError(t, p.peekToken)
+}
+
+/*
+* Function Parser.unexpectedTokenError
+*
+* Parameters: t   token.TokenType - The token type that was expected
+*             got token.Token     - The token that was found instead
+*
+* Returns: none
+*
+* Description: Adds an error to the parser's error array when a token of t was expected and got was found.
+*              Missing closing delimiters come with a hint to insert them right before the unexpected token
+*
+ */
+func (p *Parser) unexpectedTokenError(t token.TokenType, got token.Token) {
+	d := diagnostic.Diagnostic{
+		Severity: diagnostic.Error,
+		Code:     diagnostic.UnexpectedToken,
+		Message:  fmt.Sprintf("expected next token to be %s, got %s instead", t, got.Type),
+		Span:     diagnostic.SpanOf(got),
+		Expected: []token.TokenType{t},
+		Actual:   got.Type,
+	}
+
+	switch t {
+	case token.RPAREN, token.RBRACE, token.SEMICOLON:
+		d.Hints = append(d.Hints, diagnostic.FixIt{
+			Message:     fmt.Sprintf("insert %q", string(t)),
+			Span:        diagnostic.Span{Start: got.Pos, End: got.Pos},
+			Replacement: string(t),
+		})
+	}
+
+	p.errors = append(p.errors, d)
 }
@@ -11,46 +11,49 @@ package parser
 
 import (
 	"fmt"
+	"math/big"
 	"testing"
 
 	"github.com/vtallen/go-interpreter/ast"
+	"github.com/vtallen/go-interpreter/diagnostic"
 	"github.com/vtallen/go-interpreter/lexer"
+	"github.com/vtallen/go-interpreter/token"
 )
 
-// TODO: Enable after finishing the parser
-// func TestLetStatements(t *testing.T) {
-// 	tests := []struct {
-// 		input              string
-// 		expectedIdentifier string
-// 		expectedValue      interface{}
-// 	}{
-// 		{"let x = 5;", "x", 5},
-// 		{"let y = true;", "y", true},
-// 		{"let foobar = y;", "foobar", "y"},
-// 	}
-
-// 	for _, tt := range tests {
-// 		l := lexer.New(tt.input)
-// 		p := New(l)
-// 		program := p.ParseProgram()
-// 		checkParserErrors(t, p)
-
-// 		if len(program.Statements) != 1 {
-// 			t.Fatalf("program.Statments does not contain 1 statements. got=%d", len(program.Statements))
-// 		}
-
-// 		stmt := program.Statements[0]
-// 		if !testLetStatement(t, stmt, tt.expectedIdentifier) {
-// 			return
-// 		}
-
-// 		val := stmt.(*ast.LetStatement).Value
-// 		fmt.Printf("%#v", stmt.(*ast.LetStatement).Value)
-// 		if !testLiteralExpression(t, val, tt.expectedValue) {
-// 			return
-// 		}
-// 	}
-// }
+func TestLetStatements(t *testing.T) {
+	tests := []struct {
+		input              string
+		expectedIdentifier string
+		expectedValue      interface{}
+	}{
+		{"let x = 5;", "x", 5},
+		{"let y = true;", "y", true},
+		{"let foobar = y;", "foobar", "y"},
+		// The ; is optional
+		{"let x = 5", "x", 5},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statments does not contain 1 statements. got=%d", len(program.Statements))
+		}
+
+		stmt := program.Statements[0]
+		if !testLetStatement(t, stmt, tt.expectedIdentifier) {
+			return
+		}
+
+		val := stmt.(*ast.LetStatement).Value
+		if !testLiteralExpression(t, val, tt.expectedValue) {
+			return
+		}
+	}
+}
 
 func testLetStatement(t *testing.T, s ast.Statement, name string) bool {
 	if s.TokenLiteral() != "let" {
@@ -86,37 +89,97 @@ func checkParserErrors(t *testing.T, p *Parser) {
 
 	t.Errorf("parser has %d errors", len(errors))
 	for _, msg := range errors {
-		t.Errorf("parser error: %q", msg)
+		t.Errorf("parser error: %q", msg.Error())
 	}
 	t.FailNow()
 }
 
 func TestReturnStatements(t *testing.T) {
-	input := `
-    return 5;
-    return 10;
-    return 993322;
-  `
-
-	l := lexer.New(input)
-	p := New(l)
+	tests := []struct {
+		input         string
+		expectedValue interface{} // nil for a bare return
+	}{
+		{"return 5;", 5},
+		{"return true;", true},
+		{"return foobar;", "foobar"},
+		{"return 5", 5},
+		{"return;", nil},
+		{"return", nil},
+	}
 
-	program := p.ParseProgram()
-	checkParserErrors(t, p)
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
 
-	if len(program.Statements) != 3 {
-		t.Fatalf("program.Statements does not contain 3 statments. got=%d", len(program.Statements))
-	}
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+		}
 
-	for _, stmt := range program.Statements {
-		returnStmt, ok := stmt.(*ast.ReturnStatement)
+		returnStmt, ok := program.Statements[0].(*ast.ReturnStatement)
 		if !ok {
-			t.Errorf("stmt not *ast.returnStatment. got=%T", stmt)
-			continue
+			t.Fatalf("stmt not *ast.ReturnStatement. got=%T", program.Statements[0])
 		}
+
 		if returnStmt.TokenLiteral() != "return" {
 			t.Errorf("returnStmt.TokenLiteral not 'return', got %q", returnStmt.TokenLiteral())
 		}
+
+		if tt.expectedValue == nil {
+			if returnStmt.ReturnValue != nil {
+				t.Errorf("returnStmt.ReturnValue not nil. got=%s", returnStmt.ReturnValue)
+			}
+			continue
+		}
+
+		if !testLiteralExpression(t, returnStmt.ReturnValue, tt.expectedValue) {
+			return
+		}
+	}
+}
+
+func TestStatementsRoundTrip(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = 1 + 2 * 3;", "let x = (1 + (2 * 3));"},
+		{"let s = \"hi\"\nlet t = s", "let s = \"hi\";let t = s;"},
+		{"let h = {\"a\": -x}", "let h = {\"a\": (-x)};"},
+		{"return [1, 2][0]", "return ([1, 2][0]);"},
+		{"return f(x, y) && z", "return (f(x, y) && z);"},
+		{"return;", "return;"},
+		{"return", "return;"},
+		{"if (a) { 1 } else if (b) { 2 } else { 3 }", "if (a) { 1 } else if (b) { 2 } else { 3 }"},
+		{"if (a < b) { x } else if (c) { y }", "if ((a < b)) { x } else if (c) { y }"},
+		{"let y = if (a) { 1 } else { if (b) { 2 } }", "let y = if (a) { 1 } else { if (b) { 2 } };"},
+		{"while (i < 10) { break }", "while ((i < 10)) { break; }"},
+		{"while (x) { continue; };", "while (x) { continue; }"},
+		{"for (x in 0..10) { x }", "for (x in (0 .. 10)) { x }"},
+		{"for (k, v in h) { break; };", "for (k, v in h) { break; }"},
+		{"x = x + 1", "(x = (x + 1))"},
+		{"let y = a[0] *= 2;", "let y = ((a[0]) *= 2);"},
+		{"let f = fn(x, y) { return x + y; };", "let f = fn(x, y) { return (x + y); };"},
+		{"fn() { 1 }()", "fn() { 1 }()"},
+		{"let g = fn(f) { fn(x) { f(x) } }", "let g = fn(f) { fn(x) { f(x) } };"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("%q: program.String() wrong. expected=%q, got=%q", tt.input, tt.expected, program.String())
+		}
+
+		// Parsing the output again gives the same program
+		again := New(lexer.New(program.String())).ParseProgram()
+		if again.String() != program.String() {
+			t.Errorf("%q: String() does not round trip. first=%q, second=%q", tt.input, program.String(), again.String())
+		}
 	}
 }
 
@@ -179,6 +242,137 @@ func TestIntegerLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestIntegerLiteralBases(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"0xFF", 255},
+		{"0b1010", 10},
+		{"0o755", 493},
+		{"1_000_000", 1000000},
+		{"0x_7fff_ffff_ffff_ffff", 9223372036854775807},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.IntegerLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.IntegerLiteral. got=%T", stmt.Expression)
+		}
+
+		if literal.Value != tt.expected {
+			t.Errorf("%q: literal.Value not %d. got=%d", tt.input, tt.expected, literal.Value)
+		}
+
+		// The literal is printed the way it was written
+		if program.String() != tt.input {
+			t.Errorf("program.String() wrong. expected=%q, got=%q", tt.input, program.String())
+		}
+	}
+}
+
+func TestBigIntegerLiteral(t *testing.T) {
+	tests := []string{"9223372036854775808", "0x1_0000_0000_0000_0000", "123456789012345678901234567890"}
+
+	for _, input := range tests {
+		l := lexer.New(input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.IntegerLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.IntegerLiteral. got=%T", stmt.Expression)
+		}
+
+		expected, _ := new(big.Int).SetString(input, 0)
+		if literal.Big == nil || literal.Big.Cmp(expected) != 0 {
+			t.Errorf("%q: literal.Big wrong. expected=%s, got=%v", input, expected, literal.Big)
+		}
+
+		if program.String() != input {
+			t.Errorf("program.String() wrong. expected=%q, got=%q", input, program.String())
+		}
+	}
+}
+
+func TestMalformedNumbersReportedOnce(t *testing.T) {
+	l := lexer.New("1 + 0b102 + 1e999 + 017 + 08")
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	expected := []diagnostic.Code{diagnostic.InvalidNumber, diagnostic.InvalidFloat, diagnostic.InvalidNumber, diagnostic.InvalidNumber}
+
+	if len(errors) != len(expected) {
+		t.Fatalf("wrong number of errors. expected=%d, got=%d (%v)", len(expected), len(errors), errors)
+	}
+
+	for i, code := range expected {
+		if errors[i].Code != code {
+			t.Errorf("errors[%d] wrong. expected code %s, got=%s", i, code, errors[i].Error())
+		}
+	}
+}
+
+func TestFloatLiteralExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"3.14;", 3.14},
+		{".5", 0.5},
+		{"1e-9", 1e-9},
+		{"2.5E+3", 2500},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program has not enough statements. got=%d", len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+		}
+
+		literal, ok := stmt.Expression.(*ast.FloatLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.FloatLiteral. got=%T", stmt.Expression)
+		}
+		if literal.Value != tt.expected {
+			t.Errorf("literal.Value not %g. got=%g", tt.expected, literal.Value)
+		}
+	}
+}
+
+func TestFloatLiteralOutOfRange(t *testing.T) {
+	l := lexer.New("1e999")
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) != 1 {
+		t.Fatalf("expected 1 error, got=%d (%v)", len(errors), errors)
+	}
+
+	if errors[0].Code != diagnostic.InvalidFloat || errors[0].Message != `could not parse "1e999" as float` {
+		t.Errorf("wrong error. got=%s", errors[0].Error())
+	}
+}
+
 func testIntegerLiteral(t *testing.T, il ast.Expression, value int64) bool {
 	integ, ok := il.(*ast.IntegerLiteral)
 	if !ok {
@@ -230,6 +424,9 @@ func testLiteralExpression(t *testing.T, exp ast.Expression, expected interface{
 	case string:
 		return testIdentifier(t, exp, v)
 
+	case bool:
+		return testBooleanLiteral(t, exp, v)
+
 	}
 
 	t.Errorf("type of exp not handled. got=%T", exp)
@@ -237,6 +434,26 @@ func testLiteralExpression(t *testing.T, exp ast.Expression, expected interface{
 	return false
 }
 
+func testBooleanLiteral(t *testing.T, exp ast.Expression, value bool) bool {
+	bo, ok := exp.(*ast.Boolean)
+	if !ok {
+		t.Errorf("exp not *ast.Boolean. got=%T", exp)
+		return false
+	}
+
+	if bo.Value != value {
+		t.Errorf("bo.Value not %t. got=%t", value, bo.Value)
+		return false
+	}
+
+	if bo.TokenLiteral() != fmt.Sprintf("%t", value) {
+		t.Errorf("bo.TokenLiteral not %t. got=%s", value, bo.TokenLiteral())
+		return false
+	}
+
+	return true
+}
+
 func testInfixExpression(t *testing.T, exp ast.Expression, left interface{}, operator string, right interface{}) bool {
 	opExp, ok := exp.(*ast.InfixExpression)
 	if !ok {
@@ -268,6 +485,7 @@ func TestParsingPrefixExpressions(t *testing.T) {
 	}{
 		{"!5;", "!", 5},
 		{"-15", "-", 15},
+		{"~15", "~", 15},
 	}
 
 	for _, tt := range prefixTests {
@@ -315,6 +533,16 @@ func TestParsingInfixExpressions(t *testing.T) {
 		{"5 < 5;", 5, "<", 5},
 		{"5 == 5;", 5, "==", 5},
 		{"5 != 5;", 5, "!=", 5},
+		{"5 >= 5;", 5, ">=", 5},
+		{"5 <= 5;", 5, "<=", 5},
+		{"5 % 5;", 5, "%", 5},
+		{"5 & 5;", 5, "&", 5},
+		{"5 | 5;", 5, "|", 5},
+		{"5 ^ 5;", 5, "^", 5},
+		{"5 << 5;", 5, "<<", 5},
+		{"5 >> 5;", 5, ">>", 5},
+		{"5..5;", 5, "..", 5},
+		{"5..=5;", 5, "..=", 5},
 	}
 
 	for _, tt := range infixTests {
@@ -351,6 +579,38 @@ func TestParsingInfixExpressions(t *testing.T) {
 	}
 }
 
+func TestLogicalExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		operator string
+	}{
+		{"x && y", "&&"},
+		{"x || y", "||"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+
+		exp, ok := stmt.Expression.(*ast.LogicalExpression)
+		if !ok {
+			t.Fatalf("exp is not ast.LogicalExpression. got=%T", stmt.Expression)
+		}
+
+		if exp.Operator != tt.operator {
+			t.Fatalf("exp.Operator is not '%s'. got=%s", tt.operator, exp.Operator)
+		}
+
+		if !testIdentifier(t, exp.Left, "x") || !testIdentifier(t, exp.Right, "y") {
+			return
+		}
+	}
+}
+
 func TestOperatorPrecedenceParsing(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -364,6 +624,10 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"!-a",
 			"(!(-a))",
 		},
+		{
+			"-.5 * 2 + 1e3",
+			"(((-.5) * 2) + 1e3)",
+		},
 		{
 			"a + b + c",
 			"((a + b) + c)",
@@ -428,6 +692,82 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"!(true == true)",
 			"(!(true == true))",
 		},
+		{
+			"a * [1, 2, 3, 4][b * c] * d",
+			"((a * ([1, 2, 3, 4][(b * c)])) * d)",
+		},
+		{
+			"add(a * b[2], b[1], 2 * [1, 2][1])",
+			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
+		},
+		{
+			"f(x)[0]",
+			"(f(x)[0])",
+		},
+		{
+			"a + b % c * d",
+			"(a + ((b % c) * d))",
+		},
+		{
+			"a <= b == c >= d",
+			"((a <= b) == (c >= d))",
+		},
+		{
+			"a || b && c",
+			"(a || (b && c))",
+		},
+		{
+			"a && b || c && d",
+			"((a && b) || (c && d))",
+		},
+		{
+			"a == b && !c || d < e + 1",
+			"(((a == b) && (!c)) || (d < (e + 1)))",
+		},
+		{
+			"a | b ^ c & d",
+			"(a | (b ^ (c & d)))",
+		},
+		{
+			"a & b == c",
+			"(a & (b == c))",
+		},
+		{
+			"a < b << c + d",
+			"(a < (b << (c + d)))",
+		},
+		{
+			"a && b | c",
+			"(a && (b | c))",
+		},
+		{
+			"~a & ~-b >> 1",
+			"((~a) & ((~(-b)) >> 1))",
+		},
+		{
+			"0..n - 1",
+			"(0 .. (n - 1))",
+		},
+		{
+			"a < 1..=b << 2",
+			"(a < (1 ..= (b << 2)))",
+		},
+		{
+			"a = b = c",
+			"(a = (b = c))",
+		},
+		{
+			"x += y * 2 || z",
+			"(x += ((y * 2) || z))",
+		},
+		{
+			"a[i] -= 1; b %= c /= 3",
+			"((a[i]) -= 1)(b %= (c /= 3))",
+		},
+		{
+			"f(x = 1, y)",
+			"f((x = 1), y)",
+		},
 	}
 	for _, tt := range tests {
 		l := lexer.New(tt.input)
@@ -485,8 +825,8 @@ func TestIfExpression(t *testing.T) {
 	}
 }
 
-func TestFunctionLiteralParsing(t *testing.T) {
-	input := `fn(x, y) { x + y }`
+func TestIfElseIfExpression(t *testing.T) {
+	input := `if (x < y) { x } else if (x > y) { y } else { z }`
 
 	l := lexer.New(input)
 	p := New(l)
@@ -502,42 +842,297 @@ func TestFunctionLiteralParsing(t *testing.T) {
 		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
 	}
 
-	function, ok := stmt.Expression.(*ast.FunctionLiteral)
+	exp, ok := stmt.Expression.(*ast.IfExpression)
 	if !ok {
-		t.Fatalf("stmt.Expression is not ast.FunctionLiteral. got=%T", stmt.Expression)
+		t.Fatalf("stmt.Expression is not ast.IfExpression. got=%T", stmt.Expression)
 	}
 
-	if len(function.Parameters) != 2 {
-		t.Fatalf("function literal parameters wrong. want 2, got=%d\n", len(function.Parameters))
+	if !testInfixExpression(t, exp.Condition, "x", "<", "y") {
+		return
 	}
 
-	testLiteralExpression(t, function.Parameters[0], "x")
-	testLiteralExpression(t, function.Parameters[1], "y")
+	elseIf, ok := exp.Alternative.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf("exp.Alternative is not ast.IfExpression. got=%T", exp.Alternative)
+	}
 
-	if len(function.Body.Statements) != 1 {
-		t.Fatalf("function.Body.Statements has not 1 statements. got=%d\n", len(function.Body.Statements))
+	if !testInfixExpression(t, elseIf.Condition, "x", ">", "y") {
+		return
 	}
 
-	bodyStmt, ok := function.Body.Statements[0].(*ast.ExpressionStatement)
+	consequence := elseIf.Consequence.Statements[0].(*ast.ExpressionStatement)
+	if !testIdentifier(t, consequence.Expression, "y") {
+		return
+	}
+
+	alternative, ok := elseIf.Alternative.(*ast.BlockStatement)
 	if !ok {
-		t.Fatalf("function body stmt is not ast.ExpressionStatement. got=%T", function.Body.Statements[0])
+		t.Fatalf("elseIf.Alternative is not ast.BlockStatement. got=%T", elseIf.Alternative)
 	}
 
-	testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
-}
+	if !testIdentifier(t, alternative.Statements[0].(*ast.ExpressionStatement).Expression, "z") {
+		return
+	}
 
-func TestFunctionParameterParsing(t *testing.T) {
-	tests := []struct {
-		input          string
-		expectedParams []string
-	}{
-		{input: "fn() {};", expectedParams: []string{}},
-		{input: "fn(x) {};", expectedParams: []string{"x"}},
-		{input: "fn(x, y, z) {};", expectedParams: []string{"x", "y", "z"}},
+	// The chain ends where its last block does
+	if exp.End() != alternative.End() {
+		t.Errorf("exp.End() wrong. expected=%s, got=%s", alternative.End(), exp.End())
 	}
+}
 
-	for _, tt := range tests {
-		l := lexer.New(tt.input)
+func TestWhileStatement(t *testing.T) {
+	input := `while (x < y) { if (x) { break } continue; }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Body does not contain %d statements. got=%d\n", 1, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.WhileStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.WhileStatement. got=%T", program.Statements[0])
+	}
+
+	if !testInfixExpression(t, stmt.Condition, "x", "<", "y") {
+		return
+	}
+
+	if len(stmt.Body.Statements) != 2 {
+		t.Fatalf("body is not 2 statements. got=%d\n", len(stmt.Body.Statements))
+	}
+
+	ifExp := stmt.Body.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.IfExpression)
+	if _, ok := ifExp.Consequence.Statements[0].(*ast.BreakStatement); !ok {
+		t.Errorf("consequence is not ast.BreakStatement. got=%T", ifExp.Consequence.Statements[0])
+	}
+
+	if _, ok := stmt.Body.Statements[1].(*ast.ContinueStatement); !ok {
+		t.Errorf("body.Statements[1] is not ast.ContinueStatement. got=%T", stmt.Body.Statements[1])
+	}
+}
+
+func TestForInStatement(t *testing.T) {
+	tests := []struct {
+		input             string
+		expectedVariables []string
+	}{
+		{"for (x in xs) { x }", []string{"x"}},
+		{"for (k, v in xs) { x }", []string{"k", "v"}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Body does not contain %d statements. got=%d\n", 1, len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ForInStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ForInStatement. got=%T", program.Statements[0])
+		}
+
+		if len(stmt.Variables) != len(tt.expectedVariables) {
+			t.Fatalf("wrong number of variables. expected=%d, got=%d", len(tt.expectedVariables), len(stmt.Variables))
+		}
+
+		for i, name := range tt.expectedVariables {
+			testLiteralExpression(t, stmt.Variables[i], name)
+		}
+
+		if !testIdentifier(t, stmt.Iterable, "xs") {
+			return
+		}
+
+		if len(stmt.Body.Statements) != 1 {
+			t.Errorf("body is not 1 statements. got=%d\n", len(stmt.Body.Statements))
+		}
+	}
+}
+
+func TestLoopControlOutsideLoop(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+		expectedSpan    string
+	}{
+		{"break", "break outside of a loop", "1:1"},
+		{"if (x) { continue; }", "continue outside of a loop", "1:10"},
+		{"while (x) { fn() { break } }", "break outside of a loop", "1:20"},
+		{"for (x in y) { 1 } continue", "continue outside of a loop", "1:20"},
+		{"while (x) { 1 } break;", "break outside of a loop", "1:17"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != 1 {
+			t.Fatalf("%q: wrong number of errors. expected=1, got=%d", tt.input, len(errors))
+		}
+
+		if errors[0].Code != diagnostic.OutsideLoop {
+			t.Errorf("%q: Code wrong. expected=%q, got=%q", tt.input, diagnostic.OutsideLoop, errors[0].Code)
+		}
+
+		if errors[0].Message != tt.expectedMessage {
+			t.Errorf("%q: Message wrong. expected=%q, got=%q", tt.input, tt.expectedMessage, errors[0].Message)
+		}
+
+		if errors[0].Span.Start.String() != tt.expectedSpan {
+			t.Errorf("%q: Span wrong. expected=%s, got=%s", tt.input, tt.expectedSpan, errors[0].Span.Start)
+		}
+	}
+}
+
+func TestAssignExpression(t *testing.T) {
+	tests := []struct {
+		input            string
+		expectedOperator string
+		expectedTarget   string
+		expectedValue    string
+	}{
+		{"x = 5;", "=", "x", "5"},
+		{"total += n * 2", "+=", "total", "(n * 2)"},
+		{"a[0] -= 1", "-=", "(a[0])", "1"},
+		{`h["k"] = [1]`, "=", `(h["k"])`, "[1]"},
+		{"x *= y /= 2", "*=", "x", "(y /= 2)"},
+		{"x %= 3", "%=", "x", "3"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("%q: program.Statements does not contain 1 statement. got=%d", tt.input, len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("%q: program.Statements[0] is not ast.ExpressionStatement. got=%T", tt.input, program.Statements[0])
+		}
+
+		exp, ok := stmt.Expression.(*ast.AssignExpression)
+		if !ok {
+			t.Fatalf("%q: stmt.Expression is not ast.AssignExpression. got=%T", tt.input, stmt.Expression)
+		}
+
+		if exp.Operator != tt.expectedOperator {
+			t.Errorf("%q: exp.Operator is not %q. got=%q", tt.input, tt.expectedOperator, exp.Operator)
+		}
+
+		if exp.Target.String() != tt.expectedTarget {
+			t.Errorf("%q: exp.Target is not %q. got=%q", tt.input, tt.expectedTarget, exp.Target.String())
+		}
+
+		if exp.Value.String() != tt.expectedValue {
+			t.Errorf("%q: exp.Value is not %q. got=%q", tt.input, tt.expectedValue, exp.Value.String())
+		}
+	}
+}
+
+func TestInvalidAssignTarget(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+		expectedSpan    string
+	}{
+		{"1 = 2", "cannot assign to 1", "1:1"},
+		{"f() += 1", "cannot assign to f()", "1:1"},
+		{"a + b = c", "cannot assign to (a + b)", "1:1"},
+		{"x = -y = 2", "cannot assign to (-y)", "1:5"},
+		{"let z = fn() { 1 } = 2;", "cannot assign to fn() { 1 }", "1:9"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != 1 {
+			t.Fatalf("%q: wrong number of errors. expected=1, got=%d", tt.input, len(errors))
+		}
+
+		if errors[0].Code != diagnostic.InvalidTarget {
+			t.Errorf("%q: Code wrong. expected=%q, got=%q", tt.input, diagnostic.InvalidTarget, errors[0].Code)
+		}
+
+		if errors[0].Message != tt.expectedMessage {
+			t.Errorf("%q: Message wrong. expected=%q, got=%q", tt.input, tt.expectedMessage, errors[0].Message)
+		}
+
+		if errors[0].Span.Start.String() != tt.expectedSpan {
+			t.Errorf("%q: Span wrong. expected=%s, got=%s", tt.input, tt.expectedSpan, errors[0].Span.Start)
+		}
+	}
+}
+
+func TestFunctionLiteralParsing(t *testing.T) {
+	input := `fn(x, y) { x + y }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Body does not contain %d statements. got=%d\n", 1, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+
+	function, ok := stmt.Expression.(*ast.FunctionLiteral)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.FunctionLiteral. got=%T", stmt.Expression)
+	}
+
+	if len(function.Parameters) != 2 {
+		t.Fatalf("function literal parameters wrong. want 2, got=%d\n", len(function.Parameters))
+	}
+
+	testLiteralExpression(t, function.Parameters[0], "x")
+	testLiteralExpression(t, function.Parameters[1], "y")
+
+	if len(function.Body.Statements) != 1 {
+		t.Fatalf("function.Body.Statements has not 1 statements. got=%d\n", len(function.Body.Statements))
+	}
+
+	bodyStmt, ok := function.Body.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("function body stmt is not ast.ExpressionStatement. got=%T", function.Body.Statements[0])
+	}
+
+	testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
+}
+
+func TestFunctionParameterParsing(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedParams []string
+	}{
+		{input: "fn() {};", expectedParams: []string{}},
+		{input: "fn(x) {};", expectedParams: []string{"x"}},
+		{input: "fn(x, y, z) {};", expectedParams: []string{"x", "y", "z"}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
 		p := New(l)
 		program := p.ParseProgram()
 		checkParserErrors(t, p)
@@ -589,3 +1184,597 @@ func TestCallExpressionParsing(t *testing.T) {
 	testInfixExpression(t, exp.Arguments[1], 2, "*", 3)
 	testInfixExpression(t, exp.Arguments[2], 4, "+", 5)
 }
+
+func TestNodePositions(t *testing.T) {
+	input := "add(1,\n  2 * x);\nif (x) { y } else { -z }"
+
+	l := lexer.NewFile("test.mk", input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d\n", 2, len(program.Statements))
+	}
+
+	call := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.CallExpression)
+	ifExp := program.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.IfExpression)
+
+	tests := []struct {
+		node        ast.Node
+		expectedPos string
+		expectedEnd string
+	}{
+		{program, "test.mk:1:1", "test.mk:3:25"},
+		{call, "test.mk:1:1", "test.mk:2:9"},
+		{call.Arguments[0], "test.mk:1:5", "test.mk:1:6"},
+		{call.Arguments[1], "test.mk:2:3", "test.mk:2:8"},
+		{ifExp, "test.mk:3:1", "test.mk:3:25"},
+		{ifExp.Consequence, "test.mk:3:8", "test.mk:3:13"},
+		{ifExp.Alternative.(*ast.BlockStatement).Statements[0], "test.mk:3:21", "test.mk:3:23"},
+	}
+
+	for i, tt := range tests {
+		if tt.node.Pos().String() != tt.expectedPos {
+			t.Errorf("tests[%d] - %s pos wrong. expected=%s, got=%s", i, tt.node, tt.expectedPos, tt.node.Pos())
+		}
+
+		if tt.node.End().String() != tt.expectedEnd {
+			t.Errorf("tests[%d] - %s end wrong. expected=%s, got=%s", i, tt.node, tt.expectedEnd, tt.node.End())
+		}
+	}
+}
+
+func TestParserDiagnostics(t *testing.T) {
+	input := "add(1, 2;"
+
+	l := lexer.NewFile("test.mk", input)
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) == 0 {
+		t.Fatalf("parser reported no errors")
+	}
+
+	d := errors[0]
+	if d.Code != diagnostic.UnexpectedToken {
+		t.Errorf("d.Code wrong. expected=%q, got=%q", diagnostic.UnexpectedToken, d.Code)
+	}
+
+	if d.Severity != diagnostic.Error {
+		t.Errorf("d.Severity wrong. expected=%s, got=%s", diagnostic.Error, d.Severity)
+	}
+
+	if d.Span.Start.String() != "test.mk:1:9" {
+		t.Errorf("d.Span.Start wrong. expected=%s, got=%s", "test.mk:1:9", d.Span.Start)
+	}
+
+	if len(d.Expected) != 1 || d.Expected[0] != token.RPAREN {
+		t.Errorf("d.Expected wrong. expected=%v, got=%v", []token.TokenType{token.RPAREN}, d.Expected)
+	}
+
+	if d.Actual != token.SEMICOLON {
+		t.Errorf("d.Actual wrong. expected=%q, got=%q", token.SEMICOLON, d.Actual)
+	}
+
+	if len(d.Hints) != 1 || d.Hints[0].Replacement != ")" {
+		t.Errorf("d.Hints wrong. got=%+v", d.Hints)
+	}
+}
+
+func TestErrorRecovery(t *testing.T) {
+	tests := []struct {
+		input              string
+		expectedErrors     []string
+		expectedStatements []string
+	}{
+		{
+			"let = 5; let y = 10; add(1, 2; let z = 3;",
+			[]string{
+				"expected next token to be IDENT, got = instead",
+				"expected next token to be ), got ; instead",
+			},
+			[]string{"*ast.BadStatement", "*ast.LetStatement", "*ast.ExpressionStatement", "*ast.LetStatement"},
+		},
+		{
+			"if (x { y } let a = 1;",
+			[]string{"expected next token to be ), got { instead"},
+			[]string{"*ast.ExpressionStatement", "*ast.LetStatement"},
+		},
+		{
+			"if (x) { y } else if x { z } let a = 1;",
+			[]string{"expected next token to be (, got IDENT instead"},
+			[]string{"*ast.ExpressionStatement", "*ast.LetStatement"},
+		},
+		{
+			"while x { y } let a = 1;",
+			[]string{"expected next token to be (, got IDENT instead"},
+			[]string{"*ast.BadStatement", "*ast.LetStatement"},
+		},
+		{
+			"for (1 in x) { y } let a = 1;",
+			[]string{"expected next token to be IDENT, got INT instead"},
+			[]string{"*ast.BadStatement", "*ast.LetStatement"},
+		},
+		{
+			"for (x of y) { y } let a = 1;",
+			[]string{"expected next token to be IN, got IDENT instead"},
+			[]string{"*ast.BadStatement", "*ast.LetStatement"},
+		},
+		{
+			"fn() { 1 + }; let b = 2;",
+			[]string{"no prefix parse functions for } found"},
+			[]string{"*ast.ExpressionStatement", "*ast.LetStatement"},
+		},
+		{
+			"fn(1, x) { x } fn(y) { y }",
+			[]string{"expected next token to be IDENT, got INT instead"},
+			[]string{"*ast.ExpressionStatement", "*ast.ExpressionStatement"},
+		},
+		{
+			"let x = 5",
+			[]string{},
+			[]string{"*ast.LetStatement"},
+		},
+		{
+			"fn() { x",
+			[]string{"expected next token to be }, got EOF instead"},
+			[]string{"*ast.ExpressionStatement"},
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != len(tt.expectedErrors) {
+			for _, e := range errors {
+				t.Logf("parser error: %q", e.Error())
+			}
+			t.Fatalf("input %q: wrong number of errors. expected=%d, got=%d", tt.input, len(tt.expectedErrors), len(errors))
+		}
+
+		for i, msg := range tt.expectedErrors {
+			if errors[i].Message != msg {
+				t.Errorf("input %q: errors[%d] wrong. expected=%q, got=%q", tt.input, i, msg, errors[i].Message)
+			}
+		}
+
+		if len(program.Statements) != len(tt.expectedStatements) {
+			t.Fatalf("input %q: wrong number of statements. expected=%d, got=%d (%s)", tt.input, len(tt.expectedStatements), len(program.Statements), program)
+		}
+
+		for i, typ := range tt.expectedStatements {
+			if fmt.Sprintf("%T", program.Statements[i]) != typ {
+				t.Errorf("input %q: statements[%d] wrong. expected=%s, got=%T", tt.input, i, typ, program.Statements[i])
+			}
+		}
+	}
+}
+
+func TestBadNodesCoverSkippedTokens(t *testing.T) {
+	input := "let = 5 + 5; x"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+
+	bad, ok := program.Statements[0].(*ast.BadStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.BadStatement. got=%T", program.Statements[0])
+	}
+
+	if bad.Pos().Column != 1 || bad.End().Column != 13 {
+		t.Errorf("bad statement covers wrong range. got=%s-%s", bad.Pos(), bad.End())
+	}
+
+	stmt, ok := program.Statements[1].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[1] is not ast.ExpressionStatement. got=%T", program.Statements[1])
+	}
+
+	testIdentifier(t, stmt.Expression, "x")
+}
+
+func TestStringLiteralExpression(t *testing.T) {
+	input := `"hello world";`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	literal, ok := stmt.Expression.(*ast.StringLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.StringLiteral. got=%T", stmt.Expression)
+	}
+
+	if literal.Value != "hello world" {
+		t.Errorf("literal.Value not %q. got=%q", "hello world", literal.Value)
+	}
+}
+
+func TestStringExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"a" + "b" + c`, `(("a" + "b") + c)`},
+		{`add("x", "y\n")`, `add("x", "y\n")`},
+		{`-"neg"`, `(-"neg")`},
+		{`if ("a" == x) { "yes" } else { "no" }`, `if (("a" == x)) { "yes" } else { "no" }`},
+		{`fn(s) { s + "!" }("hi")`, `fn(s) { (s + "!") }("hi")`},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestLexerErrorsAreReported(t *testing.T) {
+	input := `let x = "unterminated`
+
+	l := lexer.New(input)
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) != 1 {
+		t.Fatalf("wrong number of errors. expected=1, got=%d", len(errors))
+	}
+
+	if errors[0].Code != diagnostic.UnterminatedString {
+		t.Errorf("errors[0].Code wrong. expected=%q, got=%q", diagnostic.UnterminatedString, errors[0].Code)
+	}
+}
+
+func TestInvalidUTF8ReportedOnce(t *testing.T) {
+	l := lexer.New("1 + \xff")
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) != 1 {
+		t.Fatalf("wrong number of errors. expected=1, got=%d (%v)", len(errors), errors)
+	}
+
+	if errors[0].Code != diagnostic.InvalidUTF8 {
+		t.Errorf("errors[0].Code wrong. expected=%q, got=%q", diagnostic.InvalidUTF8, errors[0].Code)
+	}
+}
+
+func TestParsingArrayLiterals(t *testing.T) {
+	input := "[1, 2 * 2, 3 + 3]"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+
+	array, ok := stmt.Expression.(*ast.ArrayLiteral)
+	if !ok {
+		t.Fatalf("exp not ast.ArrayLiteral. got=%T", stmt.Expression)
+	}
+
+	if len(array.Elements) != 3 {
+		t.Fatalf("len(array.Elements) not 3. got=%d", len(array.Elements))
+	}
+
+	testIntegerLiteral(t, array.Elements[0], 1)
+	testInfixExpression(t, array.Elements[1], 2, "*", 2)
+	testInfixExpression(t, array.Elements[2], 3, "+", 3)
+
+	if array.Pos().Column != 1 || array.End().Column != 18 {
+		t.Errorf("array covers wrong range. got=%s-%s", array.Pos(), array.End())
+	}
+}
+
+func TestParsingEmptyArrayLiteral(t *testing.T) {
+	input := "[]"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	array, ok := stmt.Expression.(*ast.ArrayLiteral)
+	if !ok {
+		t.Fatalf("exp not ast.ArrayLiteral. got=%T", stmt.Expression)
+	}
+
+	if len(array.Elements) != 0 {
+		t.Errorf("len(array.Elements) not 0. got=%d", len(array.Elements))
+	}
+}
+
+func TestParsingIndexExpressions(t *testing.T) {
+	input := "myArray[1 + 1]"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+
+	indexExp, ok := stmt.Expression.(*ast.IndexExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.IndexExpression. got=%T", stmt.Expression)
+	}
+
+	if !testIdentifier(t, indexExp.Left, "myArray") {
+		return
+	}
+
+	if !testInfixExpression(t, indexExp.Index, 1, "+", 1) {
+		return
+	}
+}
+
+func TestParsingArrayOfMixedElements(t *testing.T) {
+	input := "let a = [1, 2 * 3, fn(x){x}]; a[1]"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain 2 statements. got=%d", len(program.Statements))
+	}
+
+	if program.Statements[1].String() != "(a[1])" {
+		t.Errorf("program.Statements[1] wrong. expected=%q, got=%q", "(a[1])", program.Statements[1].String())
+	}
+}
+
+func TestParsingHashLiteralsStringKeys(t *testing.T) {
+	input := `{"one": 1, "two": 2, "three": 3}`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	hash, ok := stmt.Expression.(*ast.HashLiteral)
+	if !ok {
+		t.Fatalf("exp is not ast.HashLiteral. got=%T", stmt.Expression)
+	}
+
+	expected := []struct {
+		key   string
+		value int64
+	}{
+		{"one", 1},
+		{"two", 2},
+		{"three", 3},
+	}
+
+	if len(hash.Pairs) != len(expected) {
+		t.Fatalf("hash.Pairs has wrong length. got=%d", len(hash.Pairs))
+	}
+
+	for i, pair := range hash.Pairs {
+		literal, ok := pair.Key.(*ast.StringLiteral)
+		if !ok {
+			t.Errorf("key is not ast.StringLiteral. got=%T", pair.Key)
+			continue
+		}
+
+		if literal.Value != expected[i].key {
+			t.Errorf("hash.Pairs[%d] key wrong. expected=%q, got=%q", i, expected[i].key, literal.Value)
+		}
+
+		testIntegerLiteral(t, pair.Value, expected[i].value)
+	}
+}
+
+func TestParsingEmptyHashLiteral(t *testing.T) {
+	input := "{}"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	hash, ok := stmt.Expression.(*ast.HashLiteral)
+	if !ok {
+		t.Fatalf("exp is not ast.HashLiteral. got=%T", stmt.Expression)
+	}
+
+	if len(hash.Pairs) != 0 {
+		t.Errorf("hash.Pairs has wrong length. got=%d", len(hash.Pairs))
+	}
+}
+
+func TestParsingHashLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"one": 0 + 1, "two": 10 - 8}`, `{"one": (0 + 1), "two": (10 - 8)}`},
+		{`{"name": "monkey", 1: true, false: fn(){}}`, `{"name": "monkey", 1: true, false: fn() {  }}`},
+		{`{x + 1: [1, 2], "nested": {"a": 1}}["nested"]`, `({(x + 1): [1, 2], "nested": {"a": 1}}["nested"])`},
+		{`if (x) { {"in": "block"} }`, `if (x) { {"in": "block"} }`},
+		{`fn() { {} }`, `fn() { {} }`},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestParsingHashLiteralErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{`{"a" 1}`, "expected next token to be :, got INT instead"},
+		{`{"a": 1 "b": 2}`, "expected next token to be ,, got STRING instead"},
+		{`{"a": 1`, "expected next token to be ,, got EOF instead"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != 1 {
+			t.Fatalf("input %q: wrong number of errors. expected=1, got=%d", tt.input, len(errors))
+		}
+
+		if errors[0].Message != tt.expectedError {
+			t.Errorf("input %q: wrong error. expected=%q, got=%q", tt.input, tt.expectedError, errors[0].Message)
+		}
+	}
+}
+
+func TestIncomplete(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"1 + 2", false},
+		{"fn(x) { x }", false},
+		{"", false},
+		{"fn(x) {", true},
+		{"fn(x) {\n  if (x) {", true},
+		{"add(1,", true},
+		{"add(1", true},
+		{"(1 + 2", true},
+		{"1 +", true},
+		{"if (x) { 1 } else", true},
+		{"if (x) { 1 } else if", true},
+		{"while (x) {", true},
+		{"while (x) { break", true},
+		{"for (x in", true},
+		{"for (k, v in 0..", true},
+		{"x +=", true},
+		{"a[0] = ", true},
+		{"1 = 2", false},
+		{"if (x) { 1 } else if (y) {", true},
+		{`{"a": 1,`, true},
+		{"[1, 2", true},
+		{`"multi`, true},
+		{"1 /* not done", true},
+		{"1 // done", false},
+		{"add(1, 2;", false},
+		{"fn() { let = 1;", false},
+		{"1 + }", false},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		if p.Incomplete() != tt.expected {
+			t.Errorf("input %q: Incomplete() wrong. expected=%t, got=%t", tt.input, tt.expected, p.Incomplete())
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// Adds two numbers.
+// Both must be integers.
+add(1, 2); // three
+
+/* not attached, a blank line follows */
+
+fn(x) {
+	/* the body */
+	x * 2 // double
+}
+a; b; // after b
+// trailing groups stop at the end of their line
+c`
+
+	l := lexer.New(input)
+	l.SetMode(lexer.ScanComments)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 5 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d\n", 5, len(program.Statements))
+	}
+
+	if len(program.Comments) != 7 {
+		t.Fatalf("program.Comments does not contain %d groups. got=%d\n", 7, len(program.Comments))
+	}
+
+	add := program.Statements[0]
+	fn := program.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	body := fn.Body.Statements[0]
+
+	tests := []struct {
+		group        *ast.CommentGroup
+		expectedText string
+	}{
+		{program.CommentMap.Leading(add), "Adds two numbers.\nBoth must be integers."},
+		{program.CommentMap.Trailing(add), "three"},
+		{program.CommentMap.Leading(program.Statements[1]), ""},
+		{program.CommentMap.Leading(body), "the body"},
+		{program.CommentMap.Trailing(body), "double"},
+		{program.CommentMap.Trailing(program.Statements[2]), ""},
+		{program.CommentMap.Trailing(program.Statements[3]), "after b"},
+		{program.CommentMap.Leading(program.Statements[4]), "trailing groups stop at the end of their line"},
+	}
+
+	for i, tt := range tests {
+		text := ""
+		if tt.group != nil {
+			text = tt.group.Text()
+		}
+
+		if text != tt.expectedText {
+			t.Errorf("tests[%d] - comment wrong. expected=%q, got=%q", i, tt.expectedText, text)
+		}
+	}
+
+	if _, ok := program.CommentMap[program.Statements[1]]; ok {
+		t.Errorf("comment separated by a blank line was attached to %s", program.Statements[1])
+	}
+}
+
+func TestCommentsIgnoredByDefault(t *testing.T) {
+	program := New(lexer.New("1 + // one\n2 /* two */")).ParseProgram()
+
+	if len(program.Statements) != 1 || program.Statements[0].String() != "(1 + 2)" {
+		t.Fatalf("program wrong. got=%q", program.String())
+	}
+
+	if program.Comments != nil || program.CommentMap != nil {
+		t.Errorf("comments kept without lexer.ScanComments. got=%v", program.Comments)
+	}
+}
@@ -0,0 +1,162 @@
+/*
+* File: repl/commands.go
+*
+* Description: Contains the meta-commands of the REPL. Meta-commands start with a : and act on the session instead
+*              of being run as Monkey code
+*
+ */
+
+package repl
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/vtallen/go-interpreter/lexer"
+	"github.com/vtallen/go-interpreter/parser"
+	"github.com/vtallen/go-interpreter/token"
+)
+
+/*
+* Struct: command
+*
+* Description: A meta-command of the REPL
+ */
+type command struct {
+	name     string // Name of the command, including the :
+	args     string // Description of the arguments, shown by :help
+	help     string // One line description, shown by :help
+	run      func(s *Session, arg string, out io.Writer)
+	needsArg bool // True if the command can not be run without an argument
+}
+
+// commands is filled in by init since :help needs to list the commands
+var commands []command
+
+func init() {
+	commands = []command{
+		{name: ":tokens", args: "<code>", help: "show the tokens code is made of", run: tokensCommand, needsArg: true},
+		{name: ":ast", args: "<code>", help: "show the statements code is parsed into", run: astCommand, needsArg: true},
+		{name: ":env", help: "show the bindings of the session", run: envCommand},
+		{name: ":reset", help: "forget every binding of the session", run: resetCommand},
+		{name: ":load", args: "<file>", help: "run the program in file in the session", run: loadCommand, needsArg: true},
+		{name: ":save", args: "<file>", help: "write every input that ran without errors to file", run: saveCommand, needsArg: true},
+		{name: ":help", help: "show this help", run: helpCommand},
+	}
+}
+
+/*
+* Function: isCommand
+*
+* Parameters: line string - A line of input
+*
+* Returns: bool - True if the line is a meta-command rather than Monkey code
+ */
+func isCommand(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), ":")
+}
+
+/*
+* Function: Session.Command
+*
+* Parameters: line string    - The line of input containing the meta-command, including the :
+*             out  io.Writer - Where the output of the command is written
+*
+* Returns: none
+*
+* Description: Runs a meta-command such as :env or :load file.mk
+ */
+func (s *Session) Command(line string, out io.Writer) {
+	name, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+	arg = strings.TrimSpace(arg)
+
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+
+		if cmd.needsArg && arg == "" {
+			fmt.Fprintf(out, "usage: %s %s\n", cmd.name, cmd.args)
+			return
+		}
+
+		cmd.run(s, arg, out)
+		return
+	}
+
+	fmt.Fprintf(out, "unknown command %s, type :help for a list of commands\n", name)
+}
+
+func tokensCommand(s *Session, code string, out io.Writer) {
+	l := lexer.New(code)
+	l.SetMode(lexer.ScanComments)
+
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		fmt.Fprintf(out, "%-5s %-10s %q\n", tok.Pos, tok.Type, tok.Literal)
+	}
+
+	for _, err := range l.Errors() {
+		fmt.Fprintln(out, err.Error())
+	}
+}
+
+func astCommand(s *Session, code string, out io.Writer) {
+	l := lexer.New(code)
+	p := parser.New(l)
+
+	program := p.ParseProgram()
+	for _, stmt := range program.Statements {
+		fmt.Fprintf(out, "%T %s\n", stmt, stmt.String())
+	}
+
+	for _, err := range p.Errors() {
+		fmt.Fprintln(out, err.Error())
+	}
+}
+
+func envCommand(s *Session, arg string, out io.Writer) {
+	for _, name := range s.env.Names() {
+		val, _ := s.env.Get(name)
+		fmt.Fprintf(out, "%s = %s\n", name, val.Inspect())
+	}
+}
+
+func resetCommand(s *Session, arg string, out io.Writer) {
+	s.Reset()
+}
+
+func loadCommand(s *Session, filename string, out io.Writer) {
+	source, err := os.ReadFile(filename)
+	if err != nil {
+		fmt.Fprintf(out, "could not load %s: %s\n", filename, err)
+		return
+	}
+
+	s.Execute(filename, string(source), out)
+}
+
+// Every input is written on lines of its own, the inputs typed into the REPL do not end in a newline
+func saveCommand(s *Session, filename string, out io.Writer) {
+	var program strings.Builder
+	for _, input := range s.history {
+		program.WriteString(input)
+		if !strings.HasSuffix(input, "\n") {
+			program.WriteString("\n")
+		}
+	}
+
+	if err := os.WriteFile(filename, []byte(program.String()), 0o644); err != nil {
+		fmt.Fprintf(out, "could not save %s: %s\n", filename, err)
+		return
+	}
+
+	fmt.Fprintf(out, "saved %d inputs to %s\n", len(s.history), filename)
+}
+
+func helpCommand(s *Session, arg string, out io.Writer) {
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "%-22s %s\n", strings.TrimSpace(cmd.name+" "+cmd.args), cmd.help)
+	}
+}
@@ -10,18 +10,45 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"os"
+	"strings"
 
 	"github.com/vtallen/go-interpreter/lexer"
-	"github.com/vtallen/go-interpreter/token"
+	"github.com/vtallen/go-interpreter/parser"
 )
 
 const PROMPT = ">> "
 
+// CONTINUATION_PROMPT is shown while the input typed so far is not a complete program yet
+const CONTINUATION_PROMPT = ".. "
+
+/*
+* Function: Start
+*
+* Parameters: in  io.Reader - Where the input is read from
+*             out io.Writer - Where prompts, results, errors and anything the program prints are written
+*
+* Returns: none
+*
+* Description: Runs the REPL until in runs out of input. Prompts are only shown when in is a terminal, so piping a
+*              session into the REPL gives just the output
+*
+ */
 func Start(in io.Reader, out io.Writer) {
 	scanner := bufio.NewScanner(in)
+	session := NewSession()
+	showPrompts := isInteractive(in)
+
+	var input strings.Builder
 
 	for {
-		fmt.Printf(PROMPT)
+		if showPrompts {
+			if input.Len() == 0 {
+				fmt.Fprint(out, PROMPT)
+			} else {
+				fmt.Fprint(out, CONTINUATION_PROMPT)
+			}
+		}
 
 		scanned := scanner.Scan()
 		if !scanned {
@@ -30,10 +57,49 @@ func Start(in io.Reader, out io.Writer) {
 
 		line := scanner.Text()
 
-		l := lexer.New(line)
+		if input.Len() == 0 && isCommand(line) {
+			session.Command(line, out)
+			continue
+		}
+
+		// An empty line while waiting for more input submits what was typed so far, so there is always a way out
+		// of a statement that can not be finished
+		forceSubmit := input.Len() > 0 && strings.TrimSpace(line) == ""
+
+		input.WriteString(line)
+		input.WriteString("\n")
 
-		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
-			fmt.Printf("%+v\n", tok)
+		source := input.String()
+		if strings.TrimSpace(source) == "" {
+			input.Reset()
+			continue
 		}
+
+		l := lexer.New(source)
+		p := parser.New(l)
+
+		p.ParseProgram()
+		if p.Incomplete() && !forceSubmit {
+			continue
+		}
+
+		input.Reset()
+
+		session.Execute("", source, out)
 	}
 }
+
+// isInteractive reports whether in is a terminal a person is typing into, rather than a file or a pipe
+func isInteractive(in io.Reader) bool {
+	f, ok := in.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,188 @@
+/*
+* File: repl/repl_test.go
+*
+* Description: Replays the transcripts in testdata through the REPL and compares what it prints with the transcript.
+*              A transcript is a .repl file where lines starting with ">> " or ".. " are typed into the REPL and every
+*              other line is output expected right after the input above it. Each transcript runs in a temporary
+*              copy of testdata, so it can :load the programs there and :save files without changing testdata. Run
+*              with -update to rewrite the transcripts with the current output
+*
+ */
+
+package repl
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the transcripts in testdata with the current output")
+
+/*
+* Struct: transcriptReader
+*
+* Description: Feeds the REPL one line per Read call. Before handing out the next line, whatever the REPL wrote since
+*              the last line is copied into the transcript, so each output ends up right below the input that caused it
+ */
+type transcriptReader struct {
+	inputs     []string // The input lines, including their prompts
+	out        *bytes.Buffer
+	transcript strings.Builder
+	pending    []byte // The part of the current line that did not fit into the last Read
+}
+
+func (r *transcriptReader) Read(p []byte) (int, error) {
+	if len(r.pending) == 0 {
+		r.flush()
+
+		if len(r.inputs) == 0 {
+			return 0, io.EOF
+		}
+
+		line := r.inputs[0]
+		r.inputs = r.inputs[1:]
+
+		r.transcript.WriteString(line + "\n")
+		r.pending = []byte(line[len(PROMPT):] + "\n")
+	}
+
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+
+	return n, nil
+}
+
+func (r *transcriptReader) flush() {
+	r.transcript.Write(r.out.Bytes())
+	r.out.Reset()
+}
+
+func isInputLine(line string) bool {
+	return strings.HasPrefix(line, PROMPT) || strings.HasPrefix(line, CONTINUATION_PROMPT) ||
+		line == strings.TrimSpace(PROMPT) || line == strings.TrimSpace(CONTINUATION_PROMPT)
+}
+
+func TestTranscripts(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.repl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) == 0 {
+		t.Fatal("no transcripts found in testdata")
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range files {
+		file := filepath.Join(wd, file)
+
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			golden, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			dir := t.TempDir()
+			if err := copyPrograms(filepath.Dir(file), dir); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := os.Chdir(dir); err != nil {
+				t.Fatal(err)
+			}
+			defer os.Chdir(wd)
+
+			r := &transcriptReader{out: &bytes.Buffer{}}
+			for _, line := range strings.SplitAfter(string(golden), "\n") {
+				line = strings.TrimSuffix(line, "\n")
+				if isInputLine(line) {
+					if len(line) < len(PROMPT) {
+						// Empty inputs lose the space after the prompt in editors that strip whitespace, put it back
+						line += " "
+					}
+					r.inputs = append(r.inputs, line)
+				}
+			}
+
+			Start(r, r.out)
+			r.flush()
+
+			got := r.transcript.String()
+
+			if *update {
+				if err := os.WriteFile(file, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			if got != string(golden) {
+				t.Errorf("transcript differs (run with -update to accept the new output)\n%s", lineDiff(string(golden), got))
+			}
+		})
+	}
+}
+
+// copyPrograms copies the files in from that are not transcripts into to
+func copyPrograms(from, to string) error {
+	entries, err := os.ReadDir(from)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) == ".repl" {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(from, entry.Name()))
+		if err != nil {
+			return err
+		}
+
+		if err := os.WriteFile(filepath.Join(to, entry.Name()), data, 0o644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// lineDiff lists the lines of expected and got from the first line they differ at
+func lineDiff(expected, got string) string {
+	expectedLines := strings.Split(expected, "\n")
+	gotLines := strings.Split(got, "\n")
+
+	first := 0
+	for first < len(expectedLines) && first < len(gotLines) && expectedLines[first] == gotLines[first] {
+		first++
+	}
+
+	var out strings.Builder
+	for _, line := range expectedLines[first:] {
+		out.WriteString("- " + line + "\n")
+	}
+	for _, line := range gotLines[first:] {
+		out.WriteString("+ " + line + "\n")
+	}
+
+	return out.String()
+}
+
+func TestPromptsOnlyShownWhenInteractive(t *testing.T) {
+	var out bytes.Buffer
+	Start(strings.NewReader("1 + 1\n"), &out)
+
+	if out.String() != "2\n" {
+		t.Errorf("output wrong. expected=%q, got=%q", "2\n", out.String())
+	}
+}
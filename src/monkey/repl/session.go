@@ -0,0 +1,93 @@
+/*
+* File: repl/session.go
+*
+* Description: Contains the state the REPL keeps between inputs
+*
+ */
+
+package repl
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/vtallen/go-interpreter/diagnostic"
+	"github.com/vtallen/go-interpreter/evaluator"
+	"github.com/vtallen/go-interpreter/lexer"
+	"github.com/vtallen/go-interpreter/object"
+	"github.com/vtallen/go-interpreter/parser"
+)
+
+/*
+* Struct: Session
+*
+* Description: Everything the REPL remembers between inputs. Bindings made by let statements and function
+*              definitions stay around until the session is reset
+ */
+type Session struct {
+	env     *object.Environment
+	history []string // Inputs that ran without errors, in order. Written out by :save
+}
+
+/*
+* Function: NewSession
+*
+* Parameters: none
+*
+* Returns: *Session - Pointer to a new, empty session
+ */
+func NewSession() *Session {
+	return &Session{env: object.NewEnvironment()}
+}
+
+/*
+* Function: Session.Reset
+*
+* Parameters: none
+*
+* Returns: none
+*
+* Description: Forgets every binding and the history of the session
+ */
+func (s *Session) Reset() {
+	s.env = object.NewEnvironment()
+	s.history = nil
+}
+
+/*
+* Function: Session.Execute
+*
+* Parameters: filename string    - The name to report errors with, empty for input typed into the REPL
+*             source   string    - The program to run
+*             out      io.Writer - Where the result or errors are written
+*
+* Returns: bool - True if the program ran without errors
+*
+* Description: Parses and evaluates source in the session's environment and writes the value it evaluates to.
+*              Programs that run without errors are added to the history
+ */
+func (s *Session) Execute(filename, source string, out io.Writer) bool {
+	l := lexer.NewFile(filename, source)
+	p := parser.New(l)
+
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		diagnostic.RenderAll(out, source, p.Errors())
+		return false
+	}
+
+	s.env.SetOutput(out)
+
+	evaluated := evaluator.Eval(program, s.env)
+	if evaluated != nil {
+		fmt.Fprintf(out, "%s\n", evaluated.Inspect())
+	}
+
+	if _, ok := evaluated.(*object.Error); ok {
+		return false
+	}
+
+	s.history = append(s.history, source)
+
+	return true
+}
@@ -0,0 +1,100 @@
+package repl
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSessionKeepsBindings(t *testing.T) {
+	s := NewSession()
+
+	var out bytes.Buffer
+	s.Execute("", "let double = fn(x) { x * 2 };", &out)
+
+	if !s.Execute("", "double", &out) {
+		t.Errorf("binding not kept between inputs. got=%q", out.String())
+	}
+
+	out.Reset()
+	s.Command(":env", &out)
+
+	if !strings.HasPrefix(out.String(), "double = ") {
+		t.Errorf(":env does not list binding. got=%q", out.String())
+	}
+}
+
+func TestSessionCommands(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected string
+	}{
+		{":tokens let x", "1:1   LET        \"let\"\n1:5   IDENT      \"x\"\n"},
+		{":ast 1 + 2; x", "*ast.ExpressionStatement (1 + 2)\n*ast.ExpressionStatement x\n"},
+		{":ast 1 +", "*ast.ExpressionStatement (1 + <bad expression>)\n1:4: error[P0002]: no prefix parse functions for EOF found\n"},
+		{":tokens", "usage: :tokens <code>\n"},
+		{":load", "usage: :load <file>\n"},
+		{":nope", "unknown command :nope, type :help for a list of commands\n"},
+		{":env", ""},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		NewSession().Command(tt.line, &out)
+
+		if out.String() != tt.expected {
+			t.Errorf("line %q: output wrong. expected=%q, got=%q", tt.line, tt.expected, out.String())
+		}
+	}
+}
+
+func TestSessionHelpListsCommands(t *testing.T) {
+	var out bytes.Buffer
+	NewSession().Command(":help", &out)
+
+	for _, cmd := range commands {
+		if !strings.Contains(out.String(), cmd.name) {
+			t.Errorf(":help does not list %s. got=%q", cmd.name, out.String())
+		}
+	}
+}
+
+func TestSessionSaveLoadReset(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "session.mk")
+
+	var out bytes.Buffer
+
+	s := NewSession()
+	s.Execute("", "len([1, 2])\n", &out)
+	s.Execute("", "1 + true\n", &out) // Inputs with errors are not saved
+	s.Execute("", "\"two\"\n", &out)
+	s.Command(":save "+file, &out)
+
+	saved, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(saved) != "len([1, 2])\n\"two\"\n" {
+		t.Errorf("saved session wrong. got=%q", string(saved))
+	}
+
+	s.Command(":reset", &out)
+	if len(s.env.Names()) != 0 || len(s.history) != 0 {
+		t.Errorf("session not reset")
+	}
+
+	out.Reset()
+	s.Command(":load "+file, &out)
+	if out.String() != "two\n" {
+		t.Errorf(":load output wrong. got=%q", out.String())
+	}
+
+	out.Reset()
+	s.Command(":load "+file+".missing", &out)
+	if !strings.HasPrefix(out.String(), "could not load") {
+		t.Errorf(":load of missing file output wrong. got=%q", out.String())
+	}
+}
@@ -0,0 +1,2 @@
+let c = a + 2;
+c
\ No newline at end of file
@@ -0,0 +1,62 @@
+/*
+* File: token/position.go
+*
+* Description: Defines the source positions attached to tokens so that errors and tools can point at the exact
+*              location of a token in a source file
+*
+ */
+
+package token
+
+import "fmt"
+
+/*
+* Struct: Position
+*
+* Description: A location in a source file. Lines and columns start at 1, a Position with a Line of 0 is invalid
+*              and means the location is unknown
+ */
+type Position struct {
+	Filename string // The name of the source file, empty if the source did not come from a file
+	Offset   int    // Byte offset from the start of the source, starting at 0
+	Line     int    // Line number, starting at 1
+	Column   int    // Column number, starting at 1
+}
+
+/*
+* Function: Position.IsValid
+*
+* Parameters: none
+*
+* Returns: bool - True if the position refers to a location in a source file
+*
+* Description: Reports whether the position was set by the lexer
+ */
+func (p Position) IsValid() bool { return p.Line > 0 }
+
+/*
+* Function: Position.String
+*
+* Parameters: none
+*
+* Returns: string - The position formatted as file:line:column, line:column when there is no filename or - when
+*                   the position is invalid
+*
+* Description: Formats the position the same way compilers usually print locations, so editors can jump to it
+ */
+func (p Position) String() string {
+	s := p.Filename
+
+	if p.IsValid() {
+		if s != "" {
+			s += ":"
+		}
+		s += fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+
+	if s == "" {
+		s = "-"
+	}
+
+	return s
+}
@@ -12,15 +12,20 @@ type TokenType string
 type Token struct {
 	Type    TokenType
 	Literal string
+	Pos     Position // Position of the first character of the token
+	End     Position // Position immediately after the last character of the token
 }
 
 const (
 	ILLIGAL = "ILLEGAL" // Represents a token the parser does not know about
 	EOF     = "EOF"     // Represents the end of a file and tells the parser when to stop
+	COMMENT = "COMMENT" // A // or /* */ comment, only produced when the lexer is asked to keep comments
 
 	// Identifiers and literals
-	IDENT = "IDENT" // add, foobar, x, y, ...
-	INT   = "INT"   // literals like: 1234
+	IDENT  = "IDENT"  // add, foobar, x, y, ...
+	INT    = "INT"    // literals like: 1234
+	FLOAT  = "FLOAT"  // literals like: 3.14, 1e-9, .5
+	STRING = "STRING" // literals like: "hello world"
 
 	// Operators
 	ASSIGN   = "="
@@ -29,21 +34,46 @@ const (
 	BANG     = "!"
 	ASTERISK = "*"
 	SLASH    = "/"
+	PERCENT  = "%"
 
-	LT = "<"
-	GT = ">"
+	PLUS_ASSIGN     = "+="
+	MINUS_ASSIGN    = "-="
+	ASTERISK_ASSIGN = "*="
+	SLASH_ASSIGN    = "/="
+	PERCENT_ASSIGN  = "%="
+
+	LT    = "<"
+	GT    = ">"
+	LT_EQ = "<="
+	GT_EQ = ">="
 
 	EQ     = "=="
 	NOT_EQ = "!="
 
+	AND = "&&"
+	OR  = "||"
+
+	BIT_AND     = "&"
+	BIT_OR      = "|"
+	BIT_XOR     = "^"
+	BIT_NOT     = "~"
+	SHIFT_LEFT  = "<<"
+	SHIFT_RIGHT = ">>"
+
+	DOTDOT    = ".."
+	DOTDOT_EQ = "..="
+
 	// Delimiter characters
 	COMMA     = ","
 	SEMICOLON = ";"
+	COLON     = ":"
 
-	LPAREN = "("
-	RPAREN = ")"
-	LBRACE = "{"
-	RBRACE = "}"
+	LPAREN   = "("
+	RPAREN   = ")"
+	LBRACE   = "{"
+	RBRACE   = "}"
+	LBRACKET = "["
+	RBRACKET = "]"
 
 	// Keywords: reserved words that have meaning that are not variables
 	FUNCTION = "FUNCTION" // functions defined as fn()
@@ -53,17 +83,27 @@ const (
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	WHILE    = "WHILE"
+	BREAK    = "BREAK"
+	CONTINUE = "CONTINUE"
+	FOR      = "FOR"
+	IN       = "IN"
 )
 
 // Contains a map of reserved words for the language and their corresponding TokenType
 var keywords = map[string]TokenType{
-	"fn":     FUNCTION,
-	"let":    LET,
-	"true":   TRUE,
-	"false":  FALSE,
-	"if":     IF,
-	"else":   ELSE,
-	"return": RETURN,
+	"fn":       FUNCTION,
+	"let":      LET,
+	"true":     TRUE,
+	"false":    FALSE,
+	"if":       IF,
+	"else":     ELSE,
+	"return":   RETURN,
+	"while":    WHILE,
+	"break":    BREAK,
+	"continue": CONTINUE,
+	"for":      FOR,
+	"in":       IN,
 }
 
 /*
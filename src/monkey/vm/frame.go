@@ -0,0 +1,43 @@
+/*
+* File: vm/frame.go
+*
+* Description: Contains the call frames the virtual machine keeps for every function being executed
+*
+ */
+
+package vm
+
+import (
+	"github.com/vtallen/go-interpreter/code"
+	"github.com/vtallen/go-interpreter/object"
+)
+
+/*
+* Struct: Frame
+*
+* Description: The state of one function call. ip is the instruction being executed and basePointer is where the
+*              stack pointer was before the call, the locals of the function live right above it. loops holds the
+*              stack pointer at the start of each loop the call is in, innermost last
+ */
+type Frame struct {
+	cl          *object.Closure
+	ip          int
+	basePointer int
+	loops       []int
+}
+
+/*
+* Function: NewFrame
+*
+* Parameters: cl          *object.Closure - The closure being called
+*             basePointer int             - The stack pointer at the time of the call
+*
+* Returns: *Frame - Pointer to a new frame, positioned before the first instruction
+ */
+func NewFrame(cl *object.Closure, basePointer int) *Frame {
+	return &Frame{cl: cl, ip: -1, basePointer: basePointer}
+}
+
+func (f *Frame) Instructions() code.Instructions {
+	return f.cl.Fn.Instructions
+}
@@ -0,0 +1,879 @@
+/*
+* File: vm/vm.go
+*
+* Description: Contains the stack based virtual machine that runs the bytecode produced by the compiler
+*
+ */
+
+package vm
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/vtallen/go-interpreter/code"
+	"github.com/vtallen/go-interpreter/compiler"
+	"github.com/vtallen/go-interpreter/object"
+)
+
+const GlobalsSize = 65536
+
+// MaxFrames is how deeply function calls can be nested, not counting the frame of the main program. It is the same
+// as evaluator.MaxCallDepth, so both engines stop at the same depth
+const MaxFrames = 1024
+
+// FrameSlots is how much of the stack a frame is given room for, its locals and the values it is working on. The
+// stack holds every frame, so deep recursion runs out of frames before it runs out of stack
+const FrameSlots = 64
+const StackSize = (MaxFrames + 1) * FrameSlots
+
+// Booleans and null are shared with the evaluator, so they can be compared by pointer
+var True = object.TRUE
+var False = object.FALSE
+var Null = object.NULL
+
+/*
+* Struct: VM
+*
+* Description: Runs bytecode. sp always points to the next free slot of the stack, so the value on top of the
+*              stack is stack[sp-1]
+ */
+type VM struct {
+	constants []object.Object
+
+	stack []object.Object
+	sp    int
+
+	globals     []object.Object
+	globalNames []string // The names of the globals, indexed by slot, for error messages
+
+	frames      []*Frame
+	framesIndex int
+
+	openCells []openCell // The cells still referring to a slot of the stack, those of the innermost call last
+
+	output io.Writer // Where builtin functions such as puts write to
+}
+
+// openCell is a cell captured from the local in stack[slot], while the call owning that local is running
+type openCell struct {
+	slot int
+	cell *object.Cell
+}
+
+/*
+* Function: New
+*
+* Parameters: bytecode *compiler.Bytecode - The program to run
+*
+* Returns: *VM - Pointer to a new virtual machine, ready to run the program
+ */
+func New(bytecode *compiler.Bytecode) *VM {
+	mainFn := &object.CompiledFunction{Instructions: bytecode.Instructions}
+	mainClosure := &object.Closure{Fn: mainFn}
+	mainFrame := NewFrame(mainClosure, 0)
+
+	frames := make([]*Frame, MaxFrames+1)
+	frames[0] = mainFrame
+
+	return &VM{
+		constants: bytecode.Constants,
+
+		stack: make([]object.Object, StackSize),
+		sp:    0,
+
+		globals:     make([]object.Object, GlobalsSize),
+		globalNames: bytecode.GlobalNames,
+
+		frames:      frames,
+		framesIndex: 1,
+	}
+}
+
+/*
+* Function: NewWithGlobalsState
+*
+* Parameters: bytecode *compiler.Bytecode - The program to run
+*             s        []object.Object    - The globals to use, of length GlobalsSize
+*
+* Returns: *VM - Pointer to a new virtual machine
+*
+* Description: Creates a virtual machine that shares its globals with the caller, used together with
+*              compiler.NewWithState to run several programs one after another
+ */
+func NewWithGlobalsState(bytecode *compiler.Bytecode, s []object.Object) *VM {
+	vm := New(bytecode)
+	vm.globals = s
+	return vm
+}
+
+/*
+* Function: VM.SetOutput
+*
+* Parameters: w io.Writer - Where the program should print to
+*
+* Returns: none
+ */
+func (vm *VM) SetOutput(w io.Writer) {
+	vm.output = w
+}
+
+func (vm *VM) currentFrame() *Frame {
+	return vm.frames[vm.framesIndex-1]
+}
+
+func (vm *VM) pushFrame(f *Frame) {
+	vm.frames[vm.framesIndex] = f
+	vm.framesIndex++
+}
+
+func (vm *VM) popFrame() *Frame {
+	vm.framesIndex--
+	return vm.frames[vm.framesIndex]
+}
+
+/*
+* Function: VM.LastPoppedStackElem
+*
+* Parameters: none
+*
+* Returns: object.Object - The value most recently popped off the stack, which is the value of the last
+*                          expression statement of the program once Run has returned. nil if the program ended
+*                          with a let statement, which has no value
+ */
+func (vm *VM) LastPoppedStackElem() object.Object {
+	return vm.stack[vm.sp]
+}
+
+/*
+* Function: VM.Run
+*
+* Parameters: none
+*
+* Returns: error - Non nil if the program stopped with a runtime error
+*
+* Description: Runs the program until it reaches the end of its instructions or returns at the top level
+ */
+func (vm *VM) Run() error {
+	var ip int
+	var ins code.Instructions
+	var op code.Opcode
+
+	for vm.currentFrame().ip < len(vm.currentFrame().Instructions())-1 {
+		vm.currentFrame().ip++
+
+		ip = vm.currentFrame().ip
+		ins = vm.currentFrame().Instructions()
+		op = code.Opcode(ins[ip])
+
+		switch op {
+		case code.OpConstant:
+			constIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+
+			err := vm.push(vm.constants[constIndex])
+			if err != nil {
+				return err
+			}
+
+		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv, code.OpMod, code.OpEqual, code.OpNotEqual,
+			code.OpGreaterThan, code.OpLessThan, code.OpGreaterEqual, code.OpLessEqual,
+			code.OpBitAnd, code.OpBitOr, code.OpBitXor, code.OpShiftLeft, code.OpShiftRight,
+			code.OpRange, code.OpRangeInclusive:
+			err := vm.executeBinaryOperation(op)
+			if err != nil {
+				return err
+			}
+
+		case code.OpBang:
+			err := vm.executeBangOperator()
+			if err != nil {
+				return err
+			}
+
+		case code.OpMinus:
+			err := vm.executeMinusOperator()
+			if err != nil {
+				return err
+			}
+
+		case code.OpBitNot:
+			err := vm.executeBitwiseNotOperator()
+			if err != nil {
+				return err
+			}
+
+		case code.OpPop:
+			vm.pop()
+
+		case code.OpTrue:
+			err := vm.push(True)
+			if err != nil {
+				return err
+			}
+
+		case code.OpFalse:
+			err := vm.push(False)
+			if err != nil {
+				return err
+			}
+
+		case code.OpNull:
+			err := vm.push(Null)
+			if err != nil {
+				return err
+			}
+
+		case code.OpJump:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip = pos - 1
+
+		case code.OpJumpNotTruthy:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			condition := vm.pop()
+			if !isTruthy(condition) {
+				vm.currentFrame().ip = pos - 1
+			}
+
+		case code.OpJumpNotTruthyOrPop, code.OpJumpTruthyOrPop:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			// The operand that decides an && or || stays on the stack as the value of the whole expression
+			if isTruthy(vm.stack[vm.sp-1]) == (op == code.OpJumpTruthyOrPop) {
+				vm.currentFrame().ip = pos - 1
+			} else {
+				vm.pop()
+			}
+
+		case code.OpIter:
+			it, err := object.NewIterator(vm.pop())
+			if err != nil {
+				return err
+			}
+
+			err = vm.push(it)
+			if err != nil {
+				return err
+			}
+
+		case code.OpIterNext:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			count := int(code.ReadUint8(ins[ip+3:]))
+			vm.currentFrame().ip += 3
+
+			// The iterator stays on the stack for the whole loop, the instruction at pos pops it
+			first, second, ok := vm.stack[vm.sp-1].(*object.Iterator).Next(count)
+			if !ok {
+				vm.currentFrame().ip = pos - 1
+				break
+			}
+
+			err := vm.push(first)
+			if err == nil && count == 2 {
+				err = vm.push(second)
+			}
+			if err != nil {
+				return err
+			}
+
+		case code.OpLoop:
+			level := int(code.ReadUint8(ins[ip+1:]))
+			frame := vm.currentFrame()
+			frame.ip += 1
+
+			frame.loops = append(frame.loops[:level], vm.sp)
+
+		case code.OpLoopJump:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			level := int(code.ReadUint8(ins[ip+3:]))
+
+			// A break or continue can appear in the middle of an expression, whose operands are still on the stack
+			frame := vm.currentFrame()
+			vm.sp = frame.loops[level]
+			frame.ip = pos - 1
+
+		case code.OpSetGlobal:
+			globalIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+
+			vm.globals[globalIndex] = vm.pop()
+			vm.forgetPopped()
+
+		case code.OpGetGlobal:
+			globalIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+
+			global := vm.globals[globalIndex]
+			if global == nil {
+				return unboundError(vm.globalNames, int(globalIndex))
+			}
+
+			err := vm.push(global)
+			if err != nil {
+				return err
+			}
+
+		case code.OpSetLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			frame := vm.currentFrame()
+			vm.stack[frame.basePointer+int(localIndex)] = vm.pop()
+			vm.forgetPopped()
+
+		case code.OpGetLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			frame := vm.currentFrame()
+			local := vm.stack[frame.basePointer+int(localIndex)]
+			if local == nil {
+				return unboundError(frame.cl.Fn.LocalNames, int(localIndex))
+			}
+
+			err := vm.push(local)
+			if err != nil {
+				return err
+			}
+
+		case code.OpGetBuiltin:
+			builtinIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			err := vm.push(object.Builtins[builtinIndex].Builtin)
+			if err != nil {
+				return err
+			}
+
+		case code.OpGetFree:
+			freeIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			cl := vm.currentFrame().cl
+			free := cl.Free[freeIndex].Get()
+			if free == nil {
+				return unboundError(cl.Fn.FreeNames, int(freeIndex))
+			}
+
+			err := vm.push(free)
+			if err != nil {
+				return err
+			}
+
+		case code.OpAssignGlobal:
+			globalIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+
+			if vm.globals[globalIndex] == nil {
+				return unboundError(vm.globalNames, int(globalIndex))
+			}
+			vm.globals[globalIndex] = vm.stack[vm.sp-1]
+
+		case code.OpAssignLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			frame := vm.currentFrame()
+			slot := frame.basePointer + int(localIndex)
+			if vm.stack[slot] == nil {
+				return unboundError(frame.cl.Fn.LocalNames, int(localIndex))
+			}
+			vm.stack[slot] = vm.stack[vm.sp-1]
+
+		case code.OpAssignFree:
+			freeIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			cl := vm.currentFrame().cl
+			if cl.Free[freeIndex].Get() == nil {
+				return unboundError(cl.Fn.FreeNames, int(freeIndex))
+			}
+			cl.Free[freeIndex].Set(vm.stack[vm.sp-1])
+
+		case code.OpAssignBuiltin:
+			builtinIndex := code.ReadUint8(ins[ip+1:])
+
+			return fmt.Errorf("cannot assign to builtin: %s", object.Builtins[builtinIndex].Name)
+
+		case code.OpCaptureLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			err := vm.push(vm.captureLocal(vm.currentFrame().basePointer + int(localIndex)))
+			if err != nil {
+				return err
+			}
+
+		case code.OpCaptureFree:
+			freeIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			err := vm.push(vm.currentFrame().cl.Free[freeIndex])
+			if err != nil {
+				return err
+			}
+
+		case code.OpArray:
+			numElements := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			array := vm.buildArray(vm.sp-numElements, vm.sp)
+			vm.sp = vm.sp - numElements
+
+			err := vm.push(array)
+			if err != nil {
+				return err
+			}
+
+		case code.OpHash:
+			numElements := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			hash, err := vm.buildHash(vm.sp-numElements, vm.sp)
+			if err != nil {
+				return err
+			}
+			vm.sp = vm.sp - numElements
+
+			err = vm.push(hash)
+			if err != nil {
+				return err
+			}
+
+		case code.OpHashKey:
+			key := vm.stack[vm.sp-1]
+			if _, ok := key.(object.Hashable); !ok {
+				return fmt.Errorf("unusable as hash key: %s", key.Type())
+			}
+
+		case code.OpIndex:
+			index := vm.pop()
+			left := vm.pop()
+
+			err := vm.executeIndexExpression(left, index)
+			if err != nil {
+				return err
+			}
+
+		case code.OpSetIndex:
+			value := vm.pop()
+			index := vm.pop()
+			container := vm.pop()
+
+			err := object.SetIndex(container, index, value)
+			if err == nil {
+				err = vm.push(value)
+			}
+			if err != nil {
+				return err
+			}
+
+		case code.OpDupTwo:
+			err := vm.push(vm.stack[vm.sp-2])
+			if err == nil {
+				err = vm.push(vm.stack[vm.sp-2])
+			}
+			if err != nil {
+				return err
+			}
+
+		case code.OpCall:
+			numArgs := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			err := vm.executeCall(int(numArgs))
+			if err != nil {
+				return err
+			}
+
+		case code.OpReturnValue:
+			returnValue := vm.pop()
+
+			// A return at the top level stops the program, the value is left where LastPoppedStackElem finds it
+			if vm.framesIndex == 1 {
+				return nil
+			}
+
+			frame := vm.popFrame()
+			vm.closeCells(frame.basePointer)
+			vm.sp = frame.basePointer - 1
+
+			err := vm.push(returnValue)
+			if err != nil {
+				return err
+			}
+
+		case code.OpReturn:
+			frame := vm.popFrame()
+			vm.closeCells(frame.basePointer)
+			vm.sp = frame.basePointer - 1
+
+			err := vm.push(Null)
+			if err != nil {
+				return err
+			}
+
+		case code.OpClosure:
+			constIndex := code.ReadUint16(ins[ip+1:])
+			numFree := code.ReadUint8(ins[ip+3:])
+			vm.currentFrame().ip += 3
+
+			err := vm.pushClosure(int(constIndex), int(numFree))
+			if err != nil {
+				return err
+			}
+
+		default:
+			def, err := code.Lookup(byte(op))
+			if err != nil {
+				return err
+			}
+			return fmt.Errorf("opcode %s not implemented", def.Name)
+		}
+	}
+
+	return nil
+}
+
+func (vm *VM) push(o object.Object) error {
+	if vm.sp >= StackSize {
+		return fmt.Errorf("stack overflow")
+	}
+
+	vm.stack[vm.sp] = o
+	vm.sp++
+
+	return nil
+}
+
+func (vm *VM) pop() object.Object {
+	o := vm.stack[vm.sp-1]
+	vm.sp--
+	return o
+}
+
+// forgetPopped clears the slot of the value just popped, so a value that was bound by a let statement is not
+// mistaken for the value of the program by LastPoppedStackElem
+func (vm *VM) forgetPopped() {
+	vm.stack[vm.sp] = nil
+}
+
+// unboundError reports reading a binding whose let statement never ran, the evaluator reports it the same way
+func unboundError(names []string, index int) error {
+	if index < len(names) {
+		return fmt.Errorf("identifier not found: %s", names[index])
+	}
+	return fmt.Errorf("identifier not found")
+}
+
+// The operators the binary opcodes stand for, used in error messages
+var binaryOperators = map[code.Opcode]string{
+	code.OpAdd:            "+",
+	code.OpSub:            "-",
+	code.OpMul:            "*",
+	code.OpDiv:            "/",
+	code.OpMod:            "%",
+	code.OpEqual:          "==",
+	code.OpNotEqual:       "!=",
+	code.OpGreaterThan:    ">",
+	code.OpLessThan:       "<",
+	code.OpGreaterEqual:   ">=",
+	code.OpLessEqual:      "<=",
+	code.OpBitAnd:         "&",
+	code.OpBitOr:          "|",
+	code.OpBitXor:         "^",
+	code.OpShiftLeft:      "<<",
+	code.OpShiftRight:     ">>",
+	code.OpRange:          "..",
+	code.OpRangeInclusive: "..=",
+}
+
+/*
+* Function: VM.executeBinaryOperation
+*
+* Parameters: op code.Opcode - The operator to apply to the two values on top of the stack
+*
+* Returns: error - Non nil if the operator can not be applied to the values
+*
+* Description: Pops both operands and pushes the result. Follows the same rules as the evaluator so programs
+*              behave the same whichever engine runs them
+ */
+func (vm *VM) executeBinaryOperation(op code.Opcode) error {
+	right := vm.pop()
+	left := vm.pop()
+
+	leftType := left.Type()
+	rightType := right.Type()
+
+	switch {
+	case op == code.OpRange || op == code.OpRangeInclusive:
+		result, err := object.NewRange(binaryOperators[op], left, right)
+		if err != nil {
+			return err
+		}
+		return vm.push(result)
+	case object.IsNumber(left) && object.IsNumber(right):
+		result, err := object.NumericOperation(binaryOperators[op], left, right)
+		if err != nil {
+			return err
+		}
+		return vm.push(result)
+	case leftType == object.STRING_OBJ && rightType == object.STRING_OBJ:
+		return vm.executeBinaryStringOperation(op, left, right)
+	// Booleans and null are singletons, so comparing the pointers is enough
+	case op == code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(left == right))
+	case op == code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObject(left != right))
+	case leftType != rightType:
+		return fmt.Errorf("type mismatch: %s %s %s", leftType, binaryOperators[op], rightType)
+	default:
+		return fmt.Errorf("unknown operator: %s %s %s", leftType, binaryOperators[op], rightType)
+	}
+}
+
+func (vm *VM) executeBinaryStringOperation(op code.Opcode, left, right object.Object) error {
+	leftValue := left.(*object.String).Value
+	rightValue := right.(*object.String).Value
+
+	switch op {
+	case code.OpAdd:
+		return vm.push(&object.String{Value: leftValue + rightValue})
+	case code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(leftValue == rightValue))
+	case code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObject(leftValue != rightValue))
+	default:
+		return fmt.Errorf("unknown operator: %s %s %s", left.Type(), binaryOperators[op], right.Type())
+	}
+}
+
+func (vm *VM) executeBangOperator() error {
+	operand := vm.pop()
+
+	switch operand {
+	case True:
+		return vm.push(False)
+	case False:
+		return vm.push(True)
+	case Null:
+		return vm.push(True)
+	default:
+		return vm.push(False)
+	}
+}
+
+func (vm *VM) executeMinusOperator() error {
+	operand := vm.pop()
+
+	result, err := object.NegateNumber(operand)
+	if err != nil {
+		return err
+	}
+
+	return vm.push(result)
+}
+
+func (vm *VM) executeBitwiseNotOperator() error {
+	operand := vm.pop()
+
+	result, err := object.BitwiseNot(operand)
+	if err != nil {
+		return err
+	}
+
+	return vm.push(result)
+}
+
+func (vm *VM) buildArray(startIndex, endIndex int) object.Object {
+	elements := make([]object.Object, endIndex-startIndex)
+
+	for i := startIndex; i < endIndex; i++ {
+		elements[i-startIndex] = vm.stack[i]
+	}
+
+	return &object.Array{Elements: elements}
+}
+
+// buildHash builds a hash from the keys and values between startIndex and endIndex, which alternate
+func (vm *VM) buildHash(startIndex, endIndex int) (object.Object, error) {
+	hash := object.NewHash()
+
+	for i := startIndex; i < endIndex; i += 2 {
+		key := vm.stack[i]
+		value := vm.stack[i+1]
+
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return nil, fmt.Errorf("unusable as hash key: %s", key.Type())
+		}
+
+		hash.Set(hashKey, value)
+	}
+
+	return hash, nil
+}
+
+func (vm *VM) executeIndexExpression(left, index object.Object) error {
+	switch {
+	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
+		return vm.executeArrayIndex(left, index)
+	case left.Type() == object.HASH_OBJ:
+		return vm.executeHashIndex(left, index)
+	default:
+		return fmt.Errorf("index operator not supported: %s", left.Type())
+	}
+}
+
+// Indexing past either end of an array gives null instead of an error. A BigInt index is always past the end
+func (vm *VM) executeArrayIndex(array, index object.Object) error {
+	arrayObject := array.(*object.Array)
+	max := int64(len(arrayObject.Elements) - 1)
+
+	integer, ok := index.(*object.Integer)
+	if !ok || integer.Value < 0 || integer.Value > max {
+		return vm.push(Null)
+	}
+
+	return vm.push(arrayObject.Elements[integer.Value])
+}
+
+func (vm *VM) executeHashIndex(hash, index object.Object) error {
+	hashObject := hash.(*object.Hash)
+
+	key, ok := index.(object.Hashable)
+	if !ok {
+		return fmt.Errorf("unusable as hash key: %s", index.Type())
+	}
+
+	pair, ok := hashObject.Pairs[key.HashKey()]
+	if !ok {
+		return vm.push(Null)
+	}
+
+	return vm.push(pair.Value)
+}
+
+/*
+* Function: VM.executeCall
+*
+* Parameters: numArgs int - The number of arguments on top of the stack, the function is right below them
+*
+* Returns: error - Non nil if the value being called is not a function or the call fails
+ */
+func (vm *VM) executeCall(numArgs int) error {
+	callee := vm.stack[vm.sp-1-numArgs]
+
+	switch callee := callee.(type) {
+	case *object.Closure:
+		return vm.callClosure(callee, numArgs)
+	case *object.Builtin:
+		return vm.callBuiltin(callee, numArgs)
+	default:
+		return fmt.Errorf("not a function: %s", callee.Type())
+	}
+}
+
+// callClosure pushes a frame for cl, its arguments become the first locals of the frame
+func (vm *VM) callClosure(cl *object.Closure, numArgs int) error {
+	if numArgs != cl.Fn.NumParameters {
+		return fmt.Errorf("wrong number of arguments: want=%d, got=%d", cl.Fn.NumParameters, numArgs)
+	}
+
+	if vm.framesIndex > MaxFrames || vm.sp-numArgs+cl.Fn.NumLocals >= StackSize {
+		return fmt.Errorf("stack overflow")
+	}
+
+	frame := NewFrame(cl, vm.sp-numArgs)
+	vm.pushFrame(frame)
+
+	vm.sp = frame.basePointer + cl.Fn.NumLocals
+
+	// Locals whose let statement has not run yet must not see what an earlier call left in their slots
+	for i := vm.sp - cl.Fn.NumLocals + numArgs; i < vm.sp; i++ {
+		vm.stack[i] = nil
+	}
+
+	return nil
+}
+
+func (vm *VM) callBuiltin(builtin *object.Builtin, numArgs int) error {
+	args := vm.stack[vm.sp-numArgs : vm.sp]
+
+	out := vm.output
+	if out == nil {
+		out = os.Stdout
+	}
+
+	result := builtin.Fn(out, args...)
+	vm.sp = vm.sp - numArgs - 1
+
+	if err, ok := result.(*object.Error); ok {
+		return fmt.Errorf("%s", err.Message)
+	}
+
+	return vm.push(result)
+}
+
+// pushClosure wraps the compiled function at constIndex in a closure, capturing the numFree cells on the stack
+func (vm *VM) pushClosure(constIndex int, numFree int) error {
+	constant := vm.constants[constIndex]
+	function, ok := constant.(*object.CompiledFunction)
+	if !ok {
+		return fmt.Errorf("not a function: %+v", constant)
+	}
+
+	free := make([]*object.Cell, numFree)
+	for i := 0; i < numFree; i++ {
+		free[i] = vm.stack[vm.sp-numFree+i].(*object.Cell)
+	}
+	vm.sp = vm.sp - numFree
+
+	closure := &object.Closure{Fn: function, Free: free}
+	return vm.push(closure)
+}
+
+// captureLocal returns the cell for stack[slot], a local of the current call. Closures that capture the same local
+// share one cell, so they all see what the others assign to it
+func (vm *VM) captureLocal(slot int) *object.Cell {
+	for i := len(vm.openCells) - 1; i >= 0 && vm.openCells[i].slot >= vm.currentFrame().basePointer; i-- {
+		if vm.openCells[i].slot == slot {
+			return vm.openCells[i].cell
+		}
+	}
+
+	cell := object.NewCell(&vm.stack[slot])
+	vm.openCells = append(vm.openCells, openCell{slot: slot, cell: cell})
+	return cell
+}
+
+// closeCells closes the cells of the slots from base up, which belong to a call that is returning. Only the call on
+// top can capture its locals, so those cells are the last ones opened
+func (vm *VM) closeCells(base int) {
+	i := len(vm.openCells)
+	for i > 0 && vm.openCells[i-1].slot >= base {
+		i--
+		vm.openCells[i].cell.Close()
+	}
+	vm.openCells = vm.openCells[:i]
+}
+
+func nativeBoolToBooleanObject(input bool) *object.Boolean {
+	return object.NativeBoolToBooleanObject(input)
+}
+
+// null and false are falsy, everything else is truthy
+func isTruthy(obj object.Object) bool {
+	switch obj := obj.(type) {
+	case *object.Boolean:
+		return obj.Value
+	case *object.Null:
+		return false
+	default:
+		return true
+	}
+}
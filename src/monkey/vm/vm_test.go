@@ -0,0 +1,541 @@
+package vm
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/vtallen/go-interpreter/ast"
+	"github.com/vtallen/go-interpreter/compiler"
+	"github.com/vtallen/go-interpreter/lexer"
+	"github.com/vtallen/go-interpreter/object"
+	"github.com/vtallen/go-interpreter/parser"
+)
+
+type vmTestCase struct {
+	input    string
+	expected interface{}
+}
+
+func parse(input string) *ast.Program {
+	l := lexer.New(input)
+	p := parser.New(l)
+	return p.ParseProgram()
+}
+
+func TestIntegerArithmetic(t *testing.T) {
+	tests := []vmTestCase{
+		{"1", 1},
+		{"1 + 2", 3},
+		{"4 / 2", 2},
+		{"50 / 2 * 2 + 10 - 5", 55},
+		{"5 * (2 + 10)", 60},
+		{"-5", -5},
+		{"-50 + 100 + -50", 0},
+		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"7 % 3", 1},
+		{"-7 % 3", -1},
+		{"2 + 7 % 4 * 2", 8},
+		{"12 & 10", 8},
+		{"12 | 10", 14},
+		{"12 ^ 10", 6},
+		{"~5", -6},
+		{"1 << 4", 16},
+		{"-16 >> 2", -4},
+		{"1 | 2 ^ 3 & 4 << 1", 3},
+		{"~0 & 0xff", 255},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestFloatArithmetic(t *testing.T) {
+	tests := []vmTestCase{
+		{"1.5 + 1", 2.5},
+		{"7 / 2.0", 3.5},
+		{"-.25 * 4", -1.0},
+		{"1 == 1.0", true},
+		{"0.5 < 1", true},
+		{"2.5 > 3", false},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestBigIntegerArithmetic(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // The Inspect of the result
+	}{
+		{"9223372036854775807 + 1", "9223372036854775808"},
+		{"9223372036854775808 - 1", "9223372036854775807"},
+		{"-9223372036854775808 * -1", "9223372036854775808"},
+		{"123456789012345678901234567890 / 10", "12345678901234567890123456789"},
+		{"[1][99999999999999999999]", "null"},
+		{"1 << 64", "18446744073709551616"},
+		{"~(1 << 64) >> 60", "-17"},
+	}
+
+	for _, tt := range tests {
+		comp := compiler.New()
+		if err := comp.Compile(parse(tt.input)); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		vm := New(comp.Bytecode())
+		if err := vm.Run(); err != nil {
+			t.Fatalf("vm error: %s", err)
+		}
+
+		if result := vm.LastPoppedStackElem().Inspect(); result != tt.expected {
+			t.Errorf("%q: wrong result. expected=%q, got=%q", tt.input, tt.expected, result)
+		}
+	}
+}
+
+func TestBooleanExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{"true", true},
+		{"1 < 2", true},
+		{"1 > 2", false},
+		{"1 == 1", true},
+		{"1 != 2", true},
+		{"true == false", false},
+		{"(1 < 2) == true", true},
+		{`"a" == "a"`, true},
+		{`"a" != "a"`, false},
+		{"1 == true", false},
+		{"!true", false},
+		{"!!5", true},
+		{"!(if (false) { 5; })", true},
+		{"1 <= 2", true},
+		{"2 <= 2", true},
+		{"3 <= 2", false},
+		{"1 >= 2", false},
+		{"2 >= 2", true},
+		{"1.5 >= 1", true},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestLogicalExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{"true && true", true},
+		{"true && false", false},
+		{"false && true", false},
+		{"false || true", true},
+		{"false || false", false},
+		{"1 < 2 && 2 < 3", true},
+		{"1 && 2", 2},
+		{"0 || 5", 0},
+		{"false || 5", 5},
+		{"if (false) { 1 } && 7", Null},
+		{"false || true && false", false},
+		// The right operand is not evaluated when the left one decides the result
+		{"false && 1 / 0", false},
+		{"true || 1 / 0", true},
+		{"[false || 1, true && 2, 3]", []int{1, 2, 3}},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestConditionals(t *testing.T) {
+	tests := []vmTestCase{
+		{"if (true) { 10 }", 10},
+		{"if (1) { 10 }", 10},
+		{"if (1 < 2) { 10 } else { 20 }", 10},
+		{"if (1 > 2) { 10 } else { 20 }", 20},
+		{"if (1 > 2) { 10 }", Null},
+		{"if ((if (false) { 10 })) { 10 } else { 20 }", 20},
+		{"if (1 > 2) { 10 } else if (2 > 1) { 20 } else { 30 }", 20},
+		{"if (1 > 2) { 10 } else if (2 > 3) { 20 } else { 30 }", 30},
+		{"if (1 > 2) { 10 } else if (2 > 3) { 20 }", Null},
+		{"if (false) { 1 } else if (false) { 2 } else if (true) { 3 } else { 4 }", 3},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestStringArrayAndHashExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{`"mon" + "key" + "banana"`, "monkeybanana"},
+		{"[1 + 2, 3 * 4, 5 + 6]", []int{3, 12, 11}},
+		{"[]", []int{}},
+		{"[1, 2, 3][1]", 2},
+		{"[[1, 1, 1]][0][0]", 1},
+		{"[1, 2, 3][99]", Null},
+		{"[1][-1]", Null},
+		{"{1: 1, 2: 2}[1]", 1},
+		{"{1: 1}[0]", Null},
+		{"{}[0]", Null},
+		{`{"a": 1, "b": 2}["b"]`, 2},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestCallingFunctions(t *testing.T) {
+	tests := []vmTestCase{
+		{"fn() { 5 + 10 }()", 15},
+		{"fn() { }()", Null},
+		{"fn(a, b) { a + b }(1, 2)", 3},
+		{"fn(a) { fn(b) { a + b } }(1)(2)", 3},
+		{"fn(a) { fn(b) { fn(c) { a + b + c } } }(1)(2)(3)", 6},
+		// Recursion without let, the function is passed itself
+		{"fn(fib) { fib(fib, 15) }(fn(self, n) { if (n < 2) { n } else { self(self, n - 1) + self(self, n - 2) } })", 610},
+		{`len("four")`, 4},
+		{"len([1, 2, 3])", 3},
+		{"len({1: 2})", 1},
+		// Recursion through the binding the function is assigned to, global and local
+		{"let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } }; fib(15)", 610},
+		{"fn() { let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } }; fib(15) }()", 610},
+		// A function can call one defined after it, as long as it is called after that
+		{"let f = fn() { g() }; let g = fn() { 7 }; f()", 7},
+		// The same for closures using a local that the function around them binds later
+		{"let f = fn() { let g = fn() { y }; let y = 5; g() }; f()", 5},
+		{"fn() { let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } }; let odd = fn(n) { if (n == 0) { false } else { even(n - 1) } }; even(10) }()", true},
+		{"fn() { let g = fn() { y = 3 }; let y = 1; g(); y }()", 3},
+		{"fn() { let g = fn() { fn() { z } }; for (z in [4]) { }; g()() }()", 4},
+		// Every call gets its own cells, closures made by earlier calls keep the values those calls left
+		{"let make = fn(x) { fn() { x } }; let a = make(1); let b = make(2); [a(), b()][0] * 10 + b()", 12},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestLetStatements(t *testing.T) {
+	tests := []vmTestCase{
+		{"let one = 1; one", 1},
+		{"let one = 1; let two = 2; one + two", 3},
+		{"let one = 1; let two = one + one; one + two", 3},
+		{"let one = 1\nlet two = one + 1\ntwo", 2},
+		{"let x = 1; let x = x + 1; x", 2},
+		{"let f = fn(a) { let b = a * 2; b + 1 }; f(5)", 11},
+		{"let countDown = fn(n) { if (n == 0) { 0 } else { countDown(n - 1) } }; countDown(10)", 0},
+		{"let newAdder = fn(a) { fn(b) { a + b } }; let addTwo = newAdder(2); addTwo(3)", 5},
+		{"let fib = fn(n) { if (n < 2) { return n; } fib(n - 1) + fib(n - 2) }; fib(15)", 610},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestReturnStatements(t *testing.T) {
+	tests := []vmTestCase{
+		{"fn() { return 10; 9 }()", 10},
+		{"fn() { if (true) { return 10 } 9 }()", 10},
+		{"fn() { if (true) { if (true) { return 10; } return 1; } }()", 10},
+		{"fn() { return; 9 }()", Null},
+		{"fn() { return }()", Null},
+		{"let f = fn(x) { if (x > 0) { return x } return; }; f(1)", 1},
+		{"let f = fn(x) { if (x > 0) { return x } return; }; f(-1)", Null},
+		// A return at the top level ends the program
+		{"1; return 2; 3", 2},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestWhileStatements(t *testing.T) {
+	tests := []vmTestCase{
+		{"let i = 0; while (i < 10) { let i = i + 1 }; i", 10},
+		{"let i = 0; while (false) { let i = i + 1 }; i", 0},
+		{"let i = 0; while (true) { let i = i + 1; if (i == 5) { break } }; i", 5},
+		{"let i = 0; let sum = 0; while (i < 10) { let i = i + 1; if (i % 2 == 0) { continue; } let sum = sum + i }; sum", 25},
+		{"let i = 0; let n = 0; while (i < 3) { let i = i + 1; let j = 0; while (true) { let j = j + 1; let n = n + 1; if (j == 2) { break } } }; n", 6},
+		{"fn() { let i = 0; while (true) { let i = i + 1; if (i > 3) { return i; } } }()", 4},
+		{"fn() { while (true) { break } 7 }()", 7},
+		{"fn() { let i = 0; while (i < 3) { let i = i + 1 } }()", Null},
+		// Many more iterations than the stack has room for, so the body must not leave anything behind
+		{"let i = 0; while (i < 10000) { let i = i + 1; if (i % 2 == 0) { continue } i }; i", 10000},
+		// break and continue in the middle of an expression drop the operands already pushed
+		{"let i = 0; while (true) { i += 1; let x = if (i > 3) { break } else { 1 } }; i", 4},
+		{"let i = 0; while (true) { i += (if (i == 2) { break } else { 1 }) }; i", 2},
+		{"let i = 0; let n = 0; while (i < 5) { i += 1; n += [if (i > 2) { continue } else { i }][0] }; n", 3},
+		{"let i = 0; while (i < 5000) { i += 1; [1, 2, if (true) { continue }] }; i", 5000},
+		{"let i = 0; while (if (i < 3) { true } else { false }) { i += 1; while ([false, if (i == 2) { break }][0]) { } }; i", 2},
+		{"fn() { let x = if (true) { return 5 }; 10 }()", 5},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestForInStatements(t *testing.T) {
+	tests := []vmTestCase{
+		{"let sum = 0; for (x in [1, 2, 3]) { let sum = sum + x }; sum", 6},
+		{"let sum = 0; for (i, x in [10, 20, 30]) { let sum = sum + i * x }; sum", 80},
+		{"let sum = 0; for (x in 1..5) { let sum = sum + x }; sum", 10},
+		{"let sum = 0; for (x in 1..=5) { let sum = sum + x }; sum", 15},
+		{"let sum = 0; for (x in 5..1) { let sum = sum + x }; sum", 0},
+		{`let n = 0; for (c in "héllo") { let n = n + 1 }; n`, 5},
+		{`let last = 0; for (i, c in "héllo") { let last = i }; last`, 4},
+		{`let sum = 0; for (k in {"a": 1, "b": 2}) { let sum = sum + len(k) }; sum`, 2},
+		{`let sum = 0; for (k, v in {"a": 1, "b": 2}) { let sum = sum + v }; sum`, 3},
+		{"let sum = 0; for (x in 0..100) { if (x % 2 == 0) { continue } if (x > 10) { break } let sum = sum + x }; sum", 25},
+		{"let n = 0; for (i in 0..3) { for (j in 0..3) { if (j == 1) { break } let n = n + 1 } }; n", 3},
+		{"fn(xs) { for (x in xs) { if (x > 2) { return x } } 0 }([1, 2, 3, 4])", 3},
+		{"fn() { for (x in 0..1000000) { if (x == 5) { return x } } }()", 5},
+		{"for (x in [7, 8]) { }; x", 8},
+		{"fn() { let sum = 0; for (k, v in {1: 2, 3: 4}) { let sum = sum + k * v }; sum }()", 14},
+		{"fn() { for (x in [1]) { } }()", Null},
+		{"let r = 1..=3; let s = 0; for (x in r) { let s = s + x }; s", 6},
+		// Far more iterations than the stack has room for, so neither the iterator nor the body may pile up
+		{"let n = 0; for (x in 0..10000) { if (x % 2 == 0) { continue } let n = n + 1 }; n", 5000},
+		{"let n = 0; for (x in 0..10000) { for (y in [1]) { break } let n = n + 1 }; n", 10000},
+		{"let n = 0; for (x in 0..5) { n = n + (if (x == 3) { continue } else { x }) }; n", 7},
+		{"let i = 0; for (x in 0..5000) { i = 1 + (if (true) { continue } else { 0 }) }; i", 0},
+		{"let n = 0; for (x in 0..5) { n = len([x, if (x == 2) { break }]) + x }; n", 3},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestAssignExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{"let x = 1; x = 2; x", 2},
+		{"let x = 1; x = x + 1", 2},
+		{"let a = 1; let b = 2; a = b = 5; a + b", 10},
+		{"let x = 10; x += 5; x -= 3; x *= 2; x /= 4; x %= 4", 2},
+		{"let x = 1.5; x *= 2", 3.0},
+		{`let s = "a"; s += "b"; s`, "ab"},
+		{"let i = 0; let sum = 0; while (i < 5) { i += 1; sum += i }; sum", 15},
+		{"let f = fn(n) { n += 1; n }; f(1)", 2},
+		{"fn() { let x = 1; let y = x = 3; x + y }()", 6},
+		{"let count = 0; let inc = fn() { count += 1 }; inc(); inc(); count", 2},
+		{"let a = [1, 2, 3]; a[0] = 9; a", []int{9, 2, 3}},
+		{"let a = [1, 2, 3]; a[1] += 10; a", []int{1, 12, 3}},
+		{"let a = [1, 2]; let b = a; b[0] = 5; a", []int{5, 2}},
+		{"let a = [[1, 2]]; a[0][1] *= 3; a[0]", []int{1, 6}},
+		{`let h = {"a": 1}; h["a"] += 1; h["b"] = 3; h["a"] + h["b"]`, 5},
+		{"let a = [0, 0]; let i = 0; a[i = 1] += 7; a", []int{0, 7}},
+		{"fn() { let a = [1]; a[0] = 2 }()", 2},
+		// Closures share the variables they capture with the call that binds them and with each other
+		{"fn(a) { let inc = fn() { a += 1 }; inc(); inc(); a }(1)", 3},
+		{"fn() { let x = 1; let f = fn() { x }; x = 5; f() }()", 5},
+		{"let make = fn() { let n = 0; [fn() { n += 1 }, fn() { n }] }; let c = make(); c[0](); c[0](); c[1]()", 2},
+		{"fn() { let x = 1; fn() { fn() { x += 1 }() }(); x }()", 2},
+		{"let f = fn() { f = 5 }; f(); f", 5},
+		{"fn() { let f = fn() { f = 5 }; f(); f }()", 5},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestGlobalsState(t *testing.T) {
+	symbolTable := compiler.NewGlobalSymbolTable()
+	symbol := symbolTable.Define("answer")
+	globals := make([]object.Object, GlobalsSize)
+	globals[symbol.Index] = &object.Integer{Value: 42}
+
+	comp := compiler.NewWithState(symbolTable, []object.Object{})
+	if err := comp.Compile(parse("answer + 1")); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	vm := NewWithGlobalsState(comp.Bytecode(), globals)
+	if err := vm.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+
+	testExpectedObject(t, 43, vm.LastPoppedStackElem())
+}
+
+func TestLetHasNoValue(t *testing.T) {
+	tests := []string{"let x = 5", "7; let y = 2", "fn() { let z = 1; 2 }; let w = 3"}
+
+	for _, input := range tests {
+		comp := compiler.New()
+		if err := comp.Compile(parse(input)); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		vm := New(comp.Bytecode())
+		if err := vm.Run(); err != nil {
+			t.Fatalf("vm error: %s", err)
+		}
+
+		if last := vm.LastPoppedStackElem(); last != nil {
+			t.Errorf("%q: expected no value, got=%s", input, last.Inspect())
+		}
+	}
+}
+
+func TestFunctionsPrintLikeTheEvaluator(t *testing.T) {
+	comp := compiler.New()
+	if err := comp.Compile(parse("let add = fn(a, b) { a + b }; add")); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	vm := New(comp.Bytecode())
+	if err := vm.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+
+	expected := "fn(a, b) {\n(a + b)\n}"
+	if got := vm.LastPoppedStackElem().Inspect(); got != expected {
+		t.Errorf("closure printed wrong. expected=%q, got=%q", expected, got)
+	}
+}
+
+func TestBuiltinOutput(t *testing.T) {
+	comp := compiler.New()
+	if err := comp.Compile(parse(`puts("hello", 1 + 1)`)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	var out bytes.Buffer
+
+	vm := New(comp.Bytecode())
+	vm.SetOutput(&out)
+	if err := vm.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+
+	if out.String() != "hello\n2\n" {
+		t.Errorf("output wrong. expected=%q, got=%q", "hello\n2\n", out.String())
+	}
+
+	testExpectedObject(t, Null, vm.LastPoppedStackElem())
+}
+
+func TestRuntimeErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"5 + true", "type mismatch: INTEGER + BOOLEAN"},
+		{"true + false", "unknown operator: BOOLEAN + BOOLEAN"},
+		{`"a" - "b"`, "unknown operator: STRING - STRING"},
+		{"-true", "unknown operator: -BOOLEAN"},
+		{"1 / 0", "division by zero: 1 / 0"},
+		{"1 % 0", "division by zero: 1 % 0"},
+		{"1.5 & 1", "unknown operator: FLOAT & INTEGER"},
+		{"~1.5", "unknown operator: ~FLOAT"},
+		{"true | 1", "type mismatch: BOOLEAN | INTEGER"},
+		{"1 << -1", "negative shift count: 1 << -1"},
+		{"for (x in 5) { x }", "not iterable: INTEGER"},
+		{"1.5..2", "unknown operator: FLOAT .. INTEGER"},
+		{"true && 1 / 0", "division by zero: 1 / 0"},
+		{"1(2)", "not a function: INTEGER"},
+		{"fn(a) { a }()", "wrong number of arguments: want=1, got=0"},
+		{"1[0]", "index operator not supported: INTEGER"},
+		{"let a = [1]; a[1] = 2", "index out of range: 1"},
+		{"let a = [1]; a[-1] += 2", "type mismatch: NULL + INTEGER"},
+		{`let a = [1]; a["x"] = 2`, "unusable as array index: STRING"},
+		{`let s = "ab"; s[0] = "c"`, "index assignment not supported: STRING"},
+		{"let h = {}; h[[1]] = 1", "unusable as hash key: ARRAY"},
+		{"let x = 1; x /= 0", "division by zero: 1 / 0"},
+		{"{[1]: 2}", "unusable as hash key: ARRAY"},
+		{"{1: 2}[fn() {}]", "unusable as hash key: FUNCTION"},
+		{"len(1)", "argument to `len` not supported, got INTEGER"},
+		{"fn(f) { f(f) }(fn(f) { f(f) })", "stack overflow"},
+		// Slots whose let statement never ran, the first one was used by g before
+		{"let g = fn() { let a = 42; a }; let f = fn() { if (false) { let z = 1 }; z }; g(); f()", "identifier not found: z"},
+		{"fn() { if (false) { let z = 1 }; z + 1 }()", "identifier not found: z"},
+		{"if (false) { let q = 1 }; q", "identifier not found: q"},
+		{"fn() { if (false) { let z = 1 }; fn() { z }() }()", "identifier not found: z"},
+		{"for (k in []) {}; k + 1", "identifier not found: k"},
+		{"let f = fn() { for (k in []) {}; k }; f()", "identifier not found: k"},
+		// Names that are not bound, or can not be assigned, are only reported when the code using them runs
+		{"foobar", "identifier not found: foobar"},
+		{"fn(a) { b }(1)", "identifier not found: b"},
+		{"undefined = 1", "identifier not found: undefined"},
+		{"[1][0] = y", "identifier not found: y"},
+		{"fn() { x = 1; let x = 2 }()", "identifier not found: x"},
+		{"len = 1", "cannot assign to builtin: len"},
+		{"len += 1", "cannot assign to builtin: len"},
+	}
+
+	for _, tt := range tests {
+		comp := compiler.New()
+		if err := comp.Compile(parse(tt.input)); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		vm := New(comp.Bytecode())
+		err := vm.Run()
+		if err == nil {
+			t.Errorf("%q: expected a runtime error", tt.input)
+			continue
+		}
+
+		if err.Error() != tt.expected {
+			t.Errorf("%q: wrong error. expected=%q, got=%q", tt.input, tt.expected, err.Error())
+		}
+	}
+}
+
+func runVmTests(t *testing.T, tests []vmTestCase) {
+	t.Helper()
+
+	for _, tt := range tests {
+		program := parse(tt.input)
+
+		comp := compiler.New()
+		err := comp.Compile(program)
+		if err != nil {
+			t.Fatalf("%q: compiler error: %s", tt.input, err)
+		}
+
+		vm := New(comp.Bytecode())
+		err = vm.Run()
+		if err != nil {
+			t.Fatalf("%q: vm error: %s", tt.input, err)
+		}
+
+		stackElem := vm.LastPoppedStackElem()
+
+		testExpectedObject(t, tt.expected, stackElem)
+	}
+}
+
+func testExpectedObject(t *testing.T, expected interface{}, actual object.Object) {
+	t.Helper()
+
+	switch expected := expected.(type) {
+	case int:
+		integer, ok := actual.(*object.Integer)
+		if !ok || integer.Value != int64(expected) {
+			t.Errorf("object is not Integer %d. got=%T (%+v)", expected, actual, actual)
+		}
+
+	case float64:
+		float, ok := actual.(*object.Float)
+		if !ok || float.Value != expected {
+			t.Errorf("object is not Float %g. got=%T (%+v)", expected, actual, actual)
+		}
+
+	case bool:
+		boolean, ok := actual.(*object.Boolean)
+		if !ok || boolean.Value != expected {
+			t.Errorf("object is not Boolean %t. got=%T (%+v)", expected, actual, actual)
+		}
+
+	case string:
+		str, ok := actual.(*object.String)
+		if !ok || str.Value != expected {
+			t.Errorf("object is not String %q. got=%T (%+v)", expected, actual, actual)
+		}
+
+	case []int:
+		array, ok := actual.(*object.Array)
+		if !ok {
+			t.Errorf("object not Array: %T (%+v)", actual, actual)
+			return
+		}
+
+		if len(array.Elements) != len(expected) {
+			t.Errorf("wrong num of elements. want=%d, got=%d", len(expected), len(array.Elements))
+			return
+		}
+
+		for i, expectedElem := range expected {
+			testExpectedObject(t, expectedElem, array.Elements[i])
+		}
+
+	case *object.Null:
+		if actual != Null {
+			t.Errorf("object is not Null: %T (%+v)", actual, actual)
+		}
+	}
+}